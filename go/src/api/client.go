@@ -0,0 +1,190 @@
+package api
+
+// Client is the Backend of a running node, through its HTTP API
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"miner"
+	"net/http"
+	"net/url"
+	"proto"
+	"strings"
+	"time"
+)
+
+type Client struct {
+	Addr string // base URL of the node, e.g. http://localhost:8080
+	HTTP *http.Client
+}
+
+func NewClient(addr string) *Client {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+
+	return &Client{
+		Addr: strings.TrimRight(addr, "/"),
+		HTTP: &http.Client{Timeout: time.Minute},
+	}
+}
+
+func (c *Client) ChainInfo() (*ChainInfo, error) {
+	var info ChainInfo
+	return &info, c.call(http.MethodGet, "/chain", nil, &info)
+}
+
+func (c *Client) Block(ref string) (*BlockInfo, error) {
+	var info BlockInfo
+	return &info, c.call(http.MethodGet, "/blocks/"+url.PathEscape(ref), nil, &info)
+}
+
+func (c *Client) Header(ref string) (*pb.BlockHeader, error) {
+	var header pb.BlockHeader
+	return &header, c.call(http.MethodGet, "/headers/"+url.PathEscape(ref), nil, &header)
+}
+
+func (c *Client) Body(ref string) (*pb.BlockBody, error) {
+	var body pb.BlockBody
+	return &body, c.call(http.MethodGet, "/bodies/"+url.PathEscape(ref), nil, &body)
+}
+
+func (c *Client) SendTx(recipient string, val float64) (string, error) {
+	var rsp TxResponse
+	err := c.call(http.MethodPost, "/txs", &TxRequest{Recipient: recipient, Val: val}, &rsp)
+	return rsp.Id, err
+}
+
+func (c *Client) SubmitTx(tx *pb.Transaction) (string, error) {
+	var rsp TxResponse
+	err := c.call(http.MethodPost, "/rawtxs", tx, &rsp)
+	return rsp.Id, err
+}
+
+func (c *Client) Tx(id string) (*pb.Transaction, error) {
+	var tx pb.Transaction
+	return &tx, c.call(http.MethodGet, "/txs/"+url.PathEscape(id), nil, &tx)
+}
+
+func (c *Client) Balance(addr string) (float64, error) {
+	var rsp BalanceResponse
+	err := c.call(http.MethodGet, "/balances/"+url.PathEscape(addr), nil, &rsp)
+	return rsp.Balance, err
+}
+
+func (c *Client) Mine() (*BlockInfo, error) {
+	var info BlockInfo
+	return &info, c.call(http.MethodPost, "/mine", nil, &info)
+}
+
+func (c *Client) Validate() error {
+	var rsp ValidateResponse
+	if err := c.call(http.MethodGet, "/validate", nil, &rsp); err != nil {
+		return err
+	}
+	if !rsp.Valid {
+		return fmt.Errorf("%s", rsp.Error)
+	}
+
+	return nil
+}
+
+func (c *Client) Handshake() (*pb.Handshake, error) {
+	var h pb.Handshake
+	return &h, c.call(http.MethodGet, "/handshake", nil, &h)
+}
+
+func (c *Client) Validators() (*ValidatorsInfo, error) {
+	var info ValidatorsInfo
+	return &info, c.call(http.MethodGet, "/validators", nil, &info)
+}
+
+func (c *Client) Propose(validator, action string) error {
+	var info ValidatorsInfo
+	return c.call(http.MethodPost, "/proposals", &ProposalRequest{Validator: validator, Action: action}, &info)
+}
+
+func (c *Client) Stake(action string, val float64) (string, error) {
+	var rsp TxResponse
+	err := c.call(http.MethodPost, "/stakes", &StakeRequest{Action: action, Val: val}, &rsp)
+	return rsp.Id, err
+}
+
+func (c *Client) SubmitHeader(header *pb.SignedHeader) (*pb.Evidence, error) {
+	var rsp EvidenceResponse
+	err := c.call(http.MethodPost, "/headers", header, &rsp)
+	return rsp.Evidence, err
+}
+
+func (c *Client) StartMiner(policy miner.Policy) (*miner.Status, error) {
+	var status miner.Status
+	return &status, c.call(http.MethodPost, "/miner/start", &policy, &status)
+}
+
+func (c *Client) StopMiner() (*miner.Status, error) {
+	var status miner.Status
+	return &status, c.call(http.MethodPost, "/miner/stop", nil, &status)
+}
+
+func (c *Client) MinerStatus() (*miner.Status, error) {
+	var status miner.Status
+	return &status, c.call(http.MethodGet, "/miner", nil, &status)
+}
+
+func (c *Client) GetWork() (*WorkInfo, error) {
+	var info WorkInfo
+	return &info, c.call(http.MethodGet, "/work", nil, &info)
+}
+
+func (c *Client) SubmitWork(work string, proof int64) (*BlockInfo, error) {
+	var info BlockInfo
+	return &info, c.call(http.MethodPost, "/work", &WorkRequest{Work: work, Proof: proof}, &info)
+}
+
+// CheckNetwork checks that the node is on the chain with the ID and the genesis hash, either of them may be empty
+func (c *Client) CheckNetwork(chainID, genesis string) error {
+	h, err := c.Handshake()
+	if err != nil {
+		return err
+	}
+	if len(chainID) > 0 && h.ChainId != chainID {
+		return fmt.Errorf("Node is on the chain %q, not %q", h.ChainId, chainID)
+	}
+	if len(genesis) > 0 && h.Genesis != genesis {
+		return fmt.Errorf("Node has the genesis %s, not %s", h.Genesis, genesis)
+	}
+
+	return nil
+}
+
+// call sends the request with the JSON of in as body if not nil, and decodes the response into out
+func (c *Client) call(method, path string, in, out interface{}) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, c.Addr+path, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rsp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		var e ErrorResponse
+		if err := json.NewDecoder(rsp.Body).Decode(&e); err != nil || len(e.Error) == 0 {
+			return fmt.Errorf("Node replied %s", rsp.Status)
+		}
+		return fmt.Errorf("%s", e.Error)
+	}
+
+	return json.NewDecoder(rsp.Body).Decode(out)
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"config"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Nodes with different users and genesis run side by side in one process
+func TestMultiNode(t *testing.T) {
+	nodes := map[string]config.Config{
+		"alice": {
+			User: config.User{Address: "alice"},
+			Init: config.Accounts{{Address: "alice", Val: 50}},
+		},
+		"bob": {
+			User: config.User{Address: "bob"},
+			Init: config.Accounts{{Address: "bob", Val: 80}, {Address: "alice", Val: 1}},
+		},
+	}
+
+	clients := make(map[string]*Client)
+	for name, cfg := range nodes {
+		dir, err := ioutil.TempDir("", name)
+		assert.Nil(t, err)
+		defer os.RemoveAll(dir)
+		cfg.DataDir = dir
+
+		s, err := InitService(cfg)
+		assert.Nil(t, err)
+		ts := httptest.NewServer(NewServer(s))
+		defer ts.Close()
+		clients[name] = NewClient(ts.URL)
+	}
+
+	_, err := clients["alice"].SendTx("carol", 20)
+	assert.Nil(t, err)
+	_, err = clients["bob"].SendTx("carol", 30)
+	assert.Nil(t, err)
+	for _, c := range clients {
+		_, err := c.Mine()
+		assert.Nil(t, err)
+	}
+
+	for name, want := range map[string]map[string]float64{
+		"alice": {"alice": 30, "bob": 0, "carol": 20},
+		"bob":   {"alice": 1, "bob": 50, "carol": 30},
+	} {
+		info, err := clients[name].ChainInfo()
+		assert.Nil(t, err)
+		assert.Equal(t, name, info.User)
+		for addr, val := range want {
+			bal, err := clients[name].Balance(addr)
+			assert.Nil(t, err)
+			assert.Equal(t, val, bal, name+" "+addr)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package api
+
+// HTTP API of a node, JSON in and out
+//   GET  /chain           chain info
+//   GET  /blocks/{ref}    block by index or hash
+//   GET  /headers/{ref}   compact header of the block by index or hash, see pb.BlockHeader
+//   GET  /bodies/{ref}    body of the block by index or hash, its tries, see pb.BlockBody
+//   POST /txs             send {"recipient": addr, "val": amount}, returns {"id": id}
+//   POST /rawtxs          submit a transaction made elsewhere, returns {"id": id}
+//   GET  /txs/{id}        transaction
+//   GET  /balances/{addr} {"addr": addr, "balance": amount}
+//   POST /mine            mine the open transactions, returns the new block
+//   GET  /validate        {"valid": bool, "error": reason}
+//   GET  /handshake       network of the node, see pb.Handshake
+//   GET  /validators      validators of the next block and votes of the node, see ValidatorsInfo
+//   POST /proposals       vote {"validator": key, "action": "add"|"remove"|"discard"}, returns the validators
+//   POST /stakes          bond or unbond stake {"action": "bond"|"unbond", "val": amount}, returns {"id": id}
+//   POST /headers         check a signed block header for double signing, returns {"evidence": evidence}
+//   GET  /miner           status of the background miner, see miner.Status
+//   POST /miner/start     start the miner with the policy in the body, see miner.Policy, returns the status
+//   POST /miner/stop      stop the miner, returns the status
+//   GET  /work            work of the next block for an external miner, see WorkInfo
+//   POST /work            submit {"work": work, "proof": proof}, which adds the block of the work, returns the block
+// Errors are returned as {"error": reason} with a non-2xx status.
+import (
+	"encoding/json"
+	"fmt"
+	"miner"
+	"net/http"
+	"net/url"
+	"proto"
+	"strings"
+)
+
+type Server struct {
+	Backend Backend
+	routes  map[string]handler // by method and first segment of the path, e.g. "GET /blocks"
+}
+
+// handler serves a request, arg is the unescaped rest of the path
+type handler func(r *http.Request, arg string) (interface{}, error)
+
+type TxRequest struct {
+	Recipient string  `json:"recipient"`
+	Val       float64 `json:"val"`
+}
+
+type TxResponse struct {
+	Id string `json:"id"`
+}
+
+type BalanceResponse struct {
+	Addr    string  `json:"addr"`
+	Balance float64 `json:"balance"`
+}
+
+type ValidateResponse struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+type ProposalRequest struct {
+	Validator string `json:"validator"`
+	Action    string `json:"action"`
+}
+
+type StakeRequest struct {
+	Action string  `json:"action"`
+	Val    float64 `json:"val"`
+}
+
+type EvidenceResponse struct {
+	Evidence *pb.Evidence `json:"evidence,omitempty"`
+}
+
+type WorkRequest struct {
+	Work  string `json:"work"`
+	Proof int64  `json:"proof"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+func NewServer(b Backend) *Server {
+	s := &Server{Backend: b}
+	s.routes = map[string]handler{
+		"GET /chain": func(*http.Request, string) (interface{}, error) {
+			return s.Backend.ChainInfo()
+		},
+		"GET /blocks": func(_ *http.Request, ref string) (interface{}, error) {
+			return s.Backend.Block(ref)
+		},
+		"GET /headers": func(_ *http.Request, ref string) (interface{}, error) {
+			return s.Backend.Header(ref)
+		},
+		"GET /bodies": func(_ *http.Request, ref string) (interface{}, error) {
+			return s.Backend.Body(ref)
+		},
+		"GET /txs": func(_ *http.Request, id string) (interface{}, error) {
+			return s.Backend.Tx(id)
+		},
+		"GET /balances": func(_ *http.Request, addr string) (interface{}, error) {
+			bal, err := s.Backend.Balance(addr)
+			return &BalanceResponse{Addr: addr, Balance: bal}, err
+		},
+		"GET /validate": func(*http.Request, string) (interface{}, error) {
+			if err := s.Backend.Validate(); err != nil {
+				return &ValidateResponse{Error: err.Error()}, nil
+			}
+			return &ValidateResponse{Valid: true}, nil
+		},
+		"POST /txs": func(r *http.Request, _ string) (interface{}, error) {
+			var req TxRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				return nil, err
+			}
+
+			id, err := s.Backend.SendTx(req.Recipient, req.Val)
+			return &TxResponse{Id: id}, err
+		},
+		"POST /rawtxs": func(r *http.Request, _ string) (interface{}, error) {
+			var tx pb.Transaction
+			if err := json.NewDecoder(r.Body).Decode(&tx); err != nil {
+				return nil, err
+			}
+
+			id, err := s.Backend.SubmitTx(&tx)
+			return &TxResponse{Id: id}, err
+		},
+		"GET /handshake": func(*http.Request, string) (interface{}, error) {
+			return s.Backend.Handshake()
+		},
+		"POST /mine": func(*http.Request, string) (interface{}, error) {
+			return s.Backend.Mine()
+		},
+		"GET /validators": func(*http.Request, string) (interface{}, error) {
+			return s.Backend.Validators()
+		},
+		"POST /proposals": func(r *http.Request, _ string) (interface{}, error) {
+			var req ProposalRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				return nil, err
+			}
+			if err := s.Backend.Propose(req.Validator, req.Action); err != nil {
+				return nil, err
+			}
+
+			return s.Backend.Validators()
+		},
+		"POST /stakes": func(r *http.Request, _ string) (interface{}, error) {
+			var req StakeRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				return nil, err
+			}
+
+			id, err := s.Backend.Stake(req.Action, req.Val)
+			return &TxResponse{Id: id}, err
+		},
+		"POST /headers": func(r *http.Request, _ string) (interface{}, error) {
+			var header pb.SignedHeader
+			if err := json.NewDecoder(r.Body).Decode(&header); err != nil {
+				return nil, err
+			}
+
+			ev, err := s.Backend.SubmitHeader(&header)
+			return &EvidenceResponse{Evidence: ev}, err
+		},
+		"GET /miner": func(*http.Request, string) (interface{}, error) {
+			return s.Backend.MinerStatus()
+		},
+		"POST /miner": func(r *http.Request, action string) (interface{}, error) {
+			switch action {
+			case "start":
+				var policy miner.Policy
+				if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+					return nil, err
+				}
+				return s.Backend.StartMiner(policy)
+			case "stop":
+				return s.Backend.StopMiner()
+			}
+			return nil, fmt.Errorf("Unknown miner action %q", action)
+		},
+		"GET /work": func(*http.Request, string) (interface{}, error) {
+			return s.Backend.GetWork()
+		},
+		"POST /work": func(r *http.Request, _ string) (interface{}, error) {
+			var req WorkRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				return nil, err
+			}
+
+			return s.Backend.SubmitWork(req.Work, req.Proof)
+		},
+	}
+	return s
+}
+
+// ServeHTTP routes on the escaped path, as hashes are base64 and may contain slashes.
+// The argument is unescaped only after splitting it from the route.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.EscapedPath(), "/")
+	route, arg := path, ""
+	if i := strings.Index(path, "/"); i >= 0 {
+		route, arg = path[:i], path[i+1:]
+	}
+
+	h, ok := s.routes[r.Method+" /"+route]
+	if !ok {
+		status := http.StatusNotFound
+		if s.routes["GET /"+route] != nil || s.routes["POST /"+route] != nil {
+			status = http.StatusMethodNotAllowed
+		}
+		writeJSON(w, status, &ErrorResponse{Error: http.StatusText(status)})
+		return
+	}
+
+	arg, err := url.PathUnescape(arg)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, &ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	v, err := h(r, arg)
+	if err != nil {
+		// reads fail on what they look up, writes on what they're given
+		status := http.StatusNotFound
+		if r.Method == http.MethodPost {
+			status = http.StatusBadRequest
+		}
+		writeJSON(w, status, &ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, v)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
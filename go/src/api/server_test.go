@@ -0,0 +1,295 @@
+package api
+
+import (
+	"blockchain"
+	"config"
+	"consensus"
+	"context"
+	"genesis"
+	"keys"
+	"miner"
+	"net/http"
+	"net/http/httptest"
+	"proto"
+	"testing"
+	"time"
+	"utils"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientServer(t *testing.T) {
+	s := NewService(newTestChain(t), "")
+	ts := httptest.NewServer(NewServer(s))
+	defer ts.Close()
+	c := NewClient(ts.URL)
+
+	id, err := c.SendTx("receiverhash", 10.0)
+	assert.Nil(t, err)
+	assert.True(t, len(id) > 0)
+	block, err := c.Mine()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(block.Txs))
+
+	tx, err := c.Tx(id)
+	assert.Nil(t, err)
+	assert.Equal(t, 10.0, tx.Val)
+	assert.Equal(t, "complete", tx.Status)
+	bal, err := c.Balance("receiverhash")
+	assert.Nil(t, err)
+	assert.Equal(t, 10.0, bal)
+
+	got, err := c.Block(block.Hash)
+	assert.Nil(t, err)
+	assert.Equal(t, block.Index, got.Index)
+	info, err := c.ChainInfo()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, info.Height)
+	assert.Equal(t, "hawaii-dev", info.ChainID)
+	assert.Equal(t, block.PrevHash, info.Genesis)
+	assert.Nil(t, c.Validate())
+}
+
+func TestClientServerHeader(t *testing.T) {
+	ts := httptest.NewServer(NewServer(NewService(newTestChain(t), "")))
+	defer ts.Close()
+	c := NewClient(ts.URL)
+	_, err := c.SendTx("receiverhash", 10.0)
+	assert.Nil(t, err)
+	block, err := c.Mine()
+	assert.Nil(t, err)
+
+	// the header hashes to the block, and commits to the body sent apart
+	header, err := c.Header(block.Hash)
+	assert.Nil(t, err)
+	assert.Equal(t, block.Index, header.Index)
+	assert.Equal(t, block.PrevHash, header.PrevHash)
+	assert.Equal(t, block.Hash, utils.HashHeader(header))
+	body, err := c.Body("1")
+	assert.Nil(t, err)
+	assert.Equal(t, header.TxRoot, body.Txs.Root.Hash)
+	assert.Equal(t, header.StateRoot, body.Balances.Root.Hash)
+
+	_, err = c.Header("7")
+	assert.NotNil(t, err)
+	_, err = c.Body("unknown")
+	assert.NotNil(t, err)
+}
+
+func TestClientServerMiner(t *testing.T) {
+	ts := httptest.NewServer(NewServer(NewService(newTestChain(t), "")))
+	defer ts.Close()
+	c := NewClient(ts.URL)
+
+	status, err := c.MinerStatus()
+	assert.Nil(t, err)
+	assert.False(t, status.Running)
+	_, err = c.StartMiner(miner.Policy{Interval: -1})
+	assert.NotNil(t, err)
+
+	status, err = c.StartMiner(miner.Policy{Interval: 60})
+	assert.Nil(t, err)
+	assert.True(t, status.Running)
+	assert.Equal(t, int64(60), status.Policy.Interval)
+	assert.Eventually(t, func() bool {
+		status, _ := c.MinerStatus()
+		return status.Blocks == 1
+	}, 10*time.Second, time.Millisecond)
+
+	status, err = c.StopMiner()
+	assert.Nil(t, err)
+	assert.False(t, status.Running)
+	info, err := c.ChainInfo()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, info.Height)
+	assert.Equal(t, status.LastBlock, info.LastHash)
+
+	_, err = c.StopMiner()
+	assert.NotNil(t, err)
+	assert.NotNil(t, c.call(http.MethodPost, "/miner/pause", nil, status))
+}
+
+func TestClientServerWork(t *testing.T) {
+	ts := httptest.NewServer(NewServer(NewService(newTestChain(t), "")))
+	defer ts.Close()
+	c := NewClient(ts.URL)
+
+	_, err := c.SendTx("receiverhash", 10.0)
+	assert.Nil(t, err)
+	work, err := c.GetWork()
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), work.Header.Index)
+	got, err := consensus.Work(work.Header)
+	assert.Nil(t, err)
+	assert.Equal(t, work.Work, got)
+
+	// the miner finds the proof on its own
+	proof, err := consensus.Search(context.Background(), work.Work, int(work.Difficulty), 1, nil)
+	assert.Nil(t, err)
+	_, err = c.SubmitWork(work.Work, proof+1)
+	assert.NotNil(t, err)
+	block, err := c.SubmitWork(work.Work, proof)
+	assert.Nil(t, err)
+	work.Header.Nonce = proof
+	assert.Equal(t, utils.HashHeader(work.Header), block.Hash)
+	assert.Equal(t, 1, len(block.Txs))
+	assert.Nil(t, c.Validate())
+	bal, err := c.Balance("receiverhash")
+	assert.Nil(t, err)
+	assert.Equal(t, 10.0, bal)
+
+	_, err = c.SubmitWork(work.Work, proof)
+	assert.NotNil(t, err)
+}
+
+func TestClientServerErrors(t *testing.T) {
+	bc := newTestChain(t)
+	ts := httptest.NewServer(NewServer(NewService(bc, "")))
+	defer ts.Close()
+	c := NewClient(ts.URL)
+
+	_, err := c.Tx("unknown")
+	assert.EqualError(t, err, "No transaction unknown")
+	_, err = c.SendTx("", 1.0)
+	assert.NotNil(t, err)
+	_, err = c.Block("7")
+	assert.NotNil(t, err)
+
+	bc.MineBlock()
+	bc.Blocks[1].Hash = "forged"
+	assert.NotNil(t, c.Validate())
+
+	rsp, err := http.Post(ts.URL+"/chain", "application/json", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusMethodNotAllowed, rsp.StatusCode)
+	rsp.Body.Close()
+}
+
+func TestServerEscapedHash(t *testing.T) {
+	bc := newTestChain(t)
+	bc.MineBlock()
+	bc.Blocks[1].Hash = "a//b/+c="
+	ts := httptest.NewServer(NewServer(NewService(bc, "")))
+	defer ts.Close()
+
+	block, err := NewClient(ts.URL).Block("a//b/+c=")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), block.Index)
+}
+
+func TestServerNetwork(t *testing.T) {
+	local := newTestChain(t)
+	ts := httptest.NewServer(NewServer(NewService(local, "")))
+	defer ts.Close()
+	c := NewClient(ts.URL)
+
+	h, err := c.Handshake()
+	assert.Nil(t, err)
+	assert.Nil(t, local.CheckHandshake(h))
+	assert.Nil(t, c.CheckNetwork("hawaii-dev", local.Blocks[0].Hash))
+	assert.NotNil(t, c.CheckNetwork("main", ""))
+	assert.NotNil(t, c.CheckNetwork("", "other"))
+
+	// a transaction of the same network is accepted, one of another network rejected
+	wallet := newTestChain(t)
+	wallet.AddTransaction("receiverhash", 10.0)
+	id, err := c.SubmitTx(wallet.OpenTxs[0])
+	assert.Nil(t, err)
+	assert.Equal(t, wallet.OpenTxs[0].Id, id)
+
+	foreign := *wallet.OpenTxs[0]
+	foreign.ChainId = "main"
+	foreign.Id, _ = blockchain.TxID(&foreign)
+	_, err = c.SubmitTx(&foreign)
+	assert.NotNil(t, err)
+
+	_, err = c.Mine()
+	assert.Nil(t, err)
+	bal, _ := c.Balance("receiverhash")
+	assert.Equal(t, 10.0, bal)
+}
+
+func TestClientServerValidators(t *testing.T) {
+	priv, _ := keys.Generate()
+	other, _ := keys.Generate()
+	spec := genesis.Default(nil)
+	spec.Consensus = genesis.Consensus{Engine: genesis.EnginePoA, Signers: []string{keys.Public(priv)}}
+	bc, err := blockchain.NewBlockChain(blockchain.WithGenesis(*spec), blockchain.WithUser("alice"),
+		blockchain.WithKey(priv))
+	assert.Nil(t, err)
+	ts := httptest.NewServer(NewServer(NewService(bc, "")))
+	defer ts.Close()
+	c := NewClient(ts.URL)
+
+	assert.Nil(t, c.Propose(keys.Public(other), ProposeAdd))
+	info, err := c.Validators()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{keys.Public(priv)}, info.Validators)
+	assert.Equal(t, 1, len(info.Proposals))
+	assert.Equal(t, keys.Public(other), info.Proposals[0].Validator)
+
+	block, err := c.Mine()
+	assert.Nil(t, err)
+	assert.Equal(t, keys.Public(priv), block.Signer)
+	info, err = c.Validators()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(info.Validators))
+
+	assert.Nil(t, c.Propose(keys.Public(other), ProposeDiscard))
+	assert.NotNil(t, c.Propose(keys.Public(other), ProposeDiscard))
+	assert.NotNil(t, c.Propose(keys.Public(other), "promote"))
+	assert.NotNil(t, c.Propose("bogus", ProposeAdd))
+
+	// other engines have no validators
+	ts2 := httptest.NewServer(NewServer(NewService(newTestChain(t), "")))
+	defer ts2.Close()
+	_, err = NewClient(ts2.URL).Validators()
+	assert.NotNil(t, err)
+}
+
+func TestClientServerStakes(t *testing.T) {
+	priv, _ := keys.Generate()
+	pub := keys.Public(priv)
+	spec := genesis.Default(config.Accounts{{Address: pub, Val: 100}})
+	spec.Consensus = genesis.Consensus{Engine: genesis.EnginePoS, Stakes: config.Accounts{{Address: pub, Val: 10}}}
+	bc, err := blockchain.NewBlockChain(blockchain.WithGenesis(*spec), blockchain.WithUser(pub),
+		blockchain.WithKey(priv))
+	assert.Nil(t, err)
+	ts := httptest.NewServer(NewServer(NewService(bc, "")))
+	defer ts.Close()
+	c := NewClient(ts.URL)
+
+	_, err = c.Stake(blockchain.TxBond, 40)
+	assert.Nil(t, err)
+	_, err = c.Stake("delegate", 40)
+	assert.NotNil(t, err)
+	block, err := c.Mine()
+	assert.Nil(t, err)
+	assert.Equal(t, pub, block.Signer)
+	info, err := c.Validators()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]float64{pub: 50}, info.Stakes)
+
+	// a second block signed at the same height is slashed
+	other := proto.Clone(bc.Blocks[1]).(*pb.Block)
+	other.Timestamp++
+	payload, _ := consensus.SealPayload(other)
+	other.Signature = keys.Sign(priv, payload)
+	ev, err := c.SubmitHeader(consensus.SignedHeader(other))
+	assert.Nil(t, err)
+	assert.NotNil(t, ev)
+	ev, err = c.SubmitHeader(consensus.SignedHeader(bc.Blocks[1]))
+	assert.Nil(t, err)
+	assert.Nil(t, ev)
+
+	block, err = c.Mine()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(block.Evidence))
+	info, err = c.Validators()
+	assert.Nil(t, err)
+	assert.Empty(t, info.Validators)
+	_, err = c.Mine()
+	assert.NotNil(t, err)
+	assert.Nil(t, c.Validate())
+}
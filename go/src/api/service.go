@@ -0,0 +1,493 @@
+package api
+
+// The operations of a node, shared by the onebox CLI and the HTTP API
+// A Service runs them on a chain in memory and saves the chain after every change. The CLI uses a Service on its
+// data dir directly, or a Client talking to the Server of a running node, through the same Backend interface.
+import (
+	"blockchain"
+	"config"
+	"context"
+	"fmt"
+	"genesis"
+	"merkle"
+	"miner"
+	"os"
+	"path/filepath"
+	"proto"
+	"sort"
+	"strconv"
+	"sync"
+	"utils"
+)
+
+// ChainFile is the name of the chain file in a data dir
+const ChainFile = "chain.pb"
+
+type Backend interface {
+	ChainInfo() (*ChainInfo, error)
+	Block(ref string) (*BlockInfo, error) // ref is a block index or hash
+	Header(ref string) (*pb.BlockHeader, error)
+	Body(ref string) (*pb.BlockBody, error)
+	SendTx(recipient string, val float64) (string, error)
+	SubmitTx(tx *pb.Transaction) (string, error) // a transaction made elsewhere, of the same network
+	Tx(id string) (*pb.Transaction, error)
+	Balance(addr string) (float64, error)
+	Mine() (*BlockInfo, error)
+	Validate() error
+	Handshake() (*pb.Handshake, error)
+	Validators() (*ValidatorsInfo, error)
+	Propose(validator, action string) error                     // action is one of ProposeAdd, ProposeRemove and ProposeDiscard
+	Stake(action string, val float64) (string, error)           // action is blockchain.TxBond or TxUnbond
+	SubmitHeader(header *pb.SignedHeader) (*pb.Evidence, error) // evidence of double signing, nil if none
+	StartMiner(policy miner.Policy) (*miner.Status, error)
+	StopMiner() (*miner.Status, error)
+	MinerStatus() (*miner.Status, error)
+	GetWork() (*WorkInfo, error)                             // of the next block, for an external miner
+	SubmitWork(work string, proof int64) (*BlockInfo, error) // proof of a work, which adds its block
+}
+
+// Actions on the validators of an authority engine, see blockchain.Propose
+const (
+	ProposeAdd     = "add"
+	ProposeRemove  = "remove"
+	ProposeDiscard = "discard"
+)
+
+type ChainInfo struct {
+	ChainID    string  `json:"chainId"`
+	Genesis    string  `json:"genesis"` // hash of the genesis block, identifying the network
+	Height     int     `json:"height"`  // index of the last block
+	LastHash   string  `json:"lastHash"`
+	Difficulty int32   `json:"difficulty"`
+	OpenTxs    int     `json:"openTxs"`
+	User       string  `json:"user"`
+	StateTree  string  `json:"stateTree"`
+	Engine     string  `json:"engine"`             // consensus engine, see genesis.Consensus
+	HashRate   float64 `json:"hashRate,omitempty"` // of the current or last mining, in hashes per second
+}
+
+type BlockInfo struct {
+	Index      int32             `json:"index"`
+	Hash       string            `json:"hash"`
+	PrevHash   string            `json:"prevHash"`
+	Proof      int64             `json:"proof"`
+	Timestamp  int64             `json:"timestamp"`
+	TxRoot     string            `json:"txRoot"`
+	StateRoot  string            `json:"stateRoot"`
+	Difficulty int32             `json:"difficulty,omitempty"` // of the proof of work, with the pow engine
+	Signer     string            `json:"signer,omitempty"`
+	Coinbase   string            `json:"coinbase,omitempty"` // paid the reward, with the pow and dev engines
+	Evidence   []*pb.Evidence    `json:"evidence,omitempty"` // of the double signing slashed by the block
+	Commit     *pb.Commit        `json:"commit,omitempty"`   // certificate of the block, with the bft engine
+	Txs        []*pb.Transaction `json:"txs"`
+}
+
+type ValidatorsInfo struct {
+	Validators []string           `json:"validators"`       // of the next block, in turn order
+	Proposals  []*pb.Vote         `json:"proposals"`        // votes of the node
+	Stakes     map[string]float64 `json:"stakes,omitempty"` // of the validators, with the pos engine
+}
+
+// WorkInfo is the work of a block template for an external miner, see blockchain.GetWork
+type WorkInfo struct {
+	Work       string          `json:"work"`       // to find the proof of, see consensus.IsValidProof
+	Difficulty int32           `json:"difficulty"` // leading '0's of the hash of the work and the proof
+	Header     *pb.BlockHeader `json:"header"`     // of the template, whose work it is, see consensus.Work
+}
+
+type Service struct {
+	sync.RWMutex
+	bc    *blockchain.BlockChain
+	path  string // chain file, empty to keep the chain in memory only
+	user  string // sender of the transactions, the user of the chain if empty
+	miner *miner.Miner
+}
+
+// NewService serves the chain, saving it to path after every change if path isn't empty
+func NewService(bc *blockchain.BlockChain, path string) *Service {
+	s := &Service{bc: bc, path: path}
+	s.miner = miner.NewMiner(minedChain{s})
+	return s
+}
+
+// InitService creates a chain in the data dir of the config, with the genesis and the user of the config, and serves it
+func InitService(cfg config.Config, opts ...blockchain.Option) (*Service, error) {
+	path := ChainPath(cfg.DataDir)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("Chain already exists at %s", path)
+	}
+	if err := os.MkdirAll(cfg.DataDir, 0755); err != nil {
+		return nil, err
+	}
+
+	bc, err := blockchain.NewBlockChain(append([]blockchain.Option{blockchain.WithConfig(cfg)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	if err := bc.Save(path); err != nil {
+		return nil, err
+	}
+
+	return NewService(bc, path), nil
+}
+
+// OpenService serves the chain saved in the data dir of the config. The user of the config, if set, sends the
+// transactions instead of the user of the chain, e.g. to use another wallet. The key of the config, if set, signs
+// the blocks.
+func OpenService(cfg config.Config) (*Service, error) {
+	var opts []blockchain.Option
+	if len(cfg.Key) > 0 {
+		opts = append(opts, blockchain.WithKeyFile(cfg.Key))
+	}
+	if cfg.Workers > 0 {
+		opts = append(opts, blockchain.WithWorkers(cfg.Workers))
+	}
+
+	path := ChainPath(cfg.DataDir)
+	bc, err := blockchain.LoadBlockChain(path, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	s := NewService(bc, path)
+	s.user = cfg.User.Address
+	return s, nil
+}
+
+// ChainPath returns the path of the chain file in the data dir
+func ChainPath(dataDir string) string {
+	return filepath.Join(dataDir, ChainFile)
+}
+
+func (s *Service) ChainInfo() (*ChainInfo, error) {
+	s.RLock()
+	defer s.RUnlock()
+	last := s.bc.Blocks[len(s.bc.Blocks)-1]
+	info := &ChainInfo{
+		ChainID:    s.bc.Spec().ChainID,
+		Genesis:    s.bc.Blocks[0].Hash,
+		Height:     int(last.Index),
+		LastHash:   last.Hash,
+		Difficulty: s.bc.Difficulty,
+		OpenTxs:    len(s.bc.OpenTxs),
+		User:       s.sender(),
+		StateTree:  merkle.StatePatricia,
+		HashRate:   s.bc.HashRate(),
+	}
+	if last.Balances != nil {
+		info.StateTree = last.Balances.Kind
+	}
+	if info.Engine = s.bc.Spec().Consensus.Engine; len(info.Engine) == 0 {
+		info.Engine = genesis.EnginePoW
+	}
+	return info, nil
+}
+
+func (s *Service) Block(ref string) (*BlockInfo, error) {
+	s.RLock()
+	defer s.RUnlock()
+	block, err := s.block(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return blockInfo(block)
+}
+
+func (s *Service) Header(ref string) (*pb.BlockHeader, error) {
+	s.RLock()
+	defer s.RUnlock()
+	block, err := s.block(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.BlockHeader(block), nil
+}
+
+func (s *Service) Body(ref string) (*pb.BlockBody, error) {
+	s.RLock()
+	defer s.RUnlock()
+	block, err := s.block(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.BlockBody{Txs: block.Txs, Balances: block.Balances}, nil
+}
+
+// block returns the block by index or hash, under the lock
+func (s *Service) block(ref string) (*pb.Block, error) {
+	if index, err := strconv.Atoi(ref); err == nil {
+		if index < 0 || index >= len(s.bc.Blocks) {
+			return nil, fmt.Errorf("No block at height %d", index)
+		}
+		return s.bc.Blocks[index], nil
+	}
+
+	for _, block := range s.bc.Blocks {
+		if block.Hash == ref && len(ref) > 0 {
+			return block, nil
+		}
+	}
+	return nil, fmt.Errorf("No block with the hash %s", ref)
+}
+
+func (s *Service) SendTx(recipient string, val float64) (string, error) {
+	if len(recipient) == 0 || val <= 0 {
+		return "", fmt.Errorf("Invalid transaction of %f to %q", val, recipient)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	id := s.bc.AddTransactionFrom(s.sender(), recipient, val)
+	return id, s.save()
+}
+
+func (s *Service) sender() string {
+	if len(s.user) > 0 {
+		return s.user
+	}
+
+	return s.bc.Usr.Addr
+}
+
+func (s *Service) SubmitTx(tx *pb.Transaction) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+	if err := s.bc.ReceiveTransaction(tx); err != nil {
+		return "", err
+	}
+
+	return tx.Id, s.save()
+}
+
+func (s *Service) Tx(id string) (*pb.Transaction, error) {
+	s.RLock()
+	defer s.RUnlock()
+	if tx := s.bc.GetTransaction(id); tx != nil {
+		return tx, nil
+	}
+
+	// not mined yet
+	for _, tx := range s.bc.OpenTxs {
+		if tx.Id == id {
+			return tx, nil
+		}
+	}
+	return nil, fmt.Errorf("No transaction %s", id)
+}
+
+func (s *Service) Balance(addr string) (float64, error) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.bc.GetBalance(addr), nil
+}
+
+func (s *Service) Mine() (*BlockInfo, error) {
+	block, err := s.mineBlock(context.Background(), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return blockInfo(block)
+}
+
+// mineBlock seals the next block without holding the lock, so that the service keeps serving meanwhile, then adds
+// and saves it
+func (s *Service) mineBlock(ctx context.Context, maxTxs int) (*pb.Block, error) {
+	block, err := s.bc.ProposeBlockContext(ctx, maxTxs)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if err := s.bc.AddBlock(block); err != nil {
+		return nil, err
+	}
+	return block, s.save()
+}
+
+func (s *Service) StartMiner(policy miner.Policy) (*miner.Status, error) {
+	if err := s.miner.Start(policy); err != nil {
+		return nil, err
+	}
+
+	return s.miner.Status(), nil
+}
+
+func (s *Service) StopMiner() (*miner.Status, error) {
+	if err := s.miner.Stop(); err != nil {
+		return nil, err
+	}
+
+	return s.miner.Status(), nil
+}
+
+func (s *Service) MinerStatus() (*miner.Status, error) {
+	return s.miner.Status(), nil
+}
+
+func (s *Service) GetWork() (*WorkInfo, error) {
+	s.RLock()
+	defer s.RUnlock()
+	block, work, err := s.bc.GetWork(0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WorkInfo{Work: work, Difficulty: block.Difficulty, Header: utils.BlockHeader(block)}, nil
+}
+
+func (s *Service) SubmitWork(work string, proof int64) (*BlockInfo, error) {
+	s.Lock()
+	defer s.Unlock()
+	block, err := s.bc.SubmitWork(work, proof)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+
+	return blockInfo(block)
+}
+
+// minedChain is the chain of the service for its miner
+type minedChain struct {
+	s *Service
+}
+
+func (c minedChain) OpenTxs() int {
+	c.s.RLock()
+	defer c.s.RUnlock()
+	return len(c.s.bc.OpenTxs)
+}
+
+func (c minedChain) MineBlock(ctx context.Context, maxTxs int) (*pb.Block, error) {
+	return c.s.mineBlock(ctx, maxTxs)
+}
+
+func (c minedChain) HashRate() float64 {
+	return c.s.bc.HashRate()
+}
+
+func (s *Service) Validate() error {
+	s.RLock()
+	defer s.RUnlock()
+	return s.bc.Validate()
+}
+
+func (s *Service) Handshake() (*pb.Handshake, error) {
+	return s.bc.Handshake(), nil
+}
+
+func (s *Service) Validators() (*ValidatorsInfo, error) {
+	s.RLock()
+	defer s.RUnlock()
+	validators, err := s.bc.Validators()
+	if err != nil {
+		return nil, err
+	}
+
+	info := &ValidatorsInfo{Validators: validators, Proposals: s.bc.GetProposals()}
+	if s.bc.Spec().Consensus.Engine != genesis.EnginePoS {
+		return info, nil
+	}
+
+	stakes, err := s.bc.Stakes(int32(len(s.bc.Blocks)))
+	if err != nil {
+		return nil, err
+	}
+	info.Stakes = make(map[string]float64)
+	for _, v := range validators {
+		info.Stakes[v] = stakes[v]
+	}
+	return info, nil
+}
+
+func (s *Service) Propose(validator, action string) error {
+	s.Lock()
+	defer s.Unlock()
+	var err error
+	switch action {
+	case ProposeAdd, ProposeRemove:
+		err = s.bc.Propose(validator, action == ProposeAdd)
+	case ProposeDiscard:
+		err = s.bc.Discard(validator)
+	default:
+		err = fmt.Errorf("Unknown action %q, expected %s, %s or %s", action, ProposeAdd, ProposeRemove, ProposeDiscard)
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.save()
+}
+
+func (s *Service) Stake(action string, val float64) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+	id, err := s.bc.AddStakeTransaction(action, s.sender(), val)
+	if err != nil {
+		return "", err
+	}
+
+	return id, s.save()
+}
+
+func (s *Service) SubmitHeader(header *pb.SignedHeader) (*pb.Evidence, error) {
+	s.Lock()
+	defer s.Unlock()
+	ev, err := s.bc.ReceiveHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	return ev, s.save()
+}
+
+func (s *Service) save() error {
+	if len(s.path) == 0 {
+		return nil
+	}
+
+	return s.bc.Save(s.path)
+}
+
+func blockInfo(block *pb.Block) (*BlockInfo, error) {
+	info := &BlockInfo{
+		Index:      block.Index,
+		Hash:       block.Hash,
+		PrevHash:   block.PrevHash,
+		Proof:      block.Proof,
+		Timestamp:  block.Timestamp,
+		Difficulty: block.Difficulty,
+		Signer:     block.Signer,
+		Coinbase:   block.Coinbase,
+		Evidence:   block.Evidence,
+		Commit:     block.Commit,
+		Txs:        []*pb.Transaction{},
+	}
+	if block.Balances != nil {
+		info.StateRoot = block.Balances.Root.Hash
+	}
+	if block.Txs == nil {
+		return info, nil
+	}
+
+	info.TxRoot = block.Txs.Root.Hash
+	txs := merkle.NewTypedTrie[*pb.Transaction](merkle.LoadPatriciaTrie(block.Txs), blockchain.TxCodec)
+	err := txs.ForEach(func(id string, tx *pb.Transaction) error {
+		info.Txs = append(info.Txs, tx)
+		return nil
+	})
+	sort.Slice(info.Txs, func(i, j int) bool {
+		if info.Txs[i].Timestamp != info.Txs[j].Timestamp {
+			return info.Txs[i].Timestamp < info.Txs[j].Timestamp
+		}
+		return info.Txs[i].Id < info.Txs[j].Id
+	})
+	return info, err
+}
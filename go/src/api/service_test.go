@@ -0,0 +1,216 @@
+package api
+
+import (
+	"blockchain"
+	"config"
+	"genesis"
+	"io/ioutil"
+	"keys"
+	"miner"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testConfig is the config of the test chains, with the user 0...0 and 100 on each of the accounts 0...0 to 0...2
+func testConfig(t *testing.T) config.Config {
+	cfg, err := config.Load("../config/config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return *cfg
+}
+
+func newTestChain(t *testing.T) *blockchain.BlockChain {
+	bc, err := blockchain.NewBlockChain(blockchain.WithConfig(testConfig(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return bc
+}
+
+// newTestService inits a service in a new data dir, to be removed by the caller
+func newTestService(t *testing.T) (*Service, string) {
+	dir, err := ioutil.TempDir("", "onebox")
+	assert.Nil(t, err)
+	cfg := testConfig(t)
+	cfg.DataDir = dir
+
+	s, err := InitService(cfg)
+	assert.Nil(t, err)
+	return s, dir
+}
+
+func TestServiceTxAndMine(t *testing.T) {
+	s, dir := newTestService(t)
+	defer os.RemoveAll(dir)
+
+	id, err := s.SendTx("receiverhash", 10.0)
+	assert.Nil(t, err)
+	tx, err := s.Tx(id)
+	assert.Nil(t, err)
+	assert.Equal(t, "pending", tx.Status)
+
+	block, err := s.Mine()
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), block.Index)
+	assert.Equal(t, 1, len(block.Txs))
+	assert.Equal(t, id, block.Txs[0].Id)
+
+	tx, err = s.Tx(id)
+	assert.Nil(t, err)
+	assert.Equal(t, "complete", tx.Status)
+	bal, err := s.Balance("receiverhash")
+	assert.Nil(t, err)
+	assert.Equal(t, 10.0, bal)
+	assert.Nil(t, s.Validate())
+
+	// the changes are saved to the data dir
+	reopened, err := OpenService(config.Config{DataDir: dir})
+	assert.Nil(t, err)
+	info, err := reopened.ChainInfo()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, info.Height)
+	assert.Equal(t, block.Hash, info.LastHash)
+	bal, _ = reopened.Balance("receiverhash")
+	assert.Equal(t, 10.0, bal)
+}
+
+func TestServiceMiner(t *testing.T) {
+	s, dir := newTestService(t)
+	defer os.RemoveAll(dir)
+
+	status, err := s.StartMiner(miner.Policy{OnlyWithTxs: true, MaxTxs: 1})
+	assert.Nil(t, err)
+	assert.True(t, status.Running)
+	_, err = s.StartMiner(miner.Policy{})
+	assert.NotNil(t, err)
+
+	// a block per transaction
+	for i := 0; i < 2; i++ {
+		_, err := s.SendTx("receiverhash", 10.0)
+		assert.Nil(t, err)
+	}
+	assert.Eventually(t, func() bool {
+		info, _ := s.ChainInfo()
+		return info.Height == 2 && info.OpenTxs == 0
+	}, 10*time.Second, time.Millisecond)
+
+	status, err = s.StopMiner()
+	assert.Nil(t, err)
+	assert.False(t, status.Running)
+	assert.Equal(t, 2, status.Blocks)
+	assert.Empty(t, status.Error)
+	_, err = s.StopMiner()
+	assert.NotNil(t, err)
+
+	// the blocks are saved
+	reopened, err := OpenService(config.Config{DataDir: dir})
+	assert.Nil(t, err)
+	info, err := reopened.ChainInfo()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, info.Height)
+	assert.Equal(t, status.LastBlock, info.LastHash)
+	assert.Nil(t, reopened.Validate())
+}
+
+func TestServiceBlock(t *testing.T) {
+	s, dir := newTestService(t)
+	defer os.RemoveAll(dir)
+	mined, err := s.Mine()
+	assert.Nil(t, err)
+
+	block, err := s.Block("1")
+	assert.Nil(t, err)
+	assert.Equal(t, mined.Hash, block.Hash)
+	block, err = s.Block(mined.Hash)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), block.Index)
+
+	for _, ref := range []string{"2", "-1", "unknown", ""} {
+		_, err = s.Block(ref)
+		assert.NotNil(t, err, ref)
+	}
+}
+
+func TestServiceErrors(t *testing.T) {
+	s, dir := newTestService(t)
+	defer os.RemoveAll(dir)
+	_, err := s.SendTx("", 1.0)
+	assert.NotNil(t, err)
+	_, err = s.SendTx("receiverhash", -1.0)
+	assert.NotNil(t, err)
+	_, err = s.Tx("unknown")
+	assert.NotNil(t, err)
+
+	_, err = OpenService(config.Config{DataDir: dir + strconv.Itoa(1)})
+	assert.NotNil(t, err)
+	_, err = InitService(config.Config{DataDir: dir, User: config.User{Address: "someone"}})
+	assert.NotNil(t, err)
+}
+
+func TestServiceKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "onebox")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	priv, _ := keys.Generate()
+	cfg := testConfig(t)
+	cfg.DataDir = dir
+	cfg.Key = filepath.Join(dir, "validator.key")
+	assert.Nil(t, keys.Save(cfg.Key, priv))
+	spec := genesis.Default(cfg.Init)
+	spec.Consensus = genesis.Consensus{Engine: genesis.EnginePoA, Signers: []string{keys.Public(priv)}}
+
+	// the key of the config signs the blocks
+	s, err := InitService(cfg, blockchain.WithGenesis(*spec))
+	assert.Nil(t, err)
+	block, err := s.Mine()
+	assert.Nil(t, err)
+	assert.Equal(t, keys.Public(priv), block.Signer)
+
+	s, err = OpenService(cfg)
+	assert.Nil(t, err)
+	_, err = s.Mine()
+	assert.Nil(t, err)
+	assert.Nil(t, s.Validate())
+
+	// a node without the key only verifies
+	s, err = OpenService(config.Config{DataDir: dir})
+	assert.Nil(t, err)
+	_, err = s.Mine()
+	assert.NotNil(t, err)
+	assert.Nil(t, s.Validate())
+}
+
+func TestServiceUser(t *testing.T) {
+	_, dir := newTestService(t)
+	defer os.RemoveAll(dir)
+
+	// the user of the config sends from its own wallet on the same chain
+	s, err := OpenService(config.Config{DataDir: dir, User: config.User{Address: "00000000000000000000000000000001"}})
+	assert.Nil(t, err)
+	id, err := s.SendTx("receiverhash", 30.0)
+	assert.Nil(t, err)
+	_, err = s.Mine()
+	assert.Nil(t, err)
+
+	tx, err := s.Tx(id)
+	assert.Nil(t, err)
+	assert.Equal(t, "00000000000000000000000000000001", tx.Sender)
+	bal, _ := s.Balance("00000000000000000000000000000001")
+	assert.Equal(t, 70.0, bal)
+	bal, _ = s.Balance("00000000000000000000000000000000")
+	assert.Equal(t, 100.0, bal)
+
+	// the chain keeps its own user
+	s, err = OpenService(config.Config{DataDir: dir})
+	assert.Nil(t, err)
+	info, _ := s.ChainInfo()
+	assert.Equal(t, "00000000000000000000000000000000", info.User)
+}
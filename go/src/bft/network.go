@@ -0,0 +1,178 @@
+package bft
+
+// SimNetwork runs the validators in-process, delivering their messages in order and their timeouts in simulated time
+// Everything happens in Run, one message or timeout at a time, so a run is deterministic and repeatable. Nodes can
+// crash, and the network can be partitioned: the messages across are held back until it heals, as the validators
+// eventually get them by gossip.
+import (
+	"fmt"
+	"proto"
+	"sort"
+	"sync"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+type SimNetwork struct {
+	sync.Mutex
+	nodes   map[string]*Node
+	ids     []string // sorted, the order of delivery of a broadcast
+	queue   []delivery
+	timers  []timer
+	now     int // simulated time, in ticks
+	seq     int // of the timers, to fire the ones due at the same tick in order
+	crashed map[string]bool
+	split   func(from, to string) bool // tells if the message is held back by the partition, if any
+	held    []delivery
+}
+
+type delivery struct {
+	to string
+	m  *Message
+}
+
+type timer struct {
+	due, seq int
+	to       string
+	t        Timeout
+}
+
+func NewSimNetwork() *SimNetwork {
+	return &SimNetwork{nodes: make(map[string]*Node), crashed: make(map[string]bool)}
+}
+
+// Join adds the node to the network
+func (s *SimNetwork) Join(n *Node) {
+	s.Lock()
+	defer s.Unlock()
+	s.nodes[n.ID()] = n
+	s.ids = append(s.ids, n.ID())
+	sort.Strings(s.ids)
+}
+
+// Crash stops the node: it neither sends nor receives anything anymore
+func (s *SimNetwork) Crash(id string) {
+	s.Lock()
+	defer s.Unlock()
+	s.crashed[id] = true
+}
+
+// Broadcast queues a copy of the message for every node, the sender included
+func (s *SimNetwork) Broadcast(from string, m *Message) {
+	s.Lock()
+	defer s.Unlock()
+	if s.crashed[from] {
+		return
+	}
+
+	for _, to := range s.ids {
+		if s.crashed[to] {
+			continue
+		}
+
+		c := &Message{}
+		if m.Proposal != nil {
+			c.Proposal = proto.Clone(m.Proposal).(*pb.Proposal)
+		}
+		if m.Vote != nil {
+			c.Vote = proto.Clone(m.Vote).(*pb.ConsensusVote)
+		}
+		if s.split != nil && s.split(from, to) {
+			s.held = append(s.held, delivery{to, c})
+		} else {
+			s.queue = append(s.queue, delivery{to, c})
+		}
+	}
+}
+
+// Partition holds back the messages split returns true for, until Heal
+func (s *SimNetwork) Partition(split func(from, to string) bool) {
+	s.Lock()
+	defer s.Unlock()
+	s.split = split
+}
+
+// Heal ends the partition, and delivers the messages held back
+func (s *SimNetwork) Heal() {
+	s.Lock()
+	defer s.Unlock()
+	s.split = nil
+	s.queue = append(s.queue, s.held...)
+	s.held = nil
+}
+
+// Schedule fires the timeout after its duration, which grows with the round so that the rounds eventually last long
+// enough for the messages to get through
+func (s *SimNetwork) Schedule(to string, t Timeout) {
+	s.Lock()
+	defer s.Unlock()
+	s.seq++
+	s.timers = append(s.timers, timer{due: s.now + Duration(t), seq: s.seq, to: to, t: t})
+}
+
+// Duration returns the duration of the timeout in ticks
+func Duration(t Timeout) int {
+	base := 1
+	if t.Step == StepPropose {
+		base = 3
+	}
+	return base + int(t.Round)
+}
+
+// Run delivers the queued messages, and the earliest timeout when none is left, until done returns true.
+// It fails if nothing is left to deliver or after max steps.
+func (s *SimNetwork) Run(done func() bool, max int) error {
+	for step := 0; !done(); step++ {
+		if step == max {
+			return fmt.Errorf("Not done after %d steps", max)
+		}
+
+		d, t, ok := s.next()
+		if !ok {
+			return fmt.Errorf("Nothing left to deliver")
+		}
+		if d != nil {
+			// invalid messages are ignored, as a node would
+			s.nodes[d.to].Receive(d.m)
+		} else {
+			s.nodes[t.to].Timeout(t.t)
+		}
+	}
+	return nil
+}
+
+// next pops the next message, or else the earliest timeout and moves the time to it
+func (s *SimNetwork) next() (*delivery, *timer, bool) {
+	s.Lock()
+	defer s.Unlock()
+	for len(s.queue) > 0 {
+		d := s.queue[0]
+		s.queue = s.queue[1:]
+		if !s.crashed[d.to] {
+			return &d, nil, true
+		}
+	}
+
+	for len(s.timers) > 0 {
+		sort.Slice(s.timers, func(i, j int) bool {
+			a, b := s.timers[i], s.timers[j]
+			return a.due < b.due || (a.due == b.due && a.seq < b.seq)
+		})
+		t := s.timers[0]
+		s.timers = s.timers[1:]
+		if t.due > s.now {
+			s.now = t.due
+		}
+		if !s.crashed[t.to] {
+			return nil, &t, true
+		}
+	}
+	return nil, nil, false
+}
+
+// Now returns the simulated time, in ticks
+func (s *SimNetwork) Now() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.now
+}
@@ -0,0 +1,382 @@
+package bft
+
+// Tendermint rounds among the validators of a chain with the bft engine, see consensus.BFT
+// Every height runs rounds until a block is committed: the proposer of the round proposes a block, the validators
+// prevote for it if it's valid and they aren't locked on another block, precommit for it and lock on it once more
+// than 2/3 prevoted for it, and commit it once more than 2/3 precommitted for it. Else the timeouts move them to the
+// next round. A Node is a state machine driven by the messages and the timeouts its Network delivers, and follows
+// "The latest gossip on BFT consensus" by Buchman, Kwon and Milosevic.
+import (
+	"blockchain"
+	"consensus"
+	"crypto/ed25519"
+	"fmt"
+	"keys"
+	"proto"
+	"sort"
+	"sync"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// Step of a round
+type Step int
+
+const (
+	StepPropose Step = iota
+	StepPrevote
+	StepPrecommit
+)
+
+// Timeout of a step of a round, which the Network delivers back to the node that scheduled it
+type Timeout struct {
+	Height int32
+	Round  int32
+	Step   Step
+}
+
+// Message between the validators, either a proposal or a vote
+type Message struct {
+	Proposal *pb.Proposal
+	Vote     *pb.ConsensusVote
+}
+
+// Network delivers the messages of a node to all the validators, itself included, and its timeouts back to it
+type Network interface {
+	Broadcast(from string, m *Message)
+	Schedule(to string, t Timeout)
+}
+
+type Node struct {
+	sync.Mutex
+	chain      *blockchain.BlockChain
+	key        ed25519.PrivateKey
+	id         string // public key
+	net        Network
+	validators []string
+
+	height      int32 // of the block being agreed on
+	round       int32
+	step        Step
+	lockedRound int32 // -1 if not locked
+	lockedBlock *pb.Block
+	validRound  int32 // of the last block seen with more than 2/3 of prevotes, -1 if none
+	validBlock  *pb.Block
+	proposals   map[int32]*pb.Proposal                   // by round
+	votes       map[voteKey]map[string]*pb.ConsensusVote // by validator
+	senders     map[int32]map[string]bool                // of any message, by round
+	valid       map[string]error                         // result of checking the proposed blocks, by ID
+	done        map[string]bool                          // rules applied at most once per round
+	future      []*Message                               // of the next heights
+}
+
+type voteKey struct {
+	typ   string
+	round int32
+}
+
+// NewNode runs the rounds of the next blocks of the chain, as the validator with the key, which must be the key of
+// the chain too, so that the node signs the blocks it proposes
+func NewNode(chain *blockchain.BlockChain, key ed25519.PrivateKey, net Network) (*Node, error) {
+	if chain.Spec().Consensus.Engine != "bft" {
+		return nil, fmt.Errorf("The chain has the %s engine, not bft", chain.Spec().Consensus.Engine)
+	}
+
+	validators, err := chain.Validators()
+	if err != nil {
+		return nil, err
+	}
+
+	n := &Node{chain: chain, key: key, id: keys.Public(key), net: net, validators: validators}
+	if !contains(validators, n.id) {
+		return nil, fmt.Errorf("%s isn't a validator", n.id)
+	}
+
+	n.newHeight(int32(len(chain.Blocks)))
+	return n, nil
+}
+
+// ID returns the public key of the validator
+func (n *Node) ID() string {
+	return n.id
+}
+
+// Height returns the height of the block being agreed on, the height of the chain
+func (n *Node) Height() int32 {
+	n.Lock()
+	defer n.Unlock()
+	return n.height
+}
+
+// Start starts the first round
+func (n *Node) Start() {
+	n.Lock()
+	defer n.Unlock()
+	n.startRound(0)
+	n.apply()
+}
+
+// Receive handles a message of a validator, and returns why it's invalid if it is
+func (n *Node) Receive(m *Message) error {
+	n.Lock()
+	defer n.Unlock()
+	if err := n.receive(m); err != nil {
+		return err
+	}
+
+	n.apply()
+	return nil
+}
+
+// Timeout handles a timeout the node scheduled
+func (n *Node) Timeout(t Timeout) {
+	n.Lock()
+	defer n.Unlock()
+	if t.Height != n.height || t.Round != n.round {
+		return
+	}
+
+	switch {
+	case t.Step == StepPropose && n.step == StepPropose:
+		n.vote(consensus.Prevote, "")
+	case t.Step == StepPrevote && n.step == StepPrevote:
+		n.vote(consensus.Precommit, "")
+	case t.Step == StepPrecommit:
+		n.startRound(n.round + 1)
+	}
+	n.apply()
+}
+
+func (n *Node) receive(m *Message) error {
+	var height, round int32
+	var sender string
+	switch {
+	case m.Proposal != nil:
+		height, round, sender = m.Proposal.Height, m.Proposal.Round, m.Proposal.Proposer
+	case m.Vote != nil:
+		height, round, sender = m.Vote.Height, m.Vote.Round, m.Vote.Validator
+	default:
+		return fmt.Errorf("Empty message")
+	}
+	if height < n.height {
+		return nil
+	}
+	if height > n.height {
+		n.future = append(n.future, m)
+		return nil
+	}
+
+	if m.Proposal != nil {
+		if err := consensus.CheckProposal(n.validators, m.Proposal); err != nil {
+			return err
+		}
+		if _, ok := n.proposals[round]; !ok {
+			n.proposals[round] = m.Proposal
+		}
+	} else {
+		if err := consensus.CheckVote(n.validators, m.Vote); err != nil {
+			return err
+		}
+
+		k := voteKey{m.Vote.Type, round}
+		if n.votes[k] == nil {
+			n.votes[k] = make(map[string]*pb.ConsensusVote)
+		}
+		// a second vote of a validator in the step is equivocation, the first one counts
+		if _, ok := n.votes[k][sender]; !ok {
+			n.votes[k][sender] = m.Vote
+		}
+	}
+
+	if n.senders[round] == nil {
+		n.senders[round] = make(map[string]bool)
+	}
+	n.senders[round][sender] = true
+	return nil
+}
+
+// apply applies the rules of the algorithm that hold after a message or a timeout
+func (n *Node) apply() {
+	quorum := consensus.Quorum(n.validators)
+
+	// a block with a quorum of precommits in any round is committed
+	for round, p := range n.proposals {
+		if id := blockID(p.Block); n.count(consensus.Precommit, round, id) >= quorum && n.isValid(p.Block) {
+			n.commit(p.Block, round, id)
+			return
+		}
+	}
+
+	// more than 1/3 of the validators are in a later round, at least one of them honest
+	for round, senders := range n.senders {
+		if round > n.round && len(senders) >= len(n.validators)-quorum+1 {
+			n.startRound(round)
+			n.apply()
+			return
+		}
+	}
+
+	p := n.proposals[n.round]
+	id := ""
+	if p != nil {
+		id = blockID(p.Block)
+	}
+	if p != nil && n.step == StepPropose {
+		switch {
+		case p.ValidRound == -1:
+			if n.isValid(p.Block) && (n.lockedRound == -1 || blockID(n.lockedBlock) == id) {
+				n.vote(consensus.Prevote, id)
+			} else {
+				n.vote(consensus.Prevote, "")
+			}
+		case p.ValidRound >= 0 && p.ValidRound < n.round && n.count(consensus.Prevote, p.ValidRound, id) >= quorum:
+			if n.isValid(p.Block) && (n.lockedRound <= p.ValidRound || blockID(n.lockedBlock) == id) {
+				n.vote(consensus.Prevote, id)
+			} else {
+				n.vote(consensus.Prevote, "")
+			}
+		}
+	}
+
+	if n.step == StepPrevote && n.total(consensus.Prevote, n.round) >= quorum && n.once("prevote timeout") {
+		n.net.Schedule(n.id, Timeout{n.height, n.round, StepPrevote})
+	}
+	if p != nil && n.step >= StepPrevote && n.count(consensus.Prevote, n.round, id) >= quorum &&
+		n.isValid(p.Block) && n.once("lock") {
+		if n.step == StepPrevote {
+			n.lockedBlock, n.lockedRound = p.Block, n.round
+			n.vote(consensus.Precommit, id)
+		}
+		n.validBlock, n.validRound = p.Block, n.round
+	}
+	if n.step == StepPrevote && n.count(consensus.Prevote, n.round, "") >= quorum {
+		n.vote(consensus.Precommit, "")
+	}
+	if n.total(consensus.Precommit, n.round) >= quorum && n.once("precommit timeout") {
+		n.net.Schedule(n.id, Timeout{n.height, n.round, StepPrecommit})
+	}
+}
+
+func (n *Node) startRound(round int32) {
+	n.round, n.step = round, StepPropose
+	n.done = make(map[string]bool)
+	n.net.Schedule(n.id, Timeout{n.height, round, StepPropose})
+	if consensus.Proposer(n.validators, n.height, round) != n.id {
+		return
+	}
+
+	block := n.validBlock
+	if block == nil {
+		var err error
+		if block, err = n.chain.ProposeBlock(); err != nil {
+			// the others time out
+			return
+		}
+	}
+	if p, err := consensus.NewProposal(n.key, block, round, n.validRound); err == nil {
+		n.net.Broadcast(n.id, &Message{Proposal: p})
+	}
+}
+
+// vote broadcasts the vote of the node for the block ID or none, and moves to the next step
+func (n *Node) vote(typ, id string) {
+	if v, err := consensus.NewVote(n.key, typ, n.height, n.round, id); err == nil {
+		n.net.Broadcast(n.id, &Message{Vote: v})
+	}
+
+	n.step = StepPrevote
+	if typ == consensus.Precommit {
+		n.step = StepPrecommit
+	}
+}
+
+// commit adds the block with its certificate to the chain, and starts the next height
+func (n *Node) commit(block *pb.Block, round int32, id string) {
+	c := &pb.Commit{Height: n.height, Round: round, BlockId: id}
+	for _, v := range n.votes[voteKey{consensus.Precommit, round}] {
+		if v.BlockId == id {
+			c.Precommits = append(c.Precommits, v)
+		}
+	}
+	sort.Slice(c.Precommits, func(i, j int) bool { return c.Precommits[i].Validator < c.Precommits[j].Validator })
+
+	committed := proto.Clone(block).(*pb.Block)
+	committed.Commit = c
+	if err := n.chain.AddBlock(committed); err != nil {
+		// the block was checked, so the chain changed under the node, which can't go on
+		return
+	}
+
+	n.newHeight(n.height + 1)
+	n.startRound(0)
+	future := n.future
+	n.future = nil
+	for _, m := range future {
+		n.receive(m)
+	}
+	n.apply()
+}
+
+func (n *Node) newHeight(height int32) {
+	n.height = height
+	n.lockedRound, n.lockedBlock = -1, nil
+	n.validRound, n.validBlock = -1, nil
+	n.proposals = make(map[int32]*pb.Proposal)
+	n.votes = make(map[voteKey]map[string]*pb.ConsensusVote)
+	n.senders = make(map[int32]map[string]bool)
+	n.valid = make(map[string]error)
+}
+
+// count returns the number of votes of the type in the round for the block ID
+func (n *Node) count(typ string, round int32, id string) int {
+	c := 0
+	for _, v := range n.votes[voteKey{typ, round}] {
+		if v.BlockId == id {
+			c++
+		}
+	}
+	return c
+}
+
+// total returns the number of votes of the type in the round, for any block or none
+func (n *Node) total(typ string, round int32) int {
+	return len(n.votes[voteKey{typ, round}])
+}
+
+func (n *Node) isValid(block *pb.Block) bool {
+	id := blockID(block)
+	err, ok := n.valid[id]
+	if !ok {
+		err = n.chain.CheckProposal(block)
+		n.valid[id] = err
+	}
+	return err == nil
+}
+
+// once tells if the rule wasn't applied yet in the round
+func (n *Node) once(rule string) bool {
+	if n.done[rule] {
+		return false
+	}
+
+	n.done[rule] = true
+	return true
+}
+
+func blockID(block *pb.Block) string {
+	if block == nil {
+		return ""
+	}
+
+	id, _ := consensus.BlockID(block)
+	return id
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package bft
+
+import (
+	"blockchain"
+	"config"
+	"consensus"
+	"crypto/ed25519"
+	"genesis"
+	"keys"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestNodes returns n validators of a bft chain on a simulated network, sorted by key like the proposers
+func newTestNodes(t *testing.T, n int) ([]*Node, *SimNetwork) {
+	privs := make([]ed25519.PrivateKey, n)
+	signers := make([]string, n)
+	for i := range privs {
+		privs[i], _ = keys.Generate()
+		signers[i] = keys.Public(privs[i])
+	}
+	spec := genesis.Default(config.Accounts{{Address: "alice", Val: 10}})
+	spec.Consensus = genesis.Consensus{Engine: genesis.EngineBFT, Signers: signers}
+
+	net := NewSimNetwork()
+	nodes := make([]*Node, n)
+	for i, priv := range privs {
+		bc, err := blockchain.NewBlockChain(blockchain.WithGenesis(*spec), blockchain.WithUser("alice"),
+			blockchain.WithKey(priv))
+		assert.Nil(t, err)
+		nodes[i], err = NewNode(bc, priv, net)
+		assert.Nil(t, err)
+		net.Join(nodes[i])
+	}
+
+	validators, _ := nodes[0].chain.Validators()
+	for i, id := range validators {
+		for j := range nodes {
+			if nodes[j].ID() == id {
+				nodes[i], nodes[j] = nodes[j], nodes[i]
+			}
+		}
+	}
+	return nodes, net
+}
+
+func start(nodes []*Node) {
+	for _, n := range nodes {
+		n.Start()
+	}
+}
+
+// reached returns a condition of the nodes being all at the height
+func reached(nodes []*Node, height int32) func() bool {
+	return func() bool {
+		for _, n := range nodes {
+			if n.Height() < height {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// assertAgree asserts that the chains of the nodes hold the same committed blocks, and are valid
+func assertAgree(t *testing.T, nodes []*Node, height int32) {
+	for _, n := range nodes {
+		assert.Nil(t, n.chain.Validate())
+		for i := int32(1); i < height; i++ {
+			block := n.chain.Blocks[i]
+			assert.Equal(t, nodes[0].chain.Blocks[i].Hash, block.Hash)
+			assert.NotNil(t, block.Commit)
+			assert.True(t, len(block.Commit.Precommits) >= consensus.Quorum(n.validators))
+		}
+	}
+}
+
+func TestCommit(t *testing.T) {
+	nodes, net := newTestNodes(t, 4)
+	// the transaction of the proposer of height 1 gets to every chain
+	nodes[1].chain.AddTransactionFrom("alice", "bob", 3)
+
+	start(nodes)
+	assert.Nil(t, net.Run(reached(nodes, 4), 10000))
+	assertAgree(t, nodes, 4)
+	for _, n := range nodes {
+		// all committed in the first round, by the proposers in turn
+		for i := int32(1); i < 4; i++ {
+			assert.Equal(t, int32(0), n.chain.Blocks[i].Commit.Round)
+			assert.Equal(t, nodes[i%4].ID(), n.chain.Blocks[i].Signer)
+		}
+		assert.Equal(t, 3.0, n.chain.GetBalance("bob"))
+	}
+}
+
+func TestCrashedProposer(t *testing.T) {
+	nodes, net := newTestNodes(t, 4)
+	// the proposer of round 0 at height 1
+	net.Crash(nodes[1].ID())
+
+	start(nodes)
+	live := []*Node{nodes[0], nodes[2], nodes[3]}
+	assert.Nil(t, net.Run(reached(live, 3), 10000))
+	assertAgree(t, live, 3)
+	assert.Equal(t, int32(1), nodes[0].chain.Blocks[1].Commit.Round)
+	assert.Equal(t, nodes[2].ID(), nodes[0].chain.Blocks[1].Signer)
+	assert.Equal(t, int32(1), nodes[1].Height())
+}
+
+func TestNoQuorum(t *testing.T) {
+	nodes, net := newTestNodes(t, 4)
+	net.Crash(nodes[2].ID())
+	net.Crash(nodes[3].ID())
+
+	start(nodes)
+	assert.NotNil(t, net.Run(reached(nodes[:2], 2), 2000))
+	for _, n := range nodes {
+		assert.Equal(t, int32(1), n.Height())
+		assert.Equal(t, 1, len(n.chain.Blocks))
+	}
+}
+
+func TestPartition(t *testing.T) {
+	nodes, net := newTestNodes(t, 4)
+	side := func(id string) bool { return id == nodes[0].ID() || id == nodes[1].ID() }
+	net.Partition(func(from, to string) bool { return side(from) != side(to) })
+
+	// neither half commits
+	start(nodes)
+	assert.NotNil(t, net.Run(reached(nodes, 2), 2000))
+	for _, n := range nodes {
+		assert.Equal(t, int32(1), n.Height())
+	}
+
+	// once healed, the rounds catch up and the same blocks are committed everywhere
+	net.Heal()
+	assert.Nil(t, net.Run(reached(nodes, 3), 10000))
+	assertAgree(t, nodes, 3)
+	assert.True(t, nodes[0].chain.Blocks[1].Commit.Round > 0)
+}
+
+func TestFinality(t *testing.T) {
+	nodes, net := newTestNodes(t, 4)
+	start(nodes)
+	assert.Nil(t, net.Run(reached(nodes, 2), 10000))
+
+	// a committed block is never replaced, not even by a committed one
+	other := nodes[1].chain
+	block, err := other.ProposeBlock()
+	assert.Nil(t, err)
+	block.Index, block.PrevHash = 1, nodes[0].chain.Blocks[0].Hash
+	assert.NotNil(t, nodes[0].chain.AddBlock(block))
+	assert.Equal(t, 2, len(nodes[0].chain.Blocks))
+
+	// nor can a block be added without a certificate
+	assert.NotNil(t, nodes[0].chain.MineBlock())
+	assert.Equal(t, 2, len(nodes[0].chain.Blocks))
+	forged := *nodes[0].chain.Blocks[1]
+	forged.Commit = nil
+	assert.NotNil(t, consensus.CheckCommit(nodes[0].validators, &forged))
+}
+
+func TestInvalidMessages(t *testing.T) {
+	nodes, _ := newTestNodes(t, 4)
+	block, err := nodes[1].chain.ProposeBlock()
+	assert.Nil(t, err)
+
+	// only the proposer of the round proposes
+	p, err := consensus.NewProposal(nodes[2].key, block, 0, -1)
+	assert.Nil(t, err)
+	assert.NotNil(t, nodes[0].Receive(&Message{Proposal: p}))
+	p, err = consensus.NewProposal(nodes[1].key, block, 0, -1)
+	assert.Nil(t, err)
+	assert.Nil(t, nodes[0].Receive(&Message{Proposal: p}))
+
+	// only the validators vote
+	outsider, _ := keys.Generate()
+	v, err := consensus.NewVote(outsider, consensus.Prevote, 1, 0, "")
+	assert.Nil(t, err)
+	assert.NotNil(t, nodes[0].Receive(&Message{Vote: v}))
+	v, err = consensus.NewVote(nodes[3].key, consensus.Prevote, 1, 0, "")
+	assert.Nil(t, err)
+	v.Round = 1
+	assert.NotNil(t, nodes[0].Receive(&Message{Vote: v}))
+	assert.NotNil(t, nodes[0].Receive(&Message{}))
+
+	_, err = NewNode(nodes[0].chain, outsider, nil)
+	assert.NotNil(t, err)
+}
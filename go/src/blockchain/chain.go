@@ -1,10 +1,15 @@
 package blockchain
 
 import (
-	"config"
+	"consensus"
+	"context"
+	"errors"
 	"fmt"
+	"genesis"
 	"merkle"
 	"proto"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 	"utils"
@@ -16,54 +21,154 @@ import (
 type BlockChain struct {
 	sync.RWMutex
 	pb.Chain
+	spec      *genesis.Spec // parameters of the chain, decoded from GenesisSpec
+	engine    consensus.Engine
+	tip       context.Context // done once a block is added on the last one, see tipContext
+	newTip    context.CancelFunc
+	templates map[string]*pb.Block // of the next block for external miners, by work, see GetWork
+	states    sync.Map             // opened balances of the blocks, by block hash, see openBalances
+	stakes    sync.Map             // stakes after each block, by block hash, see stakesAfter
 }
 
-// NewBlockChain creates a new blockchain object
-// addr is the user's addr
-func NewBlockChain() *BlockChain {
-	config.InitConfig("../config/config.json")
-	bc := &BlockChain{}
-	bc.Usr = &pb.User{Addr: config.Usrcfg.Address}
-	bc.Difficulty = 1
+// ErrStale is the error of mining a block on a parent that isn't the last block anymore
+var ErrStale = errors.New("A competing block was added while mining")
+
+// NewBlockChain creates a new blockchain object with its genesis block, configured by the options
+func NewBlockChain(opts ...Option) (*BlockChain, error) {
+	o := &options{}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg, err := o.config()
+	if err != nil {
+		return nil, err
+	}
+
+	spec, err := o.genesis(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	bc := &BlockChain{spec: spec}
+	if bc.engine, err = o.consensus(spec, cfg); err != nil {
+		return nil, err
+	}
+	if bc.GenesisSpec, err = spec.Encode(); err != nil {
+		return nil, err
+	}
+	bc.Usr = &pb.User{Addr: cfg.User.Address}
+	bc.Difficulty = spec.Difficulty
 	// Genesis is for initial starting block including starting balances
-	genesis := initBlock()
-	bc.Blocks = append(bc.Blocks, genesis)
-	return bc
+	block, err := spec.Block()
+	if err != nil {
+		return nil, err
+	}
+
+	bc.Blocks = append(bc.Blocks, block)
+	return bc, nil
+}
+
+// Spec returns the genesis spec of the chain
+func (bc *BlockChain) Spec() *genesis.Spec {
+	return bc.spec
 }
 
-func initBlock() *pb.Block {
-	genesis := &pb.Block{
-		Index: 0,
-		Proof: 0,
+// MineBlock adds open transactions to the blockchain after validation, as many as fit in a block.
+// The block is sealed by the consensus engine without holding the lock, so the chain stays readable meanwhile.
+func (bc *BlockChain) MineBlock() error {
+	return bc.MineBlockContext(context.Background())
+}
+
+// MineBlockContext is MineBlock until the context is done, and fails with ErrStale as soon as another block is
+// added meanwhile, e.g. received from another node
+func (bc *BlockChain) MineBlockContext(ctx context.Context) error {
+	block, err := bc.ProposeBlockContext(ctx, 0)
+	if err != nil {
+		return err
 	}
-	status := merkle.NewPatriciaTrie()
-	for _, acc := range config.InitialAccounts {
-		status.Upsert(acc.Address, fmt.Sprintf("%f", acc.Val))
+
+	bc.RWMutex.Lock()
+	defer bc.RWMutex.Unlock()
+	return bc.addNewBlock(block, true)
+}
+
+// ProposeBlock makes and seals the next block, without adding it, e.g. to have it committed by the validators first
+func (bc *BlockChain) ProposeBlock() (*pb.Block, error) {
+	return bc.ProposeBlockContext(context.Background(), 0)
+}
+
+// ProposeBlockContext is ProposeBlock with at most maxTxs open transactions, no limit if 0, until the context is
+// done, and fails with ErrStale as soon as another block is added meanwhile
+func (bc *BlockChain) ProposeBlockContext(ctx context.Context, maxTxs int) (*pb.Block, error) {
+	bc.RWMutex.Lock()
+	block, err := bc.newBlock(maxTxs)
+	chain := bc.snapshot(len(bc.Blocks))
+	tip := bc.tipContext()
+	bc.RWMutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	stop := context.AfterFunc(tip, func() { cancel(ErrStale) })
+	defer stop()
+	if err := bc.engine.Seal(ctx, chain, block); err != nil {
+		if ctx.Err() != nil {
+			return nil, context.Cause(ctx)
+		}
+		return nil, err
 	}
-	genesis.Balances = &status.Tree
-	return genesis
+
+	// the seal may change the header, e.g. with the proof of work
+	block.Hash = utils.HashBlock(block)
+	return block, nil
 }
 
-// MineBlock adds open transactions to the blockchain after validation
-func (bc *BlockChain) MineBlock() {
-	proof := pow(bc.Blocks[len(bc.Blocks)-1].Proof, int(bc.Difficulty))
+// HashRate returns the hashes per second of the current or last mining, 0 if the engine doesn't mine
+func (bc *BlockChain) HashRate() float64 {
+	if m, ok := bc.engine.(consensus.Miner); ok {
+		return m.HashRate()
+	}
+	return 0
+}
+
+// tipContext returns a context done once a block is added on the last block, under the lock
+func (bc *BlockChain) tipContext() context.Context {
+	if bc.tip == nil {
+		bc.tip, bc.newTip = context.WithCancel(context.Background())
+	}
+	return bc.tip
+}
+
+// AddBlock adds a sealed block on top of the chain, e.g. a block made by another node and committed. Its
+// transactions are executed again, which must result in the transactions and balances of the block.
+func (bc *BlockChain) AddBlock(block *pb.Block) error {
 	bc.RWMutex.Lock()
 	defer bc.RWMutex.Unlock()
-	bc.addNewBlock(proof)
-	bc.OpenTxs = []*pb.Transaction{}
+	return bc.addNewBlock(block, false)
 }
 
 // AddTransaction creates a new transaction and add it to the open Txs list
 func (bc *BlockChain) AddTransaction(recipient string, val float64) string {
+	return bc.AddTransactionFrom(bc.Usr.Addr, recipient, val)
+}
+
+// AddTransactionFrom is AddTransaction from another sender than the user, e.g. another wallet
+func (bc *BlockChain) AddTransactionFrom(sender, recipient string, val float64) string {
 	tx := &pb.Transaction{
-		Sender:    bc.Usr.Addr,
+		Sender:    sender,
 		Recipient: recipient,
 		Val:       val,
 		Timestamp: time.Now().UnixNano(),
 		Status:    "pending",
+		ChainId:   bc.spec.ChainID,
 	}
 
-	tx.Id, _ = utils.Hash(tx)
+	tx.Id, _ = TxID(tx)
 	bc.RWMutex.Lock()
 	defer bc.RWMutex.Unlock()
 	bc.OpenTxs = append(bc.OpenTxs, tx)
@@ -74,14 +179,9 @@ func (bc *BlockChain) AddTransaction(recipient string, val float64) string {
 func (bc *BlockChain) GetTransaction(id string) *pb.Transaction {
 	for _, block := range bc.Blocks {
 		if block.Txs != nil {
-			t := merkle.NewPatriciaTrie()
-			t.Tree = *block.Txs
-			if v, ok := t.Get(id); ok {
-				var tx pb.Transaction
-				err := proto.Unmarshal([]byte(v), &tx)
-				if err == nil {
-					return &tx
-				}
+			txs := merkle.NewTypedTrie[*pb.Transaction](merkle.LoadPatriciaTrie(block.Txs), TxCodec)
+			if tx, err := txs.Get(id); err == nil {
+				return tx
 			}
 		}
 	}
@@ -91,12 +191,21 @@ func (bc *BlockChain) GetTransaction(id string) *pb.Transaction {
 
 // GetBalance retrieves the transaction from the merkle trie
 func (bc *BlockChain) GetBalance(acc string) float64 {
-	for i := len(bc.Blocks) - 1; i >= 0; i-- {
-		block := bc.Blocks[i]
+	return bc.balanceIn(bc.Blocks, acc)
+}
+
+// balanceIn returns the balance of the account after the last of the blocks, which hold the changed balances only
+func (bc *BlockChain) balanceIn(blocks []*pb.Block, acc string) float64 {
+	for i := len(blocks) - 1; i >= 0; i-- {
+		block := blocks[i]
 		if block.Balances != nil {
-			t := merkle.NewPatriciaTrie()
-			t.Tree = *block.Balances
-			if v, ok := t.GetFloat(acc); ok {
+			t, err := bc.openBalances(block)
+			if err != nil {
+				continue
+			}
+
+			bals := merkle.NewTypedTrie[float64](t, merkle.FloatCodec{})
+			if v, err := bals.Get(acc); err == nil {
 				return v
 			}
 		}
@@ -105,69 +214,294 @@ func (bc *BlockChain) GetBalance(acc string) float64 {
 	return 0.0
 }
 
-func (bc *BlockChain) addNewBlock(proof int64) {
+// openBalances opens the balances of a block of the chain. The blocks don't change once added, so the opened trees
+// are cached by block hash.
+func (bc *BlockChain) openBalances(block *pb.Block) (merkle.StateTree, error) {
+	if t, ok := bc.states.Load(block.Hash); ok {
+		return t.(merkle.StateTree), nil
+	}
+
+	t, err := merkle.OpenStateTree(block.Balances)
+	if err != nil {
+		return nil, err
+	}
+
+	bc.states.Store(block.Hash, t)
+	return t, nil
+}
+
+// newBlock makes the next block with the open transactions that fit in it, at most maxTxs unless 0, ready to be
+// sealed
+func (bc *BlockChain) newBlock(maxTxs int) (*pb.Block, error) {
 	lastBlock := bc.Blocks[len(bc.Blocks)-1]
 	block := &pb.Block{
 		Index:     lastBlock.Index + 1,
 		Timestamp: time.Now().UnixNano(),
-		Proof:     proof,
 		PrevHash:  lastBlock.Hash,
+		ChainId:   bc.spec.ChainID,
+	}
+	if err := bc.engine.Prepare(bc, block); err != nil {
+		return nil, err
 	}
 
-	txs := merkle.NewPatriciaTrie()
-	balances := merkle.NewPatriciaTrie()
-	bals := make(map[string]float64) // cache the balances to memory
-	for _, tx := range bc.OpenTxs {
-		var sbal, rbal float64
-		var ok bool
-		if sbal, ok = bals[tx.Sender]; !ok {
-			sbal = bc.GetBalance(tx.Sender)
+	var selected []*pb.Transaction
+	size := 0
+	for i, open := range bc.OpenTxs {
+		if maxTxs > 0 && i == maxTxs {
+			break
 		}
-		if rbal, ok = bals[tx.Recipient]; !ok {
-			rbal = bc.GetBalance(tx.Recipient)
+
+		size += txSize(open)
+		if bc.spec.MaxBlockSize > 0 && size > bc.spec.MaxBlockSize {
+			break
+		}
+		selected = append(selected, open)
+	}
+
+	txs, balances, err := bc.execute(bc.snapshot(len(bc.Blocks)), block, selected)
+	if err != nil {
+		return nil, err
+	}
+
+	if block.Txs, err = txs.ToTree(); err != nil {
+		return nil, err
+	}
+	if block.Balances, err = balances.ToTree(); err != nil {
+		return nil, err
+	}
+
+	// hashed before the seal as well, which signs the header rather than the hash
+	block.Hash = utils.HashBlock(block)
+	return block, nil
+}
+
+// execute applies the transactions to the state of the last block of the chain, in the order of their timestamps so
+// that anyone executing them gets the same result, then finalizes the block with the engine
+// rtype - the trie of the transactions, with their status and the records of the engine, the changed balances
+func (bc *BlockChain) execute(chain *prefix, block *pb.Block, txs []*pb.Transaction) (*merkle.PatriciaTrie,
+	merkle.StateTree, error) {
+	txs = append([]*pb.Transaction(nil), txs...)
+	sort.SliceStable(txs, func(i, j int) bool {
+		if txs[i].Timestamp != txs[j].Timestamp {
+			return txs[i].Timestamp < txs[j].Timestamp
 		}
-		if sbal >= tx.Val {
+		return txs[i].Id < txs[j].Id
+	})
+
+	trie := newTxTrie()
+	balances, err := merkle.NewStateTree(bc.spec.StateTree)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	txBatch := merkle.NewTypedTrie[*pb.Transaction](trie, TxCodec).NewBatch()
+	balBatch := merkle.NewTypedTrie[float64](balances, merkle.FloatCodec{}).NewBatch()
+	st := &blockState{chain: chain, bals: make(map[string]float64), credits: make(map[string]float64),
+		slashed: make(map[string]float64)}
+	for _, open := range txs {
+		// the open transaction stays pending until the block is added
+		tx := proto.Clone(open).(*pb.Transaction)
+		if st.apply(tx) {
 			tx.Status = "complete"
-			if data, err := proto.Marshal(tx); err == nil {
-				txs.Upsert(tx.Id, string(data))
-				bals[tx.Sender] = sbal - tx.Val
-				bals[tx.Recipient] = rbal + tx.Val
-			}
 		} else {
 			tx.Status = "failed"
-			if data, err := proto.Marshal(tx); err == nil {
-				txs.Upsert(tx.Id, string(data))
-			}
+		}
+		if err := txBatch.Put(tx.Id, tx); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := bc.engine.Finalize(chain, block, st); err != nil {
+		return nil, nil, err
+	}
+	for _, record := range append(st.coinbases(block), st.slashings(block)...) {
+		if err := txBatch.Put(record.Id, record); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for id, val := range st.bals {
+		if err := balBatch.Put(id, val); err != nil {
+			return nil, nil, err
 		}
 	}
-	for id, val := range bals {
-		balances.UpsertFloat(id, val)
+	if err := txBatch.Commit(); err != nil {
+		return nil, nil, err
+	}
+	if err := balBatch.Commit(); err != nil {
+		return nil, nil, err
+	}
+
+	return trie, balances, nil
+}
+
+// addNewBlock adds the sealed block on top of the chain, and removes its transactions from the open ones. Unless own
+// tells that the node made the block itself, its execution is checked too.
+func (bc *BlockChain) addNewBlock(block *pb.Block, own bool) error {
+	// the blocks are only ever appended, a block never replaces another
+	last := bc.Blocks[len(bc.Blocks)-1]
+	if block.Index != last.Index+1 || block.PrevHash != last.Hash {
+		return fmt.Errorf("Block %d doesn't link to the last block %d anymore", block.Index, last.Index)
+	}
+	// a block made elsewhere may not match its hash, which the next block links to
+	if err := checkIntegrity(block, last); err != nil {
+		return err
+	}
+	if err := bc.CheckBlock(block); err != nil {
+		return err
+	}
+	if err := bc.engine.VerifySeal(bc, block); err != nil {
+		return err
+	}
+	if !own {
+		if err := bc.checkExecution(bc.snapshot(len(bc.Blocks)), block); err != nil {
+			return err
+		}
+	}
+
+	mined := make(map[string]*pb.Transaction)
+	if err := forEachTx(block, func(tx *pb.Transaction) error {
+		mined[tx.Id] = tx
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	open := []*pb.Transaction{}
+	for _, tx := range bc.OpenTxs {
+		if m, ok := mined[tx.Id]; ok {
+			tx.Status = m.Status
+		} else {
+			open = append(open, tx)
+		}
+	}
+
+	evidence := []*pb.Evidence{}
+	for _, ev := range bc.Evidence {
+		if !slashes(block, ev) {
+			evidence = append(evidence, ev)
+		}
 	}
 
-	block.Txs = &txs.Tree
-	block.Balances = &balances.Tree
-	block.Hash = utils.HashBlock(block)
 	bc.Blocks = append(bc.Blocks, block)
+	bc.OpenTxs = open
+	bc.Evidence = evidence
+	if bc.newTip != nil {
+		// the blocks being mined on the previous tip are stale
+		bc.newTip()
+		bc.tip, bc.newTip = nil, nil
+	}
+	bc.templates = nil
+	return nil
+}
+
+// blockState is the state of a new block, the balances it changes, for consensus.State
+type blockState struct {
+	chain   *prefix            // before the block
+	bals    map[string]float64 // cache the balances to memory
+	credits map[string]float64 // paid by the engine
+	slashed map[string]float64 // stakes burnt by the engine
 }
 
-// Validation of sha256(last_proof+proof) has the first N bytes as '0's
-func pow(lastProof int64, difficulty int) int64 {
-	var proof int64 = 0
-	for !IsValidProof(lastProof, proof, difficulty) {
-		proof++
+func (st *blockState) balance(addr string) float64 {
+	if bal, ok := st.bals[addr]; ok {
+		return bal
 	}
 
-	return proof
+	return st.chain.GetBalance(addr)
 }
 
-func IsValidProof(lastProof, proof int64, difficulty int) bool {
-	guess := fmt.Sprintf("%d%d%d", lastProof, proof, time.Now().UnixNano())
-	hash := utils.HashBytes([]byte(guess))
-	for i := 0; i < difficulty; i++ {
-		if hash[i] != '0' {
+// apply applies the transaction to the balances, and tells if it succeeded
+func (st *blockState) apply(tx *pb.Transaction) bool {
+	// the stakes only change by bonding and unbonding
+	if strings.HasPrefix(tx.Sender, genesis.StakePrefix) || strings.HasPrefix(tx.Recipient, genesis.StakePrefix) {
+		return false
+	}
+
+	// only the records of the engine have no sender
+	if len(tx.Sender) == 0 {
+		return false
+	}
+
+	from, to := tx.Sender, tx.Recipient
+	switch tx.Type {
+	case TxTransfer:
+	case TxBond:
+		if !st.chain.staking() || !isValidator(tx.Sender) || tx.Val <= 0 {
 			return false
 		}
+		to = genesis.StakeKey(tx.Sender)
+	case TxUnbond:
+		if tx.Val <= 0 {
+			return false
+		}
+		from, to = genesis.StakeKey(tx.Sender), tx.Sender
+	default:
+		return false
 	}
 
+	bal := st.balance(from)
+	if bal < tx.Val {
+		return false
+	}
+	st.bals[from] = bal - tx.Val
+	// read after the debit, so sending to oneself keeps the balance
+	st.bals[to] = st.balance(to) + tx.Val
 	return true
 }
+
+func (st *blockState) Credit(addr string, val float64) {
+	st.bals[addr] = st.balance(addr) + val
+	st.credits[addr] += val
+}
+
+func (st *blockState) Slash(addr string) float64 {
+	key := genesis.StakeKey(addr)
+	stake := st.balance(key)
+	st.bals[key] = 0
+	st.slashed[addr] += stake
+	return stake
+}
+
+// coinbases returns the transactions recording the credits, one per address
+func (st *blockState) coinbases(block *pb.Block) []*pb.Transaction {
+	var txs []*pb.Transaction
+	for addr, val := range st.credits {
+		coinbase := &pb.Transaction{
+			Recipient: addr,
+			Val:       val,
+			Timestamp: block.Timestamp,
+			Status:    "complete",
+			ChainId:   block.ChainId,
+		}
+		coinbase.Id, _ = TxID(coinbase)
+		txs = append(txs, coinbase)
+	}
+	return txs
+}
+
+// slashings returns the transactions recording the slashed stakes, one per address
+func (st *blockState) slashings(block *pb.Block) []*pb.Transaction {
+	var txs []*pb.Transaction
+	for addr, val := range st.slashed {
+		slashing := &pb.Transaction{
+			Sender:    addr,
+			Val:       val,
+			Timestamp: block.Timestamp,
+			Status:    "complete",
+			ChainId:   block.ChainId,
+			Type:      TxSlash,
+		}
+		slashing.Id, _ = TxID(slashing)
+		txs = append(txs, slashing)
+	}
+	return txs
+}
+
+// TxCodec stores the transactions of a block as protobuf messages
+var TxCodec = merkle.NewProtoCodec(func() *pb.Transaction { return &pb.Transaction{} })
+
+// newTxTrie creates the trie of a block's transactions, keyed by id with the serialized transactions compressed
+func newTxTrie() *merkle.PatriciaTrie {
+	return merkle.NewPatriciaTrieWithCodec(merkle.Identity, merkle.Snappy)
+}
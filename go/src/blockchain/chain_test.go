@@ -1,29 +1,53 @@
 package blockchain
 
 import (
+	"config"
+	"consensus"
+	"context"
+	"genesis"
+	"keys"
 	"math/rand"
+	"merkle"
+	"proto"
 	"testing"
+	"time"
 	"utils"
 
+	"github.com/gogo/protobuf/proto"
 	"github.com/stretchr/testify/assert"
 )
 
+// testConfig is the config of the test chains, with the user 0...0 and 100 on each of the accounts 0...0 to 0...2
+const testConfig = "../config/config.json"
+
+func newTestChain(t testing.TB, opts ...Option) *BlockChain {
+	bc, err := NewBlockChain(append([]Option{WithConfigFile(testConfig)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return bc
+}
+
 func TestAddTransaction(t *testing.T) {
-	bc := NewBlockChain()
+	bc := newTestChain(t)
 	bc.AddTransaction("receiverhash", 100.0)
 	assert.Equal(t, "receiverhash", bc.OpenTxs[0].Recipient)
 	assert.Equal(t, 100.0, bc.OpenTxs[0].Val)
 }
 
 func TestAddBlock(t *testing.T) {
-	bc := NewBlockChain()
-	bc.addNewBlock(int64(100))
-	bc.addNewBlock(int64(101))
+	bc := newTestChain(t, WithEngine(consensus.NewDev()))
+	for i := 0; i < 2; i++ {
+		block, err := bc.newBlock(0)
+		assert.Nil(t, err)
+		assert.Nil(t, bc.addNewBlock(block, false))
+	}
 	assert.Equal(t, 3, len(bc.Blocks))
 }
 
 func TestMineBlockAndGet(t *testing.T) {
-	bc := NewBlockChain()
+	bc := newTestChain(t)
 	id := bc.AddTransaction("receiverhash", 100.0)
 	bc.MineBlock()
 	tx := bc.GetTransaction(id)
@@ -32,14 +56,25 @@ func TestMineBlockAndGet(t *testing.T) {
 	assert.Equal(t, 0, len(bc.OpenTxs))
 }
 
+func TestMinedTxsSwept(t *testing.T) {
+	// the body of a block only holds the nodes reachable from its roots
+	bc := newTestChain(t)
+	for i := 0; i < 20; i++ {
+		bc.AddTransaction("receiverhash", 1.0)
+	}
+	assert.Nil(t, bc.MineBlock())
+	assert.Equal(t, 0, merkle.LoadPatriciaTrie(bc.Blocks[1].Txs).Stats().Orphans)
+	assert.Equal(t, 0, merkle.LoadPatriciaTrie(bc.Blocks[1].Balances).Stats().Orphans)
+}
+
 func TestInitial(t *testing.T) {
-	bc := NewBlockChain()
+	bc := newTestChain(t)
 	bal := bc.GetBalance("00000000000000000000000000000000")
 	assert.Equal(t, 100.0, bal)
 }
 
 func TestBalance(t *testing.T) {
-	bc := NewBlockChain()
+	bc := newTestChain(t)
 	id1 := bc.AddTransaction("receiverhash", 50.0)
 	id2 := bc.AddTransaction("00000000000000000000000000000001", 50.0)
 	id3 := bc.AddTransaction("00000000000000000000000000000002", 50.0)
@@ -54,6 +89,281 @@ func TestBalance(t *testing.T) {
 	assert.Equal(t, 0.0, bal3)
 }
 
+func TestBalancesCached(t *testing.T) {
+	bc := newTestChain(t)
+	bc.AddTransaction("receiverhash", 10.0)
+	assert.Nil(t, bc.MineBlock())
+	a, err := bc.openBalances(bc.Blocks[1])
+	assert.Nil(t, err)
+	b, err := bc.openBalances(bc.Blocks[1])
+	assert.Nil(t, err)
+	assert.Same(t, a, b)
+	assert.Equal(t, 10.0, bc.GetBalance("receiverhash"))
+}
+
+func TestBalanceSparse(t *testing.T) {
+	bc := newTestChain(t, WithState(merkle.StateSparse))
+	assert.Equal(t, 100.0, bc.GetBalance("00000000000000000000000000000000"))
+	bc.AddTransaction("receiverhash", 60.0)
+	bc.AddTransaction("00000000000000000000000000000001", 50.0)
+	bc.MineBlock()
+	assert.Equal(t, merkle.StateSparse, bc.Blocks[1].Balances.Kind)
+	assert.Equal(t, 60.0, bc.GetBalance("receiverhash"))
+	assert.Equal(t, 100.0, bc.GetBalance("00000000000000000000000000000001"))
+	assert.Equal(t, 40.0, bc.GetBalance("00000000000000000000000000000000"))
+
+	_, err := NewBlockChain(WithConfigFile(testConfig), WithState("unknown"))
+	assert.NotNil(t, err)
+}
+
+func TestNewBlockChainOptions(t *testing.T) {
+	bc := newTestChain(t, WithUser("someone"))
+	assert.Equal(t, "someone", bc.Usr.Addr)
+	assert.Equal(t, 100.0, bc.GetBalance("00000000000000000000000000000002"))
+
+	// without config, the chain has neither user nor balances
+	bc, err := NewBlockChain()
+	assert.Nil(t, err)
+	assert.Equal(t, "", bc.Usr.Addr)
+	assert.Equal(t, 0.0, bc.GetBalance("00000000000000000000000000000000"))
+
+	cfg := config.Config{
+		User: config.User{Address: "alice"},
+		Init: config.Accounts{{Address: "alice", Val: 5}, {Address: "bob", Val: 7}},
+	}
+	bc, err = NewBlockChain(WithConfig(cfg))
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", bc.Usr.Addr)
+	assert.Equal(t, 7.0, bc.GetBalance("bob"))
+
+	cfg.Init = append(cfg.Init, config.Account{Address: "bob", Val: 1})
+	_, err = NewBlockChain(WithConfig(cfg))
+	assert.NotNil(t, err)
+	_, err = NewBlockChain(WithConfigFile("missing.json"))
+	assert.NotNil(t, err)
+}
+
+func TestChainsInProcess(t *testing.T) {
+	alice, err := NewBlockChain(WithConfig(config.Config{
+		User: config.User{Address: "alice"},
+		Init: config.Accounts{{Address: "alice", Val: 10}},
+	}))
+	assert.Nil(t, err)
+	bob, err := NewBlockChain(WithConfig(config.Config{
+		User: config.User{Address: "bob"},
+		Init: config.Accounts{{Address: "bob", Val: 20}},
+	}))
+	assert.Nil(t, err)
+
+	alice.AddTransaction("carol", 5)
+	bob.AddTransaction("carol", 15)
+	assert.Nil(t, alice.MineBlock())
+	assert.Nil(t, bob.MineBlock())
+	assert.Equal(t, 5.0, alice.GetBalance("carol"))
+	assert.Equal(t, 15.0, bob.GetBalance("carol"))
+	assert.Equal(t, 0.0, alice.GetBalance("bob"))
+	assert.Equal(t, 5.0, bob.GetBalance("bob"))
+}
+
+func TestGenesisSpec(t *testing.T) {
+	spec := genesis.Default(config.Accounts{{Address: "alice", Val: 10}})
+	spec.ChainID = "test"
+	spec.Difficulty = 2
+	spec.Rewards = []genesis.Reward{{Height: 1, Val: 50}, {Height: 2, Val: 5}}
+	bc, err := NewBlockChain(WithGenesis(*spec), WithUser("miner"))
+	assert.Nil(t, err)
+	hash, _ := spec.Hash()
+	assert.Equal(t, hash, bc.Blocks[0].Hash)
+	assert.Equal(t, int32(2), bc.Difficulty)
+	assert.Equal(t, "test", bc.Spec().ChainID)
+
+	// the miner is paid the reward of the height after the transactions of the block
+	id := bc.AddTransactionFrom("miner", "bob", 30)
+	assert.Nil(t, bc.MineBlock())
+	assert.Equal(t, hash, bc.Blocks[1].PrevHash)
+	assert.Equal(t, "failed", bc.GetTransaction(id).Status)
+	assert.Equal(t, 50.0, bc.GetBalance("miner"))
+	bc.AddTransactionFrom("miner", "bob", 30)
+	assert.Nil(t, bc.MineBlock())
+	assert.Equal(t, 25.0, bc.GetBalance("miner"))
+	assert.Equal(t, 30.0, bc.GetBalance("bob"))
+	assert.Nil(t, bc.Validate())
+
+	// the allocations of the genesis can't be changed
+	other, err := NewBlockChain(WithGenesis(*spec), WithState(merkle.StateSparse))
+	assert.Nil(t, err)
+	assert.NotEqual(t, hash, other.Blocks[0].Hash)
+	bc.Blocks[0].Balances = other.Blocks[0].Balances
+	assert.NotNil(t, bc.Validate())
+}
+
+func TestGenesisFromConfig(t *testing.T) {
+	cfg, err := config.Load(testConfig)
+	assert.Nil(t, err)
+	cfg.Genesis = "missing.json"
+	_, err = NewBlockChain(WithConfig(*cfg))
+	assert.NotNil(t, err)
+
+	bc := newTestChain(t)
+	assert.Equal(t, genesis.DefaultChainID, bc.Spec().ChainID)
+	assert.Equal(t, 3, len(bc.Spec().Alloc))
+}
+
+func TestEngines(t *testing.T) {
+	priv, err := keys.Generate()
+	assert.Nil(t, err)
+	spec := genesis.Default(config.Accounts{{Address: "alice", Val: 10}})
+	spec.Consensus = genesis.Consensus{Engine: genesis.EnginePoA, Signers: []string{keys.Public(priv)}}
+	bc, err := NewBlockChain(WithGenesis(*spec), WithUser("alice"), WithKey(priv))
+	assert.Nil(t, err)
+	assert.Nil(t, bc.MineBlock())
+	assert.Equal(t, keys.Public(priv), bc.Blocks[1].Signer)
+	assert.Nil(t, bc.Validate())
+
+	// only the validators sign blocks
+	other, _ := keys.Generate()
+	bc.engine = consensus.NewPoA(other)
+	bc.AddTransaction("bob", 1)
+	assert.NotNil(t, bc.MineBlock())
+	assert.Equal(t, 2, len(bc.Blocks))
+	assert.Equal(t, 1, len(bc.OpenTxs))
+
+	bc.Blocks[1].Signature = keys.Sign(other, []byte("forged"))
+	assert.NotNil(t, bc.Validate())
+
+	// the dev engine seals without proof of work
+	spec.Consensus = genesis.Consensus{Engine: genesis.EngineDev}
+	spec.Difficulty = 10
+	bc, err = NewBlockChain(WithGenesis(*spec), WithUser("alice"))
+	assert.Nil(t, err)
+	assert.Nil(t, bc.MineBlock())
+	assert.Nil(t, bc.Validate())
+}
+
+func TestProposeBlock(t *testing.T) {
+	priv, err := keys.Generate()
+	assert.Nil(t, err)
+	spec := genesis.Default(config.Accounts{{Address: "alice", Val: 10}})
+	spec.Consensus = genesis.Consensus{Engine: genesis.EngineBFT, Signers: []string{keys.Public(priv)}}
+	bc, err := NewBlockChain(WithGenesis(*spec), WithUser("alice"), WithKey(priv))
+	assert.Nil(t, err)
+
+	// a bft block is proposed, and added once committed
+	block, err := bc.ProposeBlock()
+	assert.Nil(t, err)
+	assert.Nil(t, bc.CheckProposal(block))
+	assert.NotNil(t, bc.AddBlock(block))
+	assert.NotNil(t, bc.MineBlock())
+	assert.Equal(t, 1, len(bc.Blocks))
+
+	id, err := consensus.BlockID(block)
+	assert.Nil(t, err)
+	vote, err := consensus.NewVote(priv, consensus.Precommit, 1, 0, id)
+	assert.Nil(t, err)
+	block.Commit = &pb.Commit{Height: 1, BlockId: id, Precommits: []*pb.ConsensusVote{vote}}
+	assert.Nil(t, bc.AddBlock(block))
+	assert.Nil(t, bc.Validate())
+
+	// and never replaced
+	assert.NotNil(t, bc.CheckProposal(block))
+	assert.NotNil(t, bc.AddBlock(block))
+	assert.Equal(t, 2, len(bc.Blocks))
+
+	// the other engines don't propose
+	assert.NotNil(t, newTestChain(t).CheckProposal(block))
+}
+
+func TestMineBlockContext(t *testing.T) {
+	bc := newTestChain(t)
+	bc.Difficulty = 64 // never found
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- bc.MineBlockContext(ctx) }()
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+
+	// a competing block stops the mining
+	bc = newTestChain(t)
+	bc.Difficulty = 64
+	go func() { done <- bc.MineBlockContext(context.Background()) }()
+	for bc.HashRate() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	other := newTestChain(t)
+	assert.Nil(t, other.MineBlock())
+	bc.Lock()
+	bc.Difficulty = other.Difficulty
+	bc.Unlock()
+	assert.Nil(t, bc.AddBlock(other.Blocks[1]))
+	assert.Equal(t, ErrStale, <-done)
+	assert.Equal(t, 2, len(bc.Blocks))
+	assert.Nil(t, bc.Validate())
+
+	bc = newTestChain(t, WithWorkers(2))
+	assert.Equal(t, 2, bc.engine.(*consensus.PoW).Workers)
+}
+
+func TestInvalidProof(t *testing.T) {
+	bc := newTestChain(t)
+	assert.Nil(t, bc.MineBlock())
+	assert.Nil(t, bc.Validate())
+	work, err := consensus.Work(utils.BlockHeader(bc.Blocks[1]))
+	assert.Nil(t, err)
+	for consensus.IsValidProof(work, bc.Blocks[1].Proof, int(bc.Difficulty)) {
+		bc.Blocks[1].Proof++
+	}
+	// rehashed, so only the proof is wrong
+	bc.Blocks[1].Hash = utils.HashBlock(bc.Blocks[1])
+	assert.NotNil(t, bc.Validate())
+}
+
+func TestMaxBlockSize(t *testing.T) {
+	spec := genesis.Default(config.Accounts{{Address: "alice", Val: 10}})
+	bc, err := NewBlockChain(WithGenesis(*spec), WithUser("alice"))
+	assert.Nil(t, err)
+	for i := 0; i < 5; i++ {
+		bc.AddTransaction("bob", 1)
+	}
+	bc.spec.MaxBlockSize = 2*proto.Size(bc.OpenTxs[0]) + 1
+	size := txSize(bc.OpenTxs[0])
+
+	assert.Nil(t, bc.MineBlock())
+	assert.Equal(t, 3, len(bc.OpenTxs))
+	assert.Equal(t, 2.0, bc.GetBalance("bob"))
+	assert.Nil(t, bc.MineBlock())
+	assert.Nil(t, bc.MineBlock())
+	assert.Equal(t, 0, len(bc.OpenTxs))
+	assert.Equal(t, 5.0, bc.GetBalance("bob"))
+	assert.Nil(t, bc.CheckBlock(bc.Blocks[1]))
+
+	// the blocks of the others are checked too
+	bc.spec.MaxBlockSize = size + 1
+	assert.NotNil(t, bc.CheckBlock(bc.Blocks[1]))
+	assert.NotNil(t, bc.Validate())
+}
+
+func TestMaxTxs(t *testing.T) {
+	spec := genesis.Default(config.Accounts{{Address: "alice", Val: 10}})
+	bc, err := NewBlockChain(WithGenesis(*spec), WithUser("alice"))
+	assert.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		bc.AddTransaction("bob", 1)
+	}
+
+	block, err := bc.ProposeBlockContext(context.Background(), 2)
+	assert.Nil(t, err)
+	assert.Nil(t, bc.AddBlock(block))
+	assert.Equal(t, 1, len(bc.OpenTxs))
+	assert.Equal(t, 2.0, bc.GetBalance("bob"))
+}
+
+func TestSendToSelf(t *testing.T) {
+	bc := newTestChain(t)
+	bc.AddTransaction(bc.Usr.Addr, 40)
+	assert.Nil(t, bc.MineBlock())
+	assert.Equal(t, 100.0, bc.GetBalance(bc.Usr.Addr))
+}
+
 func BenchmarkMining1(b *testing.B) {
 	mining(1, b)
 }
@@ -67,7 +377,7 @@ func BenchmarkMining3(b *testing.B) {
 }
 
 func mining(difficulty int32, b *testing.B) {
-	bc := NewBlockChain()
+	bc := newTestChain(b)
 	for i := 0; i < b.N; i++ {
 		bc.AddTransaction(utils.RandStringBytesMaskImprSrc(32), rand.Float64())
 	}
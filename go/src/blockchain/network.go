@@ -0,0 +1,142 @@
+package blockchain
+
+// Network separation
+// Every transaction and block carries the chain ID of the genesis spec. The ID of a transaction is the hash of its
+// signing payload, which includes the chain ID, so a transaction of another network can't be replayed here, and nodes
+// check each other's Handshake before exchanging anything.
+import (
+	"fmt"
+	"merkle"
+	"proto"
+	"utils"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// TxPayload returns the bytes of the transaction covered by its ID and signature: everything but the ID and the
+// status, which is set when the transaction is mined
+func TxPayload(tx *pb.Transaction) ([]byte, error) {
+	return utils.Encode(&pb.Transaction{
+		ChainId:   tx.ChainId,
+		Sender:    tx.Sender,
+		Recipient: tx.Recipient,
+		Val:       tx.Val,
+		Timestamp: tx.Timestamp,
+		Type:      tx.Type,
+	})
+}
+
+// TxID returns the ID of the transaction, the hash of its payload
+func TxID(tx *pb.Transaction) (string, error) {
+	payload, err := TxPayload(tx)
+	if err != nil {
+		return "", err
+	}
+
+	return utils.HashBytes(payload), nil
+}
+
+// CheckTransaction checks that the transaction is of the network and that its ID matches its payload
+func (bc *BlockChain) CheckTransaction(tx *pb.Transaction) error {
+	if tx.ChainId != bc.spec.ChainID {
+		return fmt.Errorf("Transaction %s is for the chain %q, not %q", tx.Id, tx.ChainId, bc.spec.ChainID)
+	}
+
+	id, err := TxID(tx)
+	if err != nil {
+		return err
+	}
+	if id != tx.Id {
+		return fmt.Errorf("Transaction %s doesn't match its payload", tx.Id)
+	}
+
+	return nil
+}
+
+// CheckBlock checks that the block and its transactions are of the network, and that they fit in a block
+func (bc *BlockChain) CheckBlock(block *pb.Block) error {
+	if block.ChainId != bc.spec.ChainID {
+		return fmt.Errorf("Block %d is for the chain %q, not %q", block.Index, block.ChainId, bc.spec.ChainID)
+	}
+	if block.Txs == nil {
+		return nil
+	}
+
+	size := 0
+	return forEachTx(block, func(tx *pb.Transaction) error {
+		size += txSize(tx)
+		if bc.spec.MaxBlockSize > 0 && size > bc.spec.MaxBlockSize {
+			return fmt.Errorf("Block %d is over the max block size %d", block.Index, bc.spec.MaxBlockSize)
+		}
+
+		return bc.CheckTransaction(tx)
+	})
+}
+
+// ReceiveTransaction adds a transaction made elsewhere, e.g. by a wallet or another node, to the open transactions
+func (bc *BlockChain) ReceiveTransaction(tx *pb.Transaction) error {
+	if err := bc.CheckTransaction(tx); err != nil {
+		return err
+	}
+
+	bc.RWMutex.Lock()
+	defer bc.RWMutex.Unlock()
+	if bc.GetTransaction(tx.Id) != nil {
+		return fmt.Errorf("Transaction %s is already mined", tx.Id)
+	}
+	for _, open := range bc.OpenTxs {
+		if open.Id == tx.Id {
+			return fmt.Errorf("Transaction %s is already pending", tx.Id)
+		}
+	}
+
+	tx.Status = "pending"
+	bc.OpenTxs = append(bc.OpenTxs, tx)
+	return nil
+}
+
+// Handshake returns the handshake of the node of the chain
+func (bc *BlockChain) Handshake() *pb.Handshake {
+	bc.RWMutex.RLock()
+	defer bc.RWMutex.RUnlock()
+	return &pb.Handshake{
+		ChainId: bc.spec.ChainID,
+		Genesis: bc.Blocks[0].Hash,
+		Height:  bc.Blocks[len(bc.Blocks)-1].Index,
+	}
+}
+
+// CheckHandshake checks that the node of the handshake is on the network of the chain
+func (bc *BlockChain) CheckHandshake(h *pb.Handshake) error {
+	return CheckHandshake(bc.Handshake(), h)
+}
+
+// CheckHandshake checks that the peer is on the network of the local node
+func CheckHandshake(local, peer *pb.Handshake) error {
+	if peer.ChainId != local.ChainId {
+		return fmt.Errorf("Peer is on the chain %q, not %q", peer.ChainId, local.ChainId)
+	}
+	if peer.Genesis != local.Genesis {
+		return fmt.Errorf("Peer has the genesis %s, not %s", peer.Genesis, local.Genesis)
+	}
+
+	return nil
+}
+
+// forEachTx calls fn with every transaction of the block
+func forEachTx(block *pb.Block, fn func(tx *pb.Transaction) error) error {
+	txs := merkle.NewTypedTrie[*pb.Transaction](merkle.LoadPatriciaTrie(block.Txs), TxCodec)
+	return txs.ForEach(func(id string, tx *pb.Transaction) error {
+		if id != tx.Id {
+			return fmt.Errorf("Transaction %s is stored as %s", tx.Id, id)
+		}
+
+		return fn(tx)
+	})
+}
+
+// txSize is the encoded size of the transaction counted against the max block size, without its status, which
+// changes once mined
+func txSize(tx *pb.Transaction) int {
+	return proto.Size(tx) - proto.Size(&pb.Transaction{Status: tx.Status})
+}
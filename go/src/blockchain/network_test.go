@@ -0,0 +1,81 @@
+package blockchain
+
+import (
+	"config"
+	"genesis"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newNetwork(t *testing.T, chainID string) *BlockChain {
+	spec := genesis.Default(config.Accounts{{Address: "alice", Val: 100}})
+	spec.ChainID = chainID
+	bc, err := NewBlockChain(WithGenesis(*spec), WithUser("alice"))
+	assert.Nil(t, err)
+	return bc
+}
+
+func TestTxID(t *testing.T) {
+	test, main := newNetwork(t, "test"), newNetwork(t, "main")
+	test.AddTransaction("bob", 10)
+	tx := test.OpenTxs[0]
+	assert.Equal(t, "test", tx.ChainId)
+	assert.Nil(t, test.CheckTransaction(tx))
+
+	// the same transfer on another network has another ID
+	replayed := *tx
+	replayed.ChainId = "main"
+	id, err := TxID(&replayed)
+	assert.Nil(t, err)
+	assert.NotEqual(t, tx.Id, id)
+
+	assert.NotNil(t, main.CheckTransaction(tx))
+	assert.NotNil(t, main.CheckTransaction(&replayed))
+	tx.Val = 20
+	assert.NotNil(t, test.CheckTransaction(tx))
+}
+
+func TestReceiveTransaction(t *testing.T) {
+	test, main := newNetwork(t, "test"), newNetwork(t, "main")
+	test.AddTransaction("bob", 10)
+	tx := test.OpenTxs[0]
+	assert.NotNil(t, main.ReceiveTransaction(tx))
+	assert.Equal(t, 0, len(main.OpenTxs))
+
+	other := newNetwork(t, "test")
+	assert.Nil(t, other.ReceiveTransaction(tx))
+	assert.NotNil(t, other.ReceiveTransaction(tx))
+	assert.Nil(t, other.MineBlock())
+	assert.Equal(t, 10.0, other.GetBalance("bob"))
+	assert.NotNil(t, other.ReceiveTransaction(tx))
+}
+
+func TestCheckBlock(t *testing.T) {
+	test, main := newNetwork(t, "test"), newNetwork(t, "main")
+	test.AddTransaction("bob", 10)
+	assert.Nil(t, test.MineBlock())
+	assert.Nil(t, test.CheckBlock(test.Blocks[1]))
+	assert.NotNil(t, main.CheckBlock(test.Blocks[1]))
+
+	test.Blocks[1].ChainId = "main"
+	assert.NotNil(t, test.Validate())
+}
+
+func TestHandshake(t *testing.T) {
+	test, main := newNetwork(t, "test"), newNetwork(t, "main")
+	h := test.Handshake()
+	assert.Equal(t, "test", h.ChainId)
+	assert.Equal(t, test.Blocks[0].Hash, h.Genesis)
+	assert.Equal(t, int32(0), h.Height)
+
+	assert.Nil(t, newNetwork(t, "test").CheckHandshake(h))
+	assert.NotNil(t, main.CheckHandshake(h))
+
+	// same chain ID, other genesis
+	spec := genesis.Default(config.Accounts{{Address: "mallory", Val: 100}})
+	spec.ChainID = "test"
+	forked, err := NewBlockChain(WithGenesis(*spec))
+	assert.Nil(t, err)
+	assert.NotNil(t, forked.CheckHandshake(h))
+}
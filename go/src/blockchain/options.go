@@ -0,0 +1,203 @@
+package blockchain
+
+// Options of NewBlockChain
+// A chain is configured by values only, e.g. a config.Config loaded by the caller, so several chains with different
+// users or genesis can live in one process.
+import (
+	"config"
+	"consensus"
+	"crypto/ed25519"
+	"genesis"
+	"keys"
+)
+
+// Option configures a new BlockChain
+type Option func(o *options) error
+
+type options struct {
+	cfg       *config.Config // nil for a chain without user nor initial accounts, never shared with the caller
+	user      string
+	spec      *genesis.Spec
+	stateKind *string
+	engine    consensus.Engine
+	key       ed25519.PrivateKey
+	keyFile   string
+	workers   int
+}
+
+// WithConfig sets the user and the initial accounts of the genesis block
+func WithConfig(c config.Config) Option {
+	return func(o *options) error {
+		o.cfg = &c
+		return nil
+	}
+}
+
+// WithConfigFile loads the config from the file, see config.Load
+func WithConfigFile(path string) Option {
+	return func(o *options) error {
+		c, err := config.Load(path)
+		if err != nil {
+			return err
+		}
+
+		o.cfg = c
+		return nil
+	}
+}
+
+// WithUser overrides the user of the config
+func WithUser(addr string) Option {
+	return func(o *options) error {
+		o.user = addr
+		return nil
+	}
+}
+
+// WithGenesis sets the genesis spec, instead of the one of the config or the default spec with the initial accounts
+// of the config
+func WithGenesis(s genesis.Spec) Option {
+	return func(o *options) error {
+		o.spec = &s
+		return nil
+	}
+}
+
+// WithGenesisFile loads the genesis spec from the file, see genesis.Load
+func WithGenesisFile(path string) Option {
+	return func(o *options) error {
+		s, err := genesis.Load(path)
+		if err != nil {
+			return err
+		}
+
+		o.spec = s
+		return nil
+	}
+}
+
+// WithEngine sets the consensus engine, instead of the one of the genesis spec, e.g. consensus.Dev for tests
+func WithEngine(e consensus.Engine) Option {
+	return func(o *options) error {
+		o.engine = e
+		return nil
+	}
+}
+
+// WithKey sets the key of the local validator, which signs the blocks of e.g. consensus.PoA
+func WithKey(priv ed25519.PrivateKey) Option {
+	return func(o *options) error {
+		o.key = priv
+		return nil
+	}
+}
+
+// WithKeyFile loads the key of the local validator from the file, see keys.Load, instead of the key of the config
+func WithKeyFile(path string) Option {
+	return func(o *options) error {
+		o.keyFile = path
+		return nil
+	}
+}
+
+// WithWorkers sets the goroutines mining with consensus.PoW, overriding the config
+func WithWorkers(n int) Option {
+	return func(o *options) error {
+		o.workers = n
+		return nil
+	}
+}
+
+// WithState sets the kind of the balances tree, see merkle.StateTree, overriding the genesis spec
+func WithState(kind string) Option {
+	return func(o *options) error {
+		o.stateKind = &kind
+		return nil
+	}
+}
+
+// config returns the config with the overrides applied, validated unless it's the empty default
+func (o *options) config() (*config.Config, error) {
+	c := &config.Config{}
+	if o.cfg != nil {
+		*c = *o.cfg
+	}
+	if len(o.user) > 0 {
+		c.User.Address = o.user
+	}
+	if o.cfg == nil {
+		return c, nil
+	}
+
+	return c, c.Validate()
+}
+
+// signer returns the key of the local validator given by the options, else by the config, nil if none
+func (o *options) signer(cfg *config.Config) (ed25519.PrivateKey, error) {
+	path := cfg.Key
+	switch {
+	case o.key != nil:
+		return o.key, nil
+	case len(o.keyFile) > 0:
+		path = o.keyFile
+	}
+	if len(path) == 0 {
+		return nil, nil
+	}
+
+	return keys.Load(path)
+}
+
+// consensus returns the engine given by the options, else the one of the genesis spec
+func (o *options) consensus(spec *genesis.Spec, cfg *config.Config) (consensus.Engine, error) {
+	if o.engine != nil {
+		return o.engine, nil
+	}
+
+	key, err := o.signer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	e, err := consensus.New(spec, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if pow, ok := e.(*consensus.PoW); ok {
+		pow.Workers = cfg.Workers
+		if o.workers > 0 {
+			pow.Workers = o.workers
+		}
+	}
+	return e, nil
+}
+
+// genesis returns the genesis spec of the chain, given by the options, else by the config, validated and in canonical
+// form, i.e. as decoded from GenesisSpec
+func (o *options) genesis(cfg *config.Config) (*genesis.Spec, error) {
+	s := genesis.Default(cfg.Init)
+	switch {
+	case o.spec != nil:
+		c := *o.spec
+		s = &c
+	case len(cfg.Genesis) > 0:
+		loaded, err := genesis.Load(cfg.Genesis)
+		if err != nil {
+			return nil, err
+		}
+		s = loaded
+	}
+
+	if o.stateKind != nil {
+		s.StateTree = *o.stateKind
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	data, err := s.Encode()
+	if err != nil {
+		return nil, err
+	}
+	return genesis.Parse(data)
+}
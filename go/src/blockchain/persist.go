@@ -0,0 +1,143 @@
+package blockchain
+
+// Persistence of the chain to a file, e.g. in the data dir of a node, and of the bodies of its blocks to a dir next to it
+// The file is the encoded pb.Chain with the headers of the blocks only. Their bodies are each in a file of the bodies
+// dir named by the block hash, written once, as a block never changes. Every file is written to a temporary file
+// first, and the bodies before the chain refers to them, so that a crash never leaves a partial chain.
+import (
+	"config"
+	"fmt"
+	"genesis"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"proto"
+	"strings"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// BodiesDir is the name of the dir of the block bodies, next to the chain file
+const BodiesDir = "bodies"
+
+// Save writes the chain to the file at path, and the bodies of its blocks not written yet to the bodies dir
+func (bc *BlockChain) Save(path string) error {
+	bc.RWMutex.RLock()
+	chain := bc.Chain
+	blocks := bc.Blocks
+	chain.Blocks = make([]*pb.Block, len(blocks))
+	for i, block := range blocks {
+		header := *block
+		header.Txs, header.Balances = nil, nil
+		chain.Blocks[i] = &header
+	}
+	data, err := proto.Marshal(&chain)
+	bc.RWMutex.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	// the blocks are only ever appended, their bodies can be written without holding the lock
+	dir := filepath.Join(filepath.Dir(path), BodiesDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, block := range blocks {
+		if err := saveBody(dir, block); err != nil {
+			return err
+		}
+	}
+
+	return writeFile(path, data)
+}
+
+// saveBody writes the body of the block to its file in dir, unless it's there already
+func saveBody(dir string, block *pb.Block) error {
+	path := bodyPath(dir, block.Hash)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	data, err := proto.Marshal(&pb.BlockBody{Txs: block.Txs, Balances: block.Balances})
+	if err != nil {
+		return err
+	}
+
+	return writeFile(path, data)
+}
+
+// loadBody reads the body of the block from its file in dir
+func loadBody(dir string, block *pb.Block) error {
+	data, err := ioutil.ReadFile(bodyPath(dir, block.Hash))
+	if err != nil {
+		return fmt.Errorf("No body of block %d: %v", block.Index, err)
+	}
+
+	body := &pb.BlockBody{}
+	if err := proto.Unmarshal(data, body); err != nil {
+		return fmt.Errorf("Body of block %d: %v", block.Index, err)
+	}
+	block.Txs, block.Balances = body.Txs, body.Balances
+	return nil
+}
+
+// bodyPath returns the file of the body of the block with the hash, the hash being made safe for a file name
+func bodyPath(dir, hash string) string {
+	return filepath.Join(dir, strings.NewReplacer("/", "_", "+", "-").Replace(hash)+".pb")
+}
+
+// writeFile writes the data to a temporary file, then renames it to path
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// LoadBlockChain reads the chain saved at path. Only the engine and key options apply, the rest is saved in the chain.
+func LoadBlockChain(path string, opts ...Option) (*BlockChain, error) {
+	o := &options{}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	bc := &BlockChain{}
+	if err := proto.Unmarshal(data, &bc.Chain); err != nil {
+		return nil, err
+	}
+	for _, block := range bc.Blocks {
+		// chains saved before the bodies dir hold the bodies, every block has balances
+		if block.Balances != nil {
+			continue
+		}
+		if err := loadBody(filepath.Join(filepath.Dir(path), BodiesDir), block); err != nil {
+			return nil, fmt.Errorf("Chain at %s: %v", path, err)
+		}
+	}
+	if len(bc.Blocks) == 0 || bc.Blocks[0].Balances == nil {
+		return nil, fmt.Errorf("Chain at %s has no genesis block", path)
+	}
+
+	if len(bc.GenesisSpec) == 0 {
+		return nil, fmt.Errorf("Chain at %s has no genesis spec", path)
+	}
+	if bc.spec, err = genesis.Parse(bc.GenesisSpec); err != nil {
+		return nil, fmt.Errorf("Chain at %s: %v", path, err)
+	}
+	if bc.engine, err = o.consensus(bc.spec, &config.Config{}); err != nil {
+		return nil, err
+	}
+	if bc.Usr == nil {
+		bc.Usr = &pb.User{}
+	}
+	return bc, nil
+}
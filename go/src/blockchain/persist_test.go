@@ -0,0 +1,100 @@
+package blockchain
+
+import (
+	"io/ioutil"
+	"merkle"
+	"os"
+	"path/filepath"
+	"proto"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chain")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "chain.pb")
+
+	bc := newTestChain(t, WithState(merkle.StateSparse))
+	id := bc.AddTransaction("receiverhash", 10.0)
+	assert.Nil(t, bc.MineBlock())
+	bc.AddTransaction("receiverhash", 1.0)
+	assert.Nil(t, bc.Save(path))
+
+	loaded, err := LoadBlockChain(path)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(loaded.Blocks))
+	assert.Equal(t, bc.Blocks[1].Hash, loaded.Blocks[1].Hash)
+	assert.Equal(t, 1, len(loaded.OpenTxs))
+	assert.Equal(t, bc.Usr.Addr, loaded.Usr.Addr)
+	assert.Equal(t, 10.0, loaded.GetTransaction(id).Val)
+	assert.Equal(t, 10.0, loaded.GetBalance("receiverhash"))
+	assert.Equal(t, bc.Spec(), loaded.Spec())
+	assert.Nil(t, loaded.Validate())
+
+	// new blocks keep the state tree of the saved chain
+	assert.Nil(t, loaded.MineBlock())
+	assert.Equal(t, merkle.StateSparse, loaded.Blocks[2].Balances.Kind)
+	assert.Equal(t, 11.0, loaded.GetBalance("receiverhash"))
+}
+
+func TestSaveBodies(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chain")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "chain.pb")
+
+	// the chain file holds the headers, the bodies are apart, one per block
+	bc := newTestChain(t)
+	bc.AddTransaction("receiverhash", 10.0)
+	assert.Nil(t, bc.MineBlock())
+	assert.Nil(t, bc.Save(path))
+	data, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	saved := &pb.Chain{}
+	assert.Nil(t, proto.Unmarshal(data, saved))
+	assert.Equal(t, bc.Blocks[1].Hash, saved.Blocks[1].Hash)
+	assert.Nil(t, saved.Blocks[1].Txs)
+	assert.Nil(t, saved.Blocks[1].Balances)
+	assert.NotNil(t, bc.Blocks[1].Txs)
+	bodies, err := ioutil.ReadDir(filepath.Join(dir, BodiesDir))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(bodies))
+
+	// only the new bodies are written
+	assert.Nil(t, bc.MineBlock())
+	first := bodyPath(filepath.Join(dir, BodiesDir), bc.Blocks[1].Hash)
+	assert.Nil(t, ioutil.WriteFile(first, []byte("kept"), 0644))
+	assert.Nil(t, bc.Save(path))
+	kept, _ := ioutil.ReadFile(first)
+	assert.Equal(t, "kept", string(kept))
+	bodies, _ = ioutil.ReadDir(filepath.Join(dir, BodiesDir))
+	assert.Equal(t, 3, len(bodies))
+
+	assert.Nil(t, os.Remove(first))
+	_, err = LoadBlockChain(path)
+	assert.NotNil(t, err)
+}
+
+func TestLoadWithoutSpec(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chain")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "chain.pb")
+
+	// a chain without its genesis spec has nothing to check its blocks against
+	bc := newTestChain(t)
+	assert.Nil(t, bc.MineBlock())
+	bc.GenesisSpec = nil
+	assert.Nil(t, bc.Save(path))
+	_, err = LoadBlockChain(path)
+	assert.EqualError(t, err, "Chain at "+path+" has no genesis spec")
+}
+
+func TestLoadMissing(t *testing.T) {
+	_, err := LoadBlockChain(filepath.Join(os.TempDir(), "no-such-chain.pb"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,172 @@
+package blockchain
+
+// Staking, for the proof of stake engine
+// Validators bond part of their balance as stake, kept in the balances under genesis.StakeKey, and unbond it back.
+// The node reports the validators signing two blocks at the same height, and its next blocks slash them.
+import (
+	"consensus"
+	"fmt"
+	"genesis"
+	"keys"
+	"merkle"
+	"proto"
+	"strings"
+	"time"
+	"utils"
+)
+
+// Transaction types
+const (
+	TxTransfer = ""       // of Val from the sender to the recipient
+	TxBond     = "bond"   // of Val of the balance of the sender as its stake, the sender being a public key
+	TxUnbond   = "unbond" // of Val of the stake of the sender back to its balance
+	TxSlash    = "slash"  // record of the burning of the stake Val of the sender, made by the engine
+)
+
+// AddStakeTransaction creates a transaction bonding or unbonding stake of the sender, and adds it to the open Txs
+func (bc *BlockChain) AddStakeTransaction(typ, sender string, val float64) (string, error) {
+	if typ != TxBond && typ != TxUnbond {
+		return "", fmt.Errorf("Unknown stake transaction %q, expected %s or %s", typ, TxBond, TxUnbond)
+	}
+	if !bc.staking() {
+		return "", fmt.Errorf("The %s engine has no stakes", bc.spec.Consensus.Engine)
+	}
+	if !isValidator(sender) {
+		return "", fmt.Errorf("Stake of %q, which isn't a public key", sender)
+	}
+	if val <= 0 {
+		return "", fmt.Errorf("Stake transaction of %f, which isn't positive", val)
+	}
+
+	tx := &pb.Transaction{
+		Sender:    sender,
+		Val:       val,
+		Timestamp: time.Now().UnixNano(),
+		Status:    "pending",
+		ChainId:   bc.spec.ChainID,
+		Type:      typ,
+	}
+
+	tx.Id, _ = TxID(tx)
+	bc.RWMutex.Lock()
+	defer bc.RWMutex.Unlock()
+	bc.OpenTxs = append(bc.OpenTxs, tx)
+	return tx.Id, nil
+}
+
+// Stakes returns the stakes in the balances before the block at the height, by address
+func (bc *BlockChain) Stakes(height int32) (map[string]float64, error) {
+	if height < 1 || int(height) > len(bc.Blocks) {
+		return nil, fmt.Errorf("No block before height %d in a chain of %d blocks", height, len(bc.Blocks))
+	}
+
+	stakes, err := bc.stakesAfter(bc.Blocks[:height])
+	if err != nil {
+		return nil, err
+	}
+
+	rst := make(map[string]float64, len(stakes))
+	for addr, stake := range stakes {
+		rst[addr] = stake
+	}
+	return rst, nil
+}
+
+// stakesAfter returns the stakes after the last of the blocks, which must not be changed. The balances of a block hold
+// the changes, so the stakes after a block are the ones after its parent with its changes applied. They are cached by
+// block hash, so the balances of every block are read once.
+func (bc *BlockChain) stakesAfter(blocks []*pb.Block) (map[string]float64, error) {
+	// the blocks from the last one with cached stakes
+	from, stakes := len(blocks), map[string]float64{}
+	for from > 0 {
+		if cached, ok := bc.stakes.Load(blocks[from-1].Hash); ok {
+			stakes = cached.(map[string]float64)
+			break
+		}
+		from--
+	}
+
+	for _, block := range blocks[from:] {
+		t, err := bc.openBalances(block)
+		if err != nil {
+			return nil, fmt.Errorf("Block %d: %v", block.Index, err)
+		}
+
+		next := make(map[string]float64, len(stakes))
+		for addr, stake := range stakes {
+			next[addr] = stake
+		}
+		bals := merkle.NewTypedTrie[float64](t, merkle.FloatCodec{})
+		if err := bals.ForEach(func(key string, val float64) error {
+			if strings.HasPrefix(key, genesis.StakePrefix) {
+				next[strings.TrimPrefix(key, genesis.StakePrefix)] = val
+			}
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("Block %d: %v", block.Index, err)
+		}
+
+		stakes = next
+		bc.stakes.Store(block.Hash, stakes)
+	}
+	return stakes, nil
+}
+
+// ReceiveHeader checks the header of a block seen by the node, e.g. from a peer, against the block of the chain at
+// its height. If they are different blocks signed by the same validator, the node keeps the evidence for its next
+// blocks to slash, and returns it.
+func (bc *BlockChain) ReceiveHeader(signed *pb.SignedHeader) (*pb.Evidence, error) {
+	header := signed.GetHeader()
+	if header == nil {
+		return nil, fmt.Errorf("Signed header lacks its header")
+	}
+
+	bc.RWMutex.Lock()
+	defer bc.RWMutex.Unlock()
+	if header.ChainId != bc.spec.ChainID {
+		return nil, fmt.Errorf("Block %d is for the chain %q, not %q", header.Index, header.ChainId, bc.spec.ChainID)
+	}
+	hash := utils.HashHeader(header)
+	if header.Index < 1 || int(header.Index) >= len(bc.Blocks) {
+		return nil, fmt.Errorf("No block at height %d to check block %s against", header.Index, hash)
+	}
+
+	own := bc.Blocks[header.Index]
+	if own.Hash == hash || own.Signer != header.Signer {
+		return nil, nil
+	}
+
+	ev := consensus.NewEvidence(consensus.SignedHeader(own), signed)
+	if err := consensus.CheckEvidence(ev); err != nil {
+		return nil, err
+	}
+	for _, b := range append(bc.Blocks, &pb.Block{Evidence: bc.Evidence}) {
+		if slashes(b, ev) {
+			// already slashed or pending
+			return ev, nil
+		}
+	}
+
+	bc.Evidence = append(bc.Evidence, ev)
+	return ev, nil
+}
+
+// staking tells if the engine of the chain uses stakes
+func (bc *BlockChain) staking() bool {
+	return bc.spec.Consensus.Engine == genesis.EnginePoS
+}
+
+func isValidator(addr string) bool {
+	_, err := keys.ParsePublic(addr)
+	return err == nil
+}
+
+// slashes tells if the block slashes the double signing of the evidence
+func slashes(block *pb.Block, ev *pb.Evidence) bool {
+	for _, slashed := range block.Evidence {
+		if slashed.A.Header.Signer == ev.A.Header.Signer && slashed.A.Header.Index == ev.A.Header.Index {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package blockchain
+
+import (
+	"config"
+	"consensus"
+	"crypto/ed25519"
+	"genesis"
+	"keys"
+	"proto"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+// newStakingChain returns a chain where alice and bob bond 10 each, alice having 100 more to spend
+func newStakingChain(t *testing.T) (*BlockChain, ed25519.PrivateKey, ed25519.PrivateKey) {
+	alice, _ := keys.Generate()
+	bob, _ := keys.Generate()
+	spec := genesis.Default(config.Accounts{{Address: keys.Public(alice), Val: 100}})
+	spec.Consensus = genesis.Consensus{Engine: genesis.EnginePoS, Stakes: config.Accounts{
+		{Address: keys.Public(alice), Val: 10}, {Address: keys.Public(bob), Val: 10}}}
+	bc, err := NewBlockChain(WithGenesis(*spec), WithUser(keys.Public(alice)), WithKey(alice))
+	assert.Nil(t, err)
+	return bc, alice, bob
+}
+
+// mineAsLeader mines the next block with the key of its leader
+func mineAsLeader(t *testing.T, bc *BlockChain, privs ...ed25519.PrivateKey) {
+	leader, err := consensus.NewPoS(nil).Leader(bc, int32(len(bc.Blocks)))
+	assert.Nil(t, err)
+	for _, priv := range privs {
+		if keys.Public(priv) == leader {
+			bc.engine = consensus.NewPoS(priv)
+		}
+	}
+	assert.Nil(t, bc.MineBlock())
+}
+
+func TestStaking(t *testing.T) {
+	bc, alice, bob := newStakingChain(t)
+	pub := keys.Public(alice)
+	_, err := bc.AddStakeTransaction(TxBond, pub, 50)
+	assert.Nil(t, err)
+	_, err = bc.AddStakeTransaction(TxUnbond, pub, 20)
+	assert.Nil(t, err)
+	id, err := bc.AddStakeTransaction(TxUnbond, pub, 100)
+	assert.Nil(t, err)
+	bc.AddTransactionFrom(pub, genesis.StakeKey(pub), 10)
+	mineAsLeader(t, bc, alice, bob)
+
+	assert.Equal(t, "failed", bc.GetTransaction(id).Status)
+	assert.Equal(t, 70.0, bc.GetBalance(pub))
+	stakes, err := bc.Stakes(int32(len(bc.Blocks)))
+	assert.Nil(t, err)
+	assert.Equal(t, 40.0, stakes[pub])
+	assert.Equal(t, 10.0, stakes[keys.Public(bob)])
+	stakes, _ = bc.Stakes(1)
+	assert.Equal(t, 10.0, stakes[pub])
+
+	// the validators are the accounts bonding stake
+	_, err = bc.AddStakeTransaction(TxUnbond, keys.Public(bob), 10)
+	assert.Nil(t, err)
+	mineAsLeader(t, bc, alice, bob)
+	validators, err := bc.Validators()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{pub}, validators)
+	assert.Nil(t, bc.Validate())
+
+	_, err = bc.AddStakeTransaction(TxBond, "alice", 1)
+	assert.NotNil(t, err)
+	_, err = bc.AddStakeTransaction("delegate", pub, 1)
+	assert.NotNil(t, err)
+	_, err = newTestChain(t).AddStakeTransaction(TxBond, pub, 1)
+	assert.NotNil(t, err)
+}
+
+func TestStakesCached(t *testing.T) {
+	bc, alice, bob := newStakingChain(t)
+	pub := keys.Public(alice)
+	_, err := bc.AddStakeTransaction(TxBond, pub, 50)
+	assert.Nil(t, err)
+	mineAsLeader(t, bc, alice, bob)
+	mineAsLeader(t, bc, alice, bob)
+
+	// the stakes after each block are read once, then follow from the ones of the parent
+	stakes, err := bc.Stakes(int32(len(bc.Blocks)))
+	assert.Nil(t, err)
+	assert.Equal(t, 60.0, stakes[pub])
+	for _, block := range bc.Blocks {
+		_, ok := bc.stakes.Load(block.Hash)
+		assert.True(t, ok)
+	}
+
+	stakes[pub] = 0
+	stakes, _ = bc.Stakes(int32(len(bc.Blocks)))
+	assert.Equal(t, 60.0, stakes[pub])
+	stakes, _ = bc.Stakes(1)
+	assert.Equal(t, 10.0, stakes[pub])
+}
+
+func TestSlashing(t *testing.T) {
+	bc, alice, bob := newStakingChain(t)
+	for i := 0; i < 30 && bc.Blocks[len(bc.Blocks)-1].Signer != keys.Public(bob); i++ {
+		mineAsLeader(t, bc, alice, bob)
+	}
+	signed := bc.Blocks[len(bc.Blocks)-1]
+	assert.Equal(t, keys.Public(bob), signed.Signer)
+
+	// bob signs another block at the same height
+	other := proto.Clone(signed).(*pb.Block)
+	other.Timestamp++
+	payload, _ := consensus.SealPayload(other)
+	other.Signature = keys.Sign(bob, payload)
+	header := consensus.SignedHeader(other)
+
+	ev, err := bc.ReceiveHeader(header)
+	assert.Nil(t, err)
+	assert.NotNil(t, ev)
+	again, err := bc.ReceiveHeader(header)
+	assert.Nil(t, err)
+	assert.Equal(t, ev, again)
+	assert.Equal(t, 1, len(bc.Evidence))
+	ev, err = bc.ReceiveHeader(consensus.SignedHeader(signed))
+	assert.Nil(t, err)
+	assert.Nil(t, ev)
+
+	// the next block burns the stake of bob, who is no validator anymore
+	mineAsLeader(t, bc, alice, bob)
+	last := bc.Blocks[len(bc.Blocks)-1]
+	assert.Equal(t, 1, len(last.Evidence))
+	assert.Empty(t, bc.Evidence)
+	stakes, _ := bc.Stakes(int32(len(bc.Blocks)))
+	assert.Equal(t, 0.0, stakes[keys.Public(bob)])
+	validators, _ := bc.Validators()
+	assert.Equal(t, []string{keys.Public(alice)}, validators)
+
+	var slashing *pb.Transaction
+	forEachTx(last, func(tx *pb.Transaction) error {
+		if tx.Type == TxSlash {
+			slashing = tx
+		}
+		return nil
+	})
+	assert.NotNil(t, slashing)
+	assert.Equal(t, keys.Public(bob), slashing.Sender)
+	assert.Equal(t, 10.0, slashing.Val)
+	assert.Nil(t, bc.Validate())
+
+	// forged evidence is rejected
+	header.Signature = keys.Sign(alice, payload)
+	_, err = bc.ReceiveHeader(header)
+	assert.NotNil(t, err)
+}
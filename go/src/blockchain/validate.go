@@ -0,0 +1,214 @@
+package blockchain
+
+import (
+	"consensus"
+	"fmt"
+	"genesis"
+	"merkle"
+	"proto"
+	"utils"
+)
+
+// Validate checks the linkage of the blocks, the integrity of their tries and their seals, and replays the execution
+// of their transactions
+func (bc *BlockChain) Validate() error {
+	bc.RWMutex.RLock()
+	defer bc.RWMutex.RUnlock()
+	if err := bc.validateGenesis(); err != nil {
+		return err
+	}
+
+	for i, block := range bc.Blocks {
+		if int(block.Index) != i {
+			return fmt.Errorf("Block %d has the index %d", i, block.Index)
+		}
+
+		// the genesis block is neither linked nor hashed
+		var parent *pb.Block
+		if i > 0 {
+			parent = bc.Blocks[i-1]
+		}
+		if err := checkIntegrity(block, parent); err != nil {
+			return err
+		}
+		if i == 0 {
+			continue
+		}
+
+		if err := bc.CheckBlock(block); err != nil {
+			return err
+		}
+		if err := bc.engine.VerifySeal(bc.snapshot(i), block); err != nil {
+			return err
+		}
+		if err := bc.checkExecution(bc.snapshot(i), block); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CheckProposal checks that the block proposed by a validator can be the next block, before it's committed. Its
+// transactions are executed again on the last block, which must result in the transactions and balances of the block.
+func (bc *BlockChain) CheckProposal(block *pb.Block) error {
+	bc.RWMutex.RLock()
+	defer bc.RWMutex.RUnlock()
+	e, ok := bc.engine.(consensus.Finality)
+	if !ok {
+		return fmt.Errorf("The %s engine has no proposals", bc.spec.Consensus.Engine)
+	}
+
+	last := bc.Blocks[len(bc.Blocks)-1]
+	if block.Index != last.Index+1 {
+		return fmt.Errorf("Block %d isn't the next block %d", block.Index, last.Index+1)
+	}
+	if err := checkIntegrity(block, last); err != nil {
+		return err
+	}
+	if err := bc.CheckBlock(block); err != nil {
+		return err
+	}
+	if err := e.VerifyProposal(bc, block); err != nil {
+		return err
+	}
+
+	return bc.checkExecution(bc.snapshot(len(bc.Blocks)), block)
+}
+
+// checkExecution executes the transactions of the block on the last block of the chain before it, without the records
+// of the engine, which the execution makes again, and compares the result with the block
+func (bc *BlockChain) checkExecution(chain *prefix, block *pb.Block) error {
+	var txs []*pb.Transaction
+	if err := forEachTx(block, func(tx *pb.Transaction) error {
+		if !isRecord(tx) {
+			txs = append(txs, tx)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	trie, balances, err := bc.execute(chain, block, txs)
+	if err != nil {
+		return fmt.Errorf("Block %d: %v", block.Index, err)
+	}
+	if trie.Root.Hash != block.Txs.Root.Hash {
+		return fmt.Errorf("Block %d has transactions that don't match their execution", block.Index)
+	}
+	root, err := balances.RootHash()
+	if err != nil {
+		return err
+	}
+	if root != block.Balances.Root.Hash {
+		return fmt.Errorf("Block %d has the state root %s, the execution of its transactions gives %s", block.Index,
+			block.Balances.Root.Hash, root)
+	}
+
+	return nil
+}
+
+// isRecord tells if the transaction of a block is a record of the engine, e.g. a reward, rather than a transaction
+// of a user, which has a sender, or failed when it has none
+func isRecord(tx *pb.Transaction) bool {
+	return tx.Status == "complete" && (len(tx.Sender) == 0 || tx.Type == TxSlash)
+}
+
+// checkIntegrity checks that the tries of the block are sound, that the balances hash to the state root of the header,
+// and that it links to the parent and matches its hash unless it's the genesis block, without parent
+func checkIntegrity(block, parent *pb.Block) error {
+	if block.Balances == nil {
+		return fmt.Errorf("Block %d has no balances", block.Index)
+	}
+
+	balances, err := merkle.LoadStateTree(block.Balances)
+	if err != nil {
+		return fmt.Errorf("Block %d: %v", block.Index, err)
+	}
+	if t, ok := balances.(*merkle.PatriciaTrie); ok && !t.Verify().OK() {
+		return fmt.Errorf("Block %d has corrupted balances", block.Index)
+	}
+	// rehashed by the checks above, up to the root
+	root, err := balances.RootHash()
+	if err != nil {
+		return fmt.Errorf("Block %d: %v", block.Index, err)
+	}
+	if stateRoot := utils.BlockHeader(block).StateRoot; root != stateRoot {
+		return fmt.Errorf("Block %d has balances hashing to %s, not to its state root %s", block.Index, root,
+			stateRoot)
+	}
+	if parent == nil {
+		return nil
+	}
+
+	if block.PrevHash != parent.Hash {
+		return fmt.Errorf("Block %d doesn't link to block %d", block.Index, parent.Index)
+	}
+	if block.Txs == nil {
+		return fmt.Errorf("Block %d has no transactions", block.Index)
+	}
+	if !merkle.LoadPatriciaTrie(block.Txs).Verify().OK() {
+		return fmt.Errorf("Block %d has corrupted transactions", block.Index)
+	}
+	if hash := utils.HashBlock(block); hash != block.Hash {
+		return fmt.Errorf("Block %d has the hash %s, expected %s", block.Index, block.Hash, hash)
+	}
+
+	return nil
+}
+
+// prefix is the chain before a block, to verify the seal of the block as when it was added, or to seal it without
+// holding the lock, as the blocks are only ever appended
+type prefix struct {
+	*BlockChain
+	blocks     []*pb.Block
+	difficulty int32
+}
+
+// snapshot returns the chain of the first n blocks, under the lock
+func (bc *BlockChain) snapshot(n int) *prefix {
+	return &prefix{bc, bc.Blocks[:n], bc.Difficulty}
+}
+
+func (p *prefix) GetBlocks() []*pb.Block {
+	return p.blocks
+}
+
+func (p *prefix) GetDifficulty() int32 {
+	return p.difficulty
+}
+
+// GetBalance returns the balance of the account after the blocks of the prefix
+func (p *prefix) GetBalance(acc string) float64 {
+	return p.balanceIn(p.blocks, acc)
+}
+
+// validateGenesis checks that the genesis block is the one of the spec
+func (bc *BlockChain) validateGenesis() error {
+	if len(bc.Blocks) == 0 {
+		return fmt.Errorf("No genesis block")
+	}
+	if len(bc.GenesisSpec) == 0 {
+		return fmt.Errorf("No genesis spec")
+	}
+
+	spec, err := genesis.Parse(bc.GenesisSpec)
+	if err != nil {
+		return fmt.Errorf("Genesis spec: %v", err)
+	}
+
+	want, err := spec.Block()
+	if err != nil {
+		return err
+	}
+
+	got := bc.Blocks[0]
+	if got.Hash != want.Hash {
+		return fmt.Errorf("Genesis block has the hash %s, the spec %s", got.Hash, want.Hash)
+	}
+	if got.Balances == nil || got.Balances.Root.Hash != want.Balances.Root.Hash {
+		return fmt.Errorf("Genesis block doesn't hold the allocations of the spec")
+	}
+
+	return nil
+}
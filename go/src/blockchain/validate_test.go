@@ -0,0 +1,243 @@
+package blockchain
+
+import (
+	"config"
+	"consensus"
+	"context"
+	"genesis"
+	"keys"
+	"merkle"
+	"proto"
+	"testing"
+	"utils"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	bc := newTestChain(t)
+	assert.Nil(t, bc.Validate())
+	bc.AddTransaction("receiverhash", 10.0)
+	assert.Nil(t, bc.MineBlock())
+	bc.AddTransaction("receiverhash", 5.0)
+	assert.Nil(t, bc.MineBlock())
+	assert.Nil(t, bc.Validate())
+}
+
+func TestValidateWithoutSpec(t *testing.T) {
+	bc := newTestChain(t)
+	assert.Nil(t, bc.MineBlock())
+	bc.GenesisSpec = nil
+	assert.EqualError(t, bc.Validate(), "No genesis spec")
+}
+
+func TestValidateBrokenLink(t *testing.T) {
+	bc := newTestChain(t)
+	assert.Nil(t, bc.MineBlock())
+	assert.Nil(t, bc.MineBlock())
+	bc.Blocks[2].PrevHash = "forged"
+	assert.NotNil(t, bc.Validate())
+}
+
+func TestValidateTamperedTx(t *testing.T) {
+	bc := newTestChain(t)
+	bc.AddTransaction("receiverhash", 10.0)
+	assert.Nil(t, bc.MineBlock())
+	for _, n := range bc.Blocks[1].Txs.Ht {
+		if len(n.Val) > 0 {
+			n.Val = "forged"
+		}
+	}
+	assert.NotNil(t, bc.Validate())
+}
+
+func TestValidateTamperedHash(t *testing.T) {
+	bc := newTestChain(t)
+	assert.Nil(t, bc.MineBlock())
+	bc.Blocks[1].Hash = "forged"
+	assert.NotNil(t, bc.Validate())
+}
+
+func TestValidateRehashed(t *testing.T) {
+	// a tampered block, even rehashed, breaks the link of the next one
+	bc := newTestChain(t)
+	assert.Nil(t, bc.MineBlock())
+	assert.Nil(t, bc.MineBlock())
+	bc.Blocks[1].Timestamp++
+	assert.EqualError(t, bc.Validate(), "Block 1 has the hash "+bc.Blocks[1].Hash+", expected "+
+		utils.HashBlock(bc.Blocks[1]))
+	assert.Nil(t, bc.engine.Seal(context.Background(), bc, bc.Blocks[1]))
+	bc.Blocks[1].Hash = utils.HashBlock(bc.Blocks[1])
+	assert.EqualError(t, bc.Validate(), "Block 2 doesn't link to block 1")
+}
+
+func TestBlockHash(t *testing.T) {
+	bc := newTestChain(t)
+	bc.AddTransaction("receiverhash", 10.0)
+	assert.Nil(t, bc.MineBlock())
+	assert.Nil(t, bc.MineBlock())
+	assert.Nil(t, bc.MineBlock())
+
+	// empty blocks have the same tries, but not the same hash
+	a, b := utils.BlockHeader(bc.Blocks[2]), utils.BlockHeader(bc.Blocks[3])
+	assert.Equal(t, a.TxRoot, b.TxRoot)
+	assert.Equal(t, a.StateRoot, b.StateRoot)
+	assert.NotEqual(t, bc.Blocks[2].Hash, bc.Blocks[3].Hash)
+	assert.Equal(t, bc.Blocks[2].Hash, bc.Blocks[3].PrevHash)
+
+	// the hash commits to every field of the header, so blocks with the same body don't collide
+	block := bc.Blocks[1]
+	assert.Equal(t, block.Hash, utils.HashBlock(block))
+	for _, tamper := range []func(b *pb.Block){
+		func(b *pb.Block) { b.Index++ },
+		func(b *pb.Block) { b.PrevHash = "other" },
+		func(b *pb.Block) { b.Proof++ },
+		func(b *pb.Block) { b.Timestamp++ },
+		func(b *pb.Block) { b.Difficulty++ },
+		func(b *pb.Block) { b.ChainId = "other" },
+		func(b *pb.Block) { b.Signer = "other" },
+		func(b *pb.Block) { b.Votes = []*pb.Vote{{Validator: "other"}} },
+		func(b *pb.Block) { b.Evidence = []*pb.Evidence{{}} },
+		func(b *pb.Block) { b.Txs = bc.Blocks[2].Txs },
+		func(b *pb.Block) { b.Balances = bc.Blocks[0].Balances },
+	} {
+		tampered := proto.Clone(block).(*pb.Block)
+		tamper(tampered)
+		assert.NotEqual(t, block.Hash, utils.HashBlock(tampered))
+	}
+
+	// but to the signature, which signs the header, nor to the commit certificate, made after
+	signed := proto.Clone(block).(*pb.Block)
+	signed.Signature, signed.Commit = []byte("signature"), &pb.Commit{Height: 1}
+	assert.Equal(t, block.Hash, utils.HashBlock(signed))
+}
+
+func TestAddBlockHash(t *testing.T) {
+	bc := newTestChain(t, WithEngine(consensus.NewDev()))
+	block, err := bc.ProposeBlock()
+	assert.Nil(t, err)
+	block.Timestamp++
+	assert.NotNil(t, bc.AddBlock(block))
+	block.Hash = utils.HashBlock(block)
+	assert.Nil(t, bc.AddBlock(block))
+	assert.Equal(t, block.Hash, bc.Blocks[1].Hash)
+}
+
+func TestAddBlockForgedBalances(t *testing.T) {
+	for _, kind := range []string{merkle.StatePatricia, merkle.StateSparse} {
+		spec := genesis.Default(config.Accounts{{Address: "alice", Val: 10}})
+		spec.StateTree = kind
+		spec.Consensus = genesis.Consensus{Engine: genesis.EngineDev}
+		bc, err := NewBlockChain(WithGenesis(*spec), WithUser("alice"))
+		assert.Nil(t, err)
+		bc.AddTransaction("bob", 4)
+		block, err := bc.ProposeBlock()
+		assert.Nil(t, err)
+
+		// balances forged under the state root of the header, which keeps the hash of the block
+		forged := proto.Clone(block).(*pb.Block)
+		forged.Balances = &pb.Tree{Kind: kind, Root: &pb.Node{Hash: block.Balances.Root.Hash}}
+		assert.Equal(t, block.Hash, utils.HashBlock(forged))
+		assert.NotNil(t, bc.AddBlock(forged))
+
+		forged = proto.Clone(block).(*pb.Block)
+		for _, n := range forged.Balances.Ht {
+			if n.Val == "4.000000" {
+				n.Val = "1000.000000"
+			}
+		}
+		assert.Equal(t, block.Hash, utils.HashBlock(forged))
+		assert.NotNil(t, bc.AddBlock(forged))
+
+		assert.Nil(t, bc.AddBlock(block))
+		assert.Equal(t, 4.0, bc.GetBalance("bob"))
+	}
+}
+
+func TestCheckProposalExecution(t *testing.T) {
+	priv, err := keys.Generate()
+	assert.Nil(t, err)
+	spec := genesis.Default(config.Accounts{{Address: "alice", Val: 10}})
+	spec.Rewards = []genesis.Reward{{Height: 1, Val: 5}}
+	spec.Consensus = genesis.Consensus{Engine: genesis.EngineBFT, Signers: []string{keys.Public(priv)}}
+	bc, err := NewBlockChain(WithGenesis(*spec), WithUser("alice"), WithKey(priv))
+	assert.Nil(t, err)
+	bc.AddTransaction("bob", 4)
+	bc.AddTransaction("bob", 100)
+	bc.AddTransactionFrom("bob", "carol", 3)
+
+	block, err := bc.ProposeBlock()
+	assert.Nil(t, err)
+	assert.Nil(t, bc.CheckProposal(block))
+
+	// a validator signing a block with forged balances, consistent with its header, doesn't get votes
+	reseal := func(block *pb.Block) {
+		block.Hash = utils.HashBlock(block)
+		assert.Nil(t, bc.engine.Seal(context.Background(), bc, block))
+		block.Hash = utils.HashBlock(block)
+	}
+	forged := proto.Clone(block).(*pb.Block)
+	balances, err := merkle.LoadStateTree(forged.Balances)
+	assert.Nil(t, err)
+	assert.Nil(t, balances.UpsertFloat("mallory", 1000))
+	forged.Balances, err = balances.ToTree()
+	assert.Nil(t, err)
+	reseal(forged)
+	assert.Nil(t, checkIntegrity(forged, bc.Blocks[0]))
+	assert.NotNil(t, bc.CheckProposal(forged))
+
+	// nor one with a forged status
+	forged = proto.Clone(block).(*pb.Block)
+	trie := merkle.LoadPatriciaTrie(forged.Txs)
+	txs := merkle.NewTypedTrie[*pb.Transaction](trie, TxCodec)
+	var failed *pb.Transaction
+	assert.Nil(t, txs.ForEach(func(id string, tx *pb.Transaction) error {
+		if tx.Status == "failed" {
+			failed = tx
+		}
+		return nil
+	}))
+	failed.Status = "complete"
+	assert.Nil(t, txs.Upsert(failed.Id, failed))
+	forged.Txs = &trie.Tree
+	reseal(forged)
+	assert.NotNil(t, bc.CheckProposal(forged))
+}
+
+func TestAddBlockExecution(t *testing.T) {
+	// the block of another miner pays its coinbase, whoever executes it again
+	spec := genesis.Default(config.Accounts{{Address: "alice", Val: 10}})
+	alice, err := NewBlockChain(WithGenesis(*spec), WithUser("alice"))
+	assert.Nil(t, err)
+	bob, err := NewBlockChain(WithGenesis(*spec), WithUser("bob"))
+	assert.Nil(t, err)
+	alice.AddTransaction("carol", 4)
+	block, err := alice.ProposeBlock()
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", block.Coinbase)
+
+	// a miner sealing a block with forged balances, consistent with its header, isn't followed
+	forged := proto.Clone(block).(*pb.Block)
+	balances, err := merkle.LoadStateTree(forged.Balances)
+	assert.Nil(t, err)
+	assert.Nil(t, balances.UpsertFloat("mallory", 1000))
+	forged.Balances, err = balances.ToTree()
+	assert.Nil(t, err)
+	assert.Nil(t, alice.engine.Seal(context.Background(), alice, forged))
+	forged.Hash = utils.HashBlock(forged)
+	assert.Nil(t, checkIntegrity(forged, bob.Blocks[0]))
+	assert.Nil(t, bob.engine.VerifySeal(bob, forged))
+	executed := "Block 1 has the state root " + forged.Balances.Root.Hash + ", the execution of its transactions gives " +
+		block.Balances.Root.Hash
+	assert.EqualError(t, bob.AddBlock(forged), executed)
+
+	assert.Nil(t, bob.AddBlock(block))
+	assert.Equal(t, alice.GetBalance("alice")-4+spec.RewardAt(1), bob.GetBalance("alice"))
+	assert.Equal(t, 4.0, bob.GetBalance("carol"))
+	assert.Nil(t, bob.Validate())
+
+	// nor replayed by the validation
+	bob.Blocks[1] = forged
+	assert.EqualError(t, bob.Validate(), executed)
+}
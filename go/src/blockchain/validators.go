@@ -0,0 +1,70 @@
+package blockchain
+
+// Votes on the validators of an authority engine, e.g. consensus.PoA
+// The proposals of the node are votes it casts in every block it signs until they are in effect or discarded.
+import (
+	"consensus"
+	"fmt"
+	"genesis"
+	"keys"
+	"proto"
+)
+
+// Validators returns the validators of the next block
+func (bc *BlockChain) Validators() ([]string, error) {
+	bc.RWMutex.RLock()
+	defer bc.RWMutex.RUnlock()
+	e, err := bc.authority()
+	if err != nil {
+		return nil, err
+	}
+
+	return e.Validators(bc, int32(len(bc.Blocks)))
+}
+
+// Propose votes for adding or removing the validator, replacing the proposal on it if any
+func (bc *BlockChain) Propose(validator string, add bool) error {
+	if _, err := keys.ParsePublic(validator); err != nil {
+		return err
+	}
+
+	bc.RWMutex.Lock()
+	defer bc.RWMutex.Unlock()
+	if bc.spec.Consensus.Engine != genesis.EnginePoA {
+		return fmt.Errorf("The %s engine has no votes on validators", bc.spec.Consensus.Engine)
+	}
+
+	bc.discard(validator)
+	bc.Proposals = append(bc.Proposals, &pb.Vote{Validator: validator, Add: add})
+	return nil
+}
+
+// Discard drops the proposal on the validator
+func (bc *BlockChain) Discard(validator string) error {
+	bc.RWMutex.Lock()
+	defer bc.RWMutex.Unlock()
+	if !bc.discard(validator) {
+		return fmt.Errorf("No proposal on %s", validator)
+	}
+
+	return nil
+}
+
+func (bc *BlockChain) discard(validator string) bool {
+	for i, v := range bc.Proposals {
+		if v.Validator == validator {
+			bc.Proposals = append(bc.Proposals[:i], bc.Proposals[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (bc *BlockChain) authority() (consensus.Authority, error) {
+	e, ok := bc.engine.(consensus.Authority)
+	if !ok {
+		return nil, fmt.Errorf("The %s engine has no validators", bc.spec.Consensus.Engine)
+	}
+
+	return e, nil
+}
@@ -0,0 +1,71 @@
+package blockchain
+
+import (
+	"config"
+	"consensus"
+	"genesis"
+	"io/ioutil"
+	"keys"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProposals(t *testing.T) {
+	alice, _ := keys.Generate()
+	bob, _ := keys.Generate()
+	spec := genesis.Default(config.Accounts{{Address: "alice", Val: 10}})
+	spec.Consensus = genesis.Consensus{Engine: genesis.EnginePoA, Signers: []string{keys.Public(alice)}}
+	bc, err := NewBlockChain(WithGenesis(*spec), WithUser("alice"), WithKey(alice))
+	assert.Nil(t, err)
+
+	assert.NotNil(t, bc.Propose("bob", true))
+	assert.NotNil(t, bc.Discard(keys.Public(bob)))
+	assert.Nil(t, bc.Propose(keys.Public(bob), false))
+	assert.Nil(t, bc.Propose(keys.Public(bob), true))
+	assert.Equal(t, 1, len(bc.Proposals))
+
+	// the vote of the only validator is a majority
+	assert.Nil(t, bc.MineBlock())
+	assert.Equal(t, 1, len(bc.Blocks[1].Votes))
+	validators, err := bc.Validators()
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{keys.Public(alice), keys.Public(bob)}, validators)
+
+	// the validators take turns
+	for i := 0; i < 4; i++ {
+		key := alice
+		if validators[len(bc.Blocks)%2] == keys.Public(bob) {
+			key = bob
+		}
+		bc.engine = consensus.NewPoA(key)
+		assert.Nil(t, bc.MineBlock())
+		assert.Equal(t, keys.Public(key), bc.Blocks[len(bc.Blocks)-1].Signer)
+		assert.Empty(t, bc.Blocks[len(bc.Blocks)-1].Votes)
+	}
+	assert.Nil(t, bc.Validate())
+
+	// the proposals are saved with the chain
+	assert.Nil(t, bc.Propose(keys.Public(alice), false))
+	dir, err := ioutil.TempDir("", "chain")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "chain.pb")
+	assert.Nil(t, bc.Save(path))
+	loaded, err := LoadBlockChain(path, WithKey(alice))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(loaded.Proposals))
+	assert.Nil(t, loaded.Discard(keys.Public(alice)))
+	assert.Equal(t, 1, len(loaded.Proposals))
+	assert.Nil(t, loaded.Validate())
+}
+
+func TestValidatorsOtherEngines(t *testing.T) {
+	bc := newTestChain(t)
+	_, err := bc.Validators()
+	assert.NotNil(t, err)
+	pub, _ := keys.Generate()
+	assert.NotNil(t, bc.Propose(keys.Public(pub), true))
+}
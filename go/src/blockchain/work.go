@@ -0,0 +1,64 @@
+package blockchain
+
+// Work of external miners
+// A miner gets the template of the next block with its work, searches for the proof of the work elsewhere, and submits
+// it. The templates are kept by their work until a block is added, as their proofs would be stale then. The reward
+// of the block goes to the user of the chain, as for a block mined by the node.
+import (
+	"consensus"
+	"fmt"
+	"genesis"
+	"proto"
+	"utils"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// maxTemplates is the count of templates kept, they are all forgotten past it
+const maxTemplates = 64
+
+// GetWork makes the next block with at most maxTxs open transactions, no limit if 0, for an external miner to find
+// the proof of, and returns it with its work, see consensus.Work
+func (bc *BlockChain) GetWork(maxTxs int) (*pb.Block, string, error) {
+	if _, ok := bc.engine.(*consensus.PoW); !ok {
+		return nil, "", fmt.Errorf("External mining needs the %s engine", genesis.EnginePoW)
+	}
+
+	bc.RWMutex.Lock()
+	defer bc.RWMutex.Unlock()
+	block, err := bc.newBlock(maxTxs)
+	if err != nil {
+		return nil, "", err
+	}
+	work, err := consensus.Work(utils.BlockHeader(block))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(bc.templates) >= maxTemplates {
+		bc.templates = nil
+	}
+	if bc.templates == nil {
+		bc.templates = make(map[string]*pb.Block)
+	}
+	bc.templates[work] = block
+	return block, work, nil
+}
+
+// SubmitWork adds the block of the work with the proof
+func (bc *BlockChain) SubmitWork(work string, proof int64) (*pb.Block, error) {
+	bc.RWMutex.Lock()
+	defer bc.RWMutex.Unlock()
+	template, ok := bc.templates[work]
+	if !ok {
+		return nil, fmt.Errorf("Unknown or stale work %s", work)
+	}
+
+	block := proto.Clone(template).(*pb.Block)
+	block.Proof = proof
+	block.Hash = utils.HashBlock(block)
+	if err := bc.addNewBlock(block, true); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
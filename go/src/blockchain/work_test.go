@@ -0,0 +1,49 @@
+package blockchain
+
+import (
+	"consensus"
+	"context"
+	"testing"
+	"utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWork(t *testing.T) {
+	bc := newTestChain(t)
+	id := bc.AddTransaction("bob", 1)
+	block, work, err := bc.GetWork(0)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), block.Index)
+	got, err := consensus.Work(utils.BlockHeader(block))
+	assert.Nil(t, err)
+	assert.Equal(t, work, got)
+
+	// the proof is checked, the template is kept until a block is added
+	proof, err := consensus.Search(context.Background(), work, int(bc.Difficulty), 1, nil)
+	assert.Nil(t, err)
+	_, err = bc.SubmitWork(work, proof+1)
+	assert.NotNil(t, err)
+	_, err = bc.SubmitWork("other", proof)
+	assert.NotNil(t, err)
+	added, err := bc.SubmitWork(work, proof)
+	assert.Nil(t, err)
+	assert.Equal(t, proof, added.Proof)
+	assert.Equal(t, utils.HashBlock(added), added.Hash)
+	assert.Equal(t, 2, len(bc.Blocks))
+	assert.Equal(t, "complete", bc.GetTransaction(id).Status)
+	assert.Nil(t, bc.Validate())
+
+	// stale work
+	_, stale, err := bc.GetWork(0)
+	assert.Nil(t, err)
+	assert.Nil(t, bc.MineBlock())
+	_, err = bc.SubmitWork(stale, proof)
+	assert.NotNil(t, err)
+	_, err = bc.SubmitWork(work, proof)
+	assert.NotNil(t, err)
+
+	bc = newTestChain(t, WithEngine(consensus.NewDev()))
+	_, _, err = bc.GetWork(0)
+	assert.NotNil(t, err)
+}
@@ -1,12 +1,35 @@
 package config
 
+// Node configuration, read from a JSON file
+// The file is given by its path, else by $HAWAII_CONFIG, so nothing depends on the working directory. The env vars
+// override the file, and the callers apply their flags last, e.g. onebox -user, before validating.
+// A Config is a plain value handed to the constructors, there is no global config, so several nodes or wallets
+// with different configs can run in one process.
 import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
 	config "github.com/micro/go-config"
 	"github.com/micro/go-config/source/file"
 )
 
-var Usrcfg User
-var InitialAccounts Accounts
+const (
+	EnvPath    = "HAWAII_CONFIG"  // path of the config file
+	EnvUser    = "HAWAII_USER"    // address of the user
+	EnvDataDir = "HAWAII_DATADIR" // data dir of the node
+	EnvKey     = "HAWAII_KEY"     // path of the validator key
+)
+
+type Config struct {
+	User    User     `json:"user"`
+	Init    Accounts `json:"init"`    // balances of the genesis block, unless there is a genesis spec
+	Genesis string   `json:"genesis"` // path of the genesis spec, relative to the config file
+	DataDir string   `json:"datadir"` // where a node keeps its chain
+	Key     string   `json:"key"`     // path of the validator key file, relative to the config file, see keys.Load
+	Workers int      `json:"workers"` // goroutines mining with the pow engine, one per CPU if 0
+}
 
 type User struct {
 	Address string `json:"address"`
@@ -20,10 +43,78 @@ type Account struct {
 
 type Accounts []Account
 
-func InitConfig(cfgfile string) {
-	config.Load(file.NewSource(
-		file.WithPath(cfgfile),
-	))
-	config.Get("user").Scan(&Usrcfg)
-	config.Get("init").Scan(&InitialAccounts)
+// Load reads the config file at path, or at $HAWAII_CONFIG if path is empty, applies the env overrides and validates it
+func Load(path string) (*Config, error) {
+	if len(path) == 0 {
+		path = os.Getenv(EnvPath)
+	}
+	if len(path) == 0 {
+		return nil, fmt.Errorf("No config file given, set its path or %s", EnvPath)
+	}
+
+	conf := config.NewConfig()
+	if err := conf.Load(file.NewSource(file.WithPath(path))); err != nil {
+		return nil, fmt.Errorf("Config %s: %v", path, err)
+	}
+
+	c := &Config{}
+	if err := conf.Scan(c); err != nil {
+		return nil, fmt.Errorf("Config %s: %v", path, err)
+	}
+	if len(c.Genesis) > 0 && !filepath.IsAbs(c.Genesis) {
+		c.Genesis = filepath.Join(filepath.Dir(path), c.Genesis)
+	}
+	if len(c.Key) > 0 && !filepath.IsAbs(c.Key) {
+		c.Key = filepath.Join(filepath.Dir(path), c.Key)
+	}
+
+	c.ApplyEnv()
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("Config %s: %v", path, err)
+	}
+	return c, nil
+}
+
+// ApplyEnv overrides the config with the env vars that are set
+func (c *Config) ApplyEnv() {
+	if addr := os.Getenv(EnvUser); len(addr) > 0 {
+		c.User.Address = addr
+	}
+	if dir := os.Getenv(EnvDataDir); len(dir) > 0 {
+		c.DataDir = dir
+	}
+	if key := os.Getenv(EnvKey); len(key) > 0 {
+		c.Key = key
+	}
+}
+
+// Validate checks that the config has a user, sound initial accounts and workers
+func (c *Config) Validate() error {
+	if len(c.User.Address) == 0 {
+		return fmt.Errorf("No user address")
+	}
+	if c.Workers < 0 {
+		return fmt.Errorf("Negative workers %d", c.Workers)
+	}
+
+	return c.Init.Validate()
+}
+
+// Validate checks that the accounts have an address, listed once, and a finite positive balance
+func (accs Accounts) Validate() error {
+	seen := make(map[string]bool)
+	for i, acc := range accs {
+		if len(acc.Address) == 0 {
+			return fmt.Errorf("Initial account %d has no address", i)
+		}
+		if acc.Val <= 0 || math.IsNaN(acc.Val) || math.IsInf(acc.Val, 0) {
+			return fmt.Errorf("Initial account %s has the invalid balance %f", acc.Address, acc.Val)
+		}
+		if seen[acc.Address] {
+			return fmt.Errorf("Initial account %s is listed twice", acc.Address)
+		}
+		seen[acc.Address] = true
+	}
+
+	return nil
 }
@@ -1,13 +1,79 @@
 package config
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
-func TestConfig(t *testing.T) {
-	InitConfig("config.json")
-	assert.True(t, len(Usrcfg.Address) > 0)
-	assert.Equal(t, 100.0, InitialAccounts[0].Val)
+func TestLoad(t *testing.T) {
+	c, err := Load("config.json")
+	assert.Nil(t, err)
+	assert.Equal(t, "00000000000000000000000000000000", c.User.Address)
+	assert.Equal(t, 3, len(c.Init))
+	assert.Equal(t, 100.0, c.Init[2].Val)
+}
+
+func TestLoadEnv(t *testing.T) {
+	path, err := filepath.Abs("config.json")
+	assert.Nil(t, err)
+	os.Setenv(EnvPath, path)
+	os.Setenv(EnvUser, "envuser")
+	os.Setenv(EnvDataDir, "/var/lib/hawaii")
+	os.Setenv(EnvKey, "/etc/hawaii/validator.key")
+	defer os.Unsetenv(EnvPath)
+	defer os.Unsetenv(EnvUser)
+	defer os.Unsetenv(EnvDataDir)
+	defer os.Unsetenv(EnvKey)
+
+	c, err := Load("")
+	assert.Nil(t, err)
+	assert.Equal(t, "envuser", c.User.Address)
+	assert.Equal(t, "/var/lib/hawaii", c.DataDir)
+	assert.Equal(t, "/etc/hawaii/validator.key", c.Key)
+	assert.Equal(t, 3, len(c.Init))
+}
+
+func TestLoadPaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.json")
+	content := `{"user": {"address": "u"}, "genesis": "genesis.json", "key": "keys/validator.key"}`
+	assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+
+	// the paths are relative to the config file
+	c, err := Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "genesis.json"), c.Genesis)
+	assert.Equal(t, filepath.Join(dir, "keys/validator.key"), c.Key)
+}
+
+func TestLoadErrors(t *testing.T) {
+	os.Unsetenv(EnvPath)
+	_, err := Load("")
+	assert.NotNil(t, err)
+	_, err = Load("missing.json")
+	assert.NotNil(t, err)
+
+	dir, err := ioutil.TempDir("", "config")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{
+		"malformed": `{"user": `,
+		"no user":   `{"init": [{"address": "a", "val": 1}]}`,
+		"no addr":   `{"user": {"address": "u"}, "init": [{"val": 1}]}`,
+		"negative":  `{"user": {"address": "u"}, "init": [{"address": "a", "val": -1}]}`,
+		"zero":      `{"user": {"address": "u"}, "init": [{"address": "a", "val": 0}]}`,
+		"twice":     `{"user": {"address": "u"}, "init": [{"address": "a", "val": 1}, {"address": "a", "val": 2}]}`,
+		"workers":   `{"user": {"address": "u"}, "workers": -1}`,
+	} {
+		path := filepath.Join(dir, "config.json")
+		assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+		_, err = Load(path)
+		assert.NotNil(t, err, name)
+	}
 }
@@ -0,0 +1,228 @@
+package consensus
+
+// Byzantine fault tolerant finality, of the Tendermint kind
+// A fixed set of validators, the signers of the genesis spec, agree on every block in rounds: the proposer of the
+// round proposes a block, the validators prevote for it, then precommit for it once more than 2/3 of them prevoted
+// for it. A block is committed by the precommits of more than 2/3 of the validators, the commit certificate stored
+// with the block. As long as less than 1/3 of the validators are faulty, no two blocks are committed at a height, so
+// a committed block is final. The rounds run in package bft, this engine makes and checks the blocks and the votes.
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"keys"
+	"proto"
+	"sort"
+	"utils"
+)
+
+// Types of the votes of a round
+const (
+	Prevote   = "prevote"
+	Precommit = "precommit"
+)
+
+type BFT struct {
+	key ed25519.PrivateKey // of the local validator, nil if the node only verifies
+}
+
+func NewBFT(key ed25519.PrivateKey) *BFT {
+	return &BFT{key: key}
+}
+
+// Validators returns the validators, the same at every height
+func (e *BFT) Validators(chain Chain, height int32) ([]string, error) {
+	list := append([]string(nil), chain.Spec().Consensus.Signers...)
+	sort.Strings(list)
+	return list, nil
+}
+
+// Proposer returns the proposer of the round at the height among the validators, in turn
+func Proposer(validators []string, height, round int32) string {
+	return validators[int(height+round)%len(validators)]
+}
+
+// Quorum returns the number of votes of more than 2/3 of the validators
+func Quorum(validators []string) int {
+	return len(validators)*2/3 + 1
+}
+
+// Prepare names the local validator as signer of the block
+func (e *BFT) Prepare(chain Chain, block *pb.Block) error {
+	if e.key == nil {
+		return fmt.Errorf("No validator key to sign blocks")
+	}
+
+	validators, _ := e.Validators(chain, block.Index)
+	signer := keys.Public(e.key)
+	if !contains(validators, signer) {
+		return fmt.Errorf("%s isn't a validator", signer)
+	}
+
+	block.Signer = signer
+	return nil
+}
+
+// Finalize pays the block reward to the signer
+func (e *BFT) Finalize(chain Chain, block *pb.Block, state State) error {
+	payReward(chain, block, block.Signer, state)
+	return nil
+}
+
+// Seal signs the block, which makes it ready to be proposed
+func (e *BFT) Seal(ctx context.Context, chain Chain, block *pb.Block) error {
+	if e.key == nil {
+		return fmt.Errorf("No validator key to sign blocks")
+	}
+
+	return sign(e.key, block)
+}
+
+// VerifyProposal checks that the block is signed by a validator, before voting for it
+func (e *BFT) VerifyProposal(chain Chain, block *pb.Block) error {
+	validators, _ := e.Validators(chain, block.Index)
+	if !contains(validators, block.Signer) {
+		return fmt.Errorf("Block %d is signed by %q, who isn't a validator", block.Index, block.Signer)
+	}
+
+	return verifySignature(block)
+}
+
+// VerifySeal checks that the block is signed by a validator and committed
+func (e *BFT) VerifySeal(chain Chain, block *pb.Block) error {
+	if err := e.VerifyProposal(chain, block); err != nil {
+		return err
+	}
+
+	validators, _ := e.Validators(chain, block.Index)
+	return CheckCommit(validators, block)
+}
+
+// BlockID returns the ID of the block the validators vote for, the hash of its header
+func BlockID(block *pb.Block) (string, error) {
+	return utils.HashBlock(block), nil
+}
+
+// CheckCommit checks that the commit certificate of the block holds the precommits for it of more than 2/3 of the
+// validators, in the same round
+func CheckCommit(validators []string, block *pb.Block) error {
+	c := block.Commit
+	if c == nil {
+		return fmt.Errorf("Block %d has no commit certificate", block.Index)
+	}
+
+	id, err := BlockID(block)
+	if err != nil {
+		return err
+	}
+	if c.Height != block.Index || c.BlockId != id {
+		return fmt.Errorf("Commit certificate of block %d is for another block", block.Index)
+	}
+
+	voted := make(map[string]bool)
+	for _, v := range c.Precommits {
+		if v.Type != Precommit || v.Height != c.Height || v.Round != c.Round || v.BlockId != c.BlockId {
+			return fmt.Errorf("Commit certificate of block %d holds a vote for something else", block.Index)
+		}
+		if voted[v.Validator] {
+			return fmt.Errorf("Commit certificate of block %d holds two votes of %s", block.Index, v.Validator)
+		}
+		if err := CheckVote(validators, v); err != nil {
+			return fmt.Errorf("Commit certificate of block %d: %v", block.Index, err)
+		}
+		voted[v.Validator] = true
+	}
+	if len(voted) < Quorum(validators) {
+		return fmt.Errorf("Commit certificate of block %d has %d precommits of %d validators", block.Index, len(voted),
+			len(validators))
+	}
+
+	return nil
+}
+
+// NewVote returns the vote of the key, for the block ID or for none if empty
+func NewVote(key ed25519.PrivateKey, typ string, height, round int32, blockID string) (*pb.ConsensusVote, error) {
+	v := &pb.ConsensusVote{Type: typ, Height: height, Round: round, BlockId: blockID, Validator: keys.Public(key)}
+	payload, err := utils.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	v.Signature = keys.Sign(key, payload)
+	return v, nil
+}
+
+// CheckVote checks that the vote is signed by one of the validators
+func CheckVote(validators []string, v *pb.ConsensusVote) error {
+	if v.Type != Prevote && v.Type != Precommit {
+		return fmt.Errorf("Unknown vote %q", v.Type)
+	}
+	if !contains(validators, v.Validator) {
+		return fmt.Errorf("Vote of %q, who isn't a validator", v.Validator)
+	}
+
+	unsigned := *v
+	unsigned.Signature = nil
+	payload, err := utils.Encode(&unsigned)
+	if err != nil {
+		return err
+	}
+	if !keys.Verify(v.Validator, payload, v.Signature) {
+		return fmt.Errorf("Vote of %s has an invalid signature", v.Validator)
+	}
+
+	return nil
+}
+
+// NewProposal returns the proposal of the block by the key in the round
+func NewProposal(key ed25519.PrivateKey, block *pb.Block, round, validRound int32) (*pb.Proposal, error) {
+	p := &pb.Proposal{Block: block, Height: block.Index, Round: round, ValidRound: validRound,
+		Proposer: keys.Public(key)}
+	payload, err := proposalPayload(p)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Signature = keys.Sign(key, payload)
+	return p, nil
+}
+
+// CheckProposal checks that the proposal is signed by the proposer of its round
+func CheckProposal(validators []string, p *pb.Proposal) error {
+	if p.Block == nil || p.Block.Index != p.Height {
+		return fmt.Errorf("Proposal of round %d at height %d lacks its block", p.Round, p.Height)
+	}
+	if proposer := Proposer(validators, p.Height, p.Round); p.Proposer != proposer {
+		return fmt.Errorf("Proposal of %q in round %d, whose proposer is %s", p.Proposer, p.Round, proposer)
+	}
+
+	payload, err := proposalPayload(p)
+	if err != nil {
+		return err
+	}
+	if !keys.Verify(p.Proposer, payload, p.Signature) {
+		return fmt.Errorf("Proposal of %s has an invalid signature", p.Proposer)
+	}
+
+	return nil
+}
+
+// proposalPayload returns the bytes signed by the proposer, the proposal with the ID of the block instead of the block
+func proposalPayload(p *pb.Proposal) ([]byte, error) {
+	id, err := BlockID(p.Block)
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.Encode(&pb.Proposal{BlockId: id, Height: p.Height, Round: p.Round, ValidRound: p.ValidRound,
+		Proposer: p.Proposer})
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
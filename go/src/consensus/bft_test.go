@@ -0,0 +1,116 @@
+package consensus
+
+import (
+	"context"
+	"crypto/ed25519"
+	"genesis"
+	"keys"
+	"proto"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// proposeBFT returns a block of the next height signed by the key
+func proposeBFT(t *testing.T, key ed25519.PrivateKey, c *testChain) *pb.Block {
+	e := NewBFT(key)
+	block := c.next()
+	assert.Nil(t, e.Prepare(c, block))
+	assert.Nil(t, e.Finalize(c, block, credits{}))
+	block.Hash = "block"
+	assert.Nil(t, e.Seal(context.Background(), c, block))
+	return block
+}
+
+// commitBFT adds the precommits of the keys in the round to the block
+func commitBFT(t *testing.T, privs []ed25519.PrivateKey, block *pb.Block, round int32) {
+	id, err := BlockID(block)
+	assert.Nil(t, err)
+	block.Commit = &pb.Commit{Height: block.Index, Round: round, BlockId: id}
+	for _, priv := range privs {
+		v, err := NewVote(priv, Precommit, block.Index, round, id)
+		assert.Nil(t, err)
+		block.Commit.Precommits = append(block.Commit.Precommits, v)
+	}
+}
+
+func TestBFTProposer(t *testing.T) {
+	validators := []string{"a", "b", "c", "d"}
+	assert.Equal(t, "b", Proposer(validators, 1, 0))
+	assert.Equal(t, "c", Proposer(validators, 1, 1))
+	assert.Equal(t, "a", Proposer(validators, 2, 2))
+	assert.Equal(t, 3, Quorum(validators))
+	assert.Equal(t, 3, Quorum(validators[:3]))
+	assert.Equal(t, 1, Quorum(validators[:1]))
+	assert.Equal(t, 5, Quorum(append(validators, "e", "f")))
+}
+
+func TestBFTCommit(t *testing.T) {
+	privs, c := newTestValidators(t, 4)
+	c.spec.Consensus.Engine = genesis.EngineBFT
+	e := NewBFT(nil)
+
+	// a proposed block is valid to vote for, not to add
+	block := proposeBFT(t, privs[1], c)
+	assert.Nil(t, e.VerifyProposal(c, block))
+	assert.NotNil(t, e.VerifySeal(c, block))
+
+	// it needs the precommits of more than 2/3 of the validators
+	commitBFT(t, privs[:2], block, 1)
+	assert.NotNil(t, e.VerifySeal(c, block))
+	commitBFT(t, privs[:3], block, 1)
+	assert.Nil(t, e.VerifySeal(c, block))
+
+	// all for the block in the same round, from different validators
+	commitBFT(t, []ed25519.PrivateKey{privs[0], privs[1], privs[0]}, block, 1)
+	assert.NotNil(t, CheckCommit(c.spec.Consensus.Signers, block))
+	commitBFT(t, privs[:3], block, 1)
+	block.Commit.Precommits[2].Round = 2
+	assert.NotNil(t, CheckCommit(c.spec.Consensus.Signers, block))
+	commitBFT(t, privs[:3], block, 1)
+	block.Commit.Precommits[2].Signature = block.Commit.Precommits[1].Signature
+	assert.NotNil(t, CheckCommit(c.spec.Consensus.Signers, block))
+
+	// the certificate is for the block
+	commitBFT(t, privs[:3], block, 1)
+	tampered := *block
+	tampered.Timestamp++
+	assert.NotNil(t, CheckCommit(c.spec.Consensus.Signers, &tampered))
+	assert.NotNil(t, e.VerifyProposal(c, &tampered))
+
+	// of the validators
+	outsider, _ := keys.Generate()
+	commitBFT(t, []ed25519.PrivateKey{privs[0], privs[1], outsider}, block, 1)
+	assert.NotNil(t, CheckCommit(c.spec.Consensus.Signers, block))
+	assert.NotNil(t, NewBFT(outsider).Prepare(c, c.next()))
+	assert.NotNil(t, e.Prepare(c, c.next()))
+}
+
+func TestBFTMessages(t *testing.T) {
+	privs, c := newTestValidators(t, 4)
+	c.spec.Consensus.Engine = genesis.EngineBFT
+	validators := c.spec.Consensus.Signers
+	block := proposeBFT(t, privs[1], c)
+
+	// votes are signed by the validators
+	v, err := NewVote(privs[2], Prevote, 1, 0, "")
+	assert.Nil(t, err)
+	assert.Nil(t, CheckVote(validators, v))
+	v.BlockId = "other"
+	assert.NotNil(t, CheckVote(validators, v))
+	v, _ = NewVote(privs[2], "vote", 1, 0, "")
+	assert.NotNil(t, CheckVote(validators, v))
+
+	// proposals by the proposer of the round
+	p, err := NewProposal(privs[1], block, 0, -1)
+	assert.Nil(t, err)
+	assert.Nil(t, CheckProposal(validators, p))
+	p.ValidRound = 0
+	assert.NotNil(t, CheckProposal(validators, p))
+	p, _ = NewProposal(privs[1], block, 1, -1)
+	assert.NotNil(t, CheckProposal(validators, p))
+	p, _ = NewProposal(privs[2], block, 1, 0)
+	assert.Nil(t, CheckProposal(validators, p))
+	p.Block = nil
+	assert.NotNil(t, CheckProposal(validators, p))
+}
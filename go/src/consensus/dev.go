@@ -0,0 +1,50 @@
+package consensus
+
+// Development engine, which seals the blocks without proof, e.g. for tests and local chains
+// A block is sealed once the block time of the spec has passed since its parent, so that a local chain mined back to
+// back makes blocks at the pace of the spec. The block keeps the timestamp it was made and executed with.
+import (
+	"context"
+	"proto"
+	"time"
+)
+
+type Dev struct{}
+
+func NewDev() *Dev {
+	return &Dev{}
+}
+
+// Prepare sets the local user as the coinbase of the block
+func (e *Dev) Prepare(chain Chain, block *pb.Block) error {
+	block.Coinbase = chain.GetUsr().GetAddr()
+	return nil
+}
+
+// Finalize pays the block reward to the coinbase of the block
+func (e *Dev) Finalize(chain Chain, block *pb.Block, state State) error {
+	payReward(chain, block, block.Coinbase, state)
+	return nil
+}
+
+// Seal waits for the block time since the parent
+func (e *Dev) Seal(ctx context.Context, chain Chain, block *pb.Block) error {
+	p, err := parent(chain, block)
+	if err != nil {
+		return err
+	}
+
+	next := time.Unix(0, p.Timestamp).Add(time.Duration(chain.Spec().BlockTime) * time.Second)
+	if wait := time.Until(next); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	return nil
+}
+
+func (e *Dev) VerifySeal(chain Chain, block *pb.Block) error {
+	return nil
+}
@@ -0,0 +1,91 @@
+package consensus
+
+// Consensus engines decide who makes the blocks and how the other nodes check them
+// The chain makes a block in 4 steps: Prepare sets the consensus fields of the new block, the transactions are
+// applied, Finalize changes the state after them, e.g. to pay the block reward, and Seal makes the block valid, which
+// may take long, e.g. to find the proof of work, so it stops once its context is done. VerifySeal checks the seal of
+// a block made by any node.
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"genesis"
+	"proto"
+)
+
+type Engine interface {
+	Prepare(chain Chain, block *pb.Block) error
+	Finalize(chain Chain, block *pb.Block, state State) error
+	Seal(ctx context.Context, chain Chain, block *pb.Block) error
+	VerifySeal(chain Chain, block *pb.Block) error
+}
+
+// Authority is an Engine where a set of validators makes the blocks
+type Authority interface {
+	Engine
+	Validators(chain Chain, height int32) ([]string, error) // of the block at the height
+}
+
+// Miner is an Engine whose seal is searched for, e.g. a proof of work
+type Miner interface {
+	Engine
+	HashRate() float64 // hashes per second of the current seal, else of the last one
+}
+
+// Finality is an Authority whose validators commit the blocks, proposed to them first
+type Finality interface {
+	Authority
+	VerifyProposal(chain Chain, block *pb.Block) error
+}
+
+// Chain is the view of the chain the engines work with, the blocks before the one they handle
+type Chain interface {
+	Spec() *genesis.Spec
+	GetDifficulty() int32
+	GetBlocks() []*pb.Block
+	GetUsr() *pb.User                                // the local user, who makes the blocks
+	GetProposals() []*pb.Vote                        // votes of the local validator
+	GetEvidence() []*pb.Evidence                     // of double signing seen by the node
+	Stakes(height int32) (map[string]float64, error) // bonded in the state before the block at the height
+}
+
+// State is the state of the block being made, after its transactions
+type State interface {
+	Credit(addr string, val float64)
+	Slash(addr string) float64 // burns the stake of addr, returns it
+}
+
+// New returns the engine of the genesis spec, with the key of the local validator if any
+func New(spec *genesis.Spec, key ed25519.PrivateKey) (Engine, error) {
+	switch spec.Consensus.Engine {
+	case "", genesis.EnginePoW:
+		return NewPoW(), nil
+	case genesis.EnginePoA:
+		return NewPoA(key), nil
+	case genesis.EnginePoS:
+		return NewPoS(key), nil
+	case genesis.EngineBFT:
+		return NewBFT(key), nil
+	case genesis.EngineDev:
+		return NewDev(), nil
+	}
+
+	return nil, fmt.Errorf("Unknown consensus engine %q", spec.Consensus.Engine)
+}
+
+// parent returns the block before the block
+func parent(chain Chain, block *pb.Block) (*pb.Block, error) {
+	blocks := chain.GetBlocks()
+	if block.Index < 1 || int(block.Index) > len(blocks) {
+		return nil, fmt.Errorf("No parent of block %d in a chain of %d blocks", block.Index, len(blocks))
+	}
+
+	return blocks[block.Index-1], nil
+}
+
+// payReward credits the reward of the block at its height to the address
+func payReward(chain Chain, block *pb.Block, addr string, state State) {
+	if reward := chain.Spec().RewardAt(block.Index); reward > 0 && len(addr) > 0 {
+		state.Credit(addr, reward)
+	}
+}
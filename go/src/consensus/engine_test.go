@@ -0,0 +1,121 @@
+package consensus
+
+import (
+	"context"
+	"genesis"
+	"proto"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testChain is a chain of blocks for the engines
+type testChain struct {
+	spec       *genesis.Spec
+	difficulty int32
+	blocks     []*pb.Block
+	user       string
+	proposals  []*pb.Vote
+	evidence   []*pb.Evidence
+	stakes     map[string]float64 // at every height
+}
+
+func newTestChain(user string) *testChain {
+	spec := genesis.Default(nil)
+	spec.Rewards = []genesis.Reward{{Height: 1, Val: 10}}
+	return &testChain{spec: spec, difficulty: 1, blocks: []*pb.Block{{Index: 0}}, user: user}
+}
+
+func (c *testChain) Spec() *genesis.Spec         { return c.spec }
+func (c *testChain) GetDifficulty() int32        { return c.difficulty }
+func (c *testChain) GetBlocks() []*pb.Block      { return c.blocks }
+func (c *testChain) GetUsr() *pb.User            { return &pb.User{Addr: c.user} }
+func (c *testChain) GetProposals() []*pb.Vote    { return c.proposals }
+func (c *testChain) GetEvidence() []*pb.Evidence { return c.evidence }
+
+func (c *testChain) Stakes(height int32) (map[string]float64, error) {
+	return c.stakes, nil
+}
+
+func (c *testChain) next() *pb.Block {
+	return &pb.Block{Index: int32(len(c.blocks))}
+}
+
+// credits is a State recording the credits
+type credits map[string]float64
+
+func (c credits) Credit(addr string, val float64) {
+	c[addr] += val
+}
+
+// Slash records the slashing as the stake account set to 0
+func (c credits) Slash(addr string) float64 {
+	c[genesis.StakeKey(addr)] = 0
+	return 0
+}
+
+// makeBlock runs the steps of the chain, and adds the block
+func makeBlock(t *testing.T, e Engine, c *testChain) (*pb.Block, credits) {
+	block := c.next()
+	st := credits{}
+	assert.Nil(t, e.Prepare(c, block))
+	assert.Nil(t, e.Finalize(c, block, st))
+	block.Hash = "block" + strconv.Itoa(int(block.Index))
+	assert.Nil(t, e.Seal(context.Background(), c, block))
+	assert.Nil(t, e.VerifySeal(c, block))
+	c.blocks = append(c.blocks, block)
+	return block, st
+}
+
+func TestNew(t *testing.T) {
+	spec := genesis.Default(nil)
+	e, err := New(spec, nil)
+	assert.Nil(t, err)
+	assert.IsType(t, &PoW{}, e)
+
+	spec.Consensus.Engine = genesis.EngineDev
+	e, err = New(spec, nil)
+	assert.Nil(t, err)
+	assert.IsType(t, &Dev{}, e)
+
+	spec.Consensus = genesis.Consensus{Engine: genesis.EnginePoA, Signers: []string{"a"}}
+	e, err = New(spec, nil)
+	assert.Nil(t, err)
+	assert.IsType(t, &PoA{}, e)
+
+	spec.Consensus.Engine = "unknown"
+	_, err = New(spec, nil)
+	assert.NotNil(t, err)
+}
+
+func TestDev(t *testing.T) {
+	c := newTestChain("me")
+	_, st := makeBlock(t, NewDev(), c)
+	assert.Equal(t, credits{"me": 10}, st)
+
+	// the rewards follow the schedule
+	c.spec.Rewards = nil
+	_, st = makeBlock(t, NewDev(), c)
+	assert.Equal(t, credits{}, st)
+
+	// a block is sealed a block time after its parent
+	blockTime := time.Duration(c.spec.BlockTime) * time.Second
+	parent := c.blocks[len(c.blocks)-1]
+	parent.Timestamp = time.Now().UnixNano()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.NotNil(t, NewDev().Seal(ctx, c, c.next()))
+
+	// the records of the block were stamped when it was made, so the seal leaves its timestamp alone
+	parent.Timestamp = time.Now().Add(20*time.Millisecond - blockTime).UnixNano()
+	block := c.next()
+	block.Timestamp = time.Now().UnixNano()
+	made := block.Timestamp
+	start := time.Now()
+	assert.Nil(t, NewDev().Seal(context.Background(), c, block))
+	assert.True(t, time.Now().UnixNano() >= parent.Timestamp+blockTime.Nanoseconds())
+	assert.True(t, time.Since(start) >= 10*time.Millisecond)
+	assert.Equal(t, made, block.Timestamp)
+}
@@ -0,0 +1,168 @@
+package consensus
+
+// Proof of authority
+// The validators, known by their public keys, take turns to make the blocks: the validator of a block is the one at
+// the height modulo the number of validators, in the order of their keys. The signer signs the block, and votes in it
+// on the validators with the proposals of the node. A validator is added or removed as soon as a majority of the
+// validators voted for it, from the next block on. The initial validators are the signers of the genesis spec.
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"keys"
+	"proto"
+	"sort"
+)
+
+type PoA struct {
+	key ed25519.PrivateKey // of the local validator, nil if the node only verifies
+}
+
+func NewPoA(key ed25519.PrivateKey) *PoA {
+	return &PoA{key: key}
+}
+
+// Validators returns the validators of the block at the height, in turn order
+func (e *PoA) Validators(chain Chain, height int32) ([]string, error) {
+	s, err := e.snapshot(chain, height)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.list(), nil
+}
+
+// Prepare names the local validator as signer of the block if it's its turn, and adds its votes
+func (e *PoA) Prepare(chain Chain, block *pb.Block) error {
+	if e.key == nil {
+		return fmt.Errorf("No validator key to sign blocks")
+	}
+
+	s, err := e.snapshot(chain, block.Index)
+	if err != nil {
+		return err
+	}
+
+	signer := keys.Public(e.key)
+	if inTurn := s.inTurn(block.Index); signer != inTurn {
+		return fmt.Errorf("Block %d is for the validator %s, not %s", block.Index, inTurn, signer)
+	}
+
+	block.Signer = signer
+	block.Votes = nil
+	for _, v := range chain.GetProposals() {
+		// proposals already in effect are moot
+		if v.Add != s.validators[v.Validator] {
+			block.Votes = append(block.Votes, &pb.Vote{Validator: v.Validator, Add: v.Add})
+		}
+	}
+	return nil
+}
+
+// Finalize pays the block reward to the signer
+func (e *PoA) Finalize(chain Chain, block *pb.Block, state State) error {
+	payReward(chain, block, block.Signer, state)
+	return nil
+}
+
+// Seal signs the block
+func (e *PoA) Seal(ctx context.Context, chain Chain, block *pb.Block) error {
+	if e.key == nil {
+		return fmt.Errorf("No validator key to sign blocks")
+	}
+
+	return sign(e.key, block)
+}
+
+// VerifySeal checks that the block is signed by the validator in turn
+func (e *PoA) VerifySeal(chain Chain, block *pb.Block) error {
+	s, err := e.snapshot(chain, block.Index)
+	if err != nil {
+		return err
+	}
+	if inTurn := s.inTurn(block.Index); block.Signer != inTurn {
+		return fmt.Errorf("Block %d is signed by %q, not the validator in turn %s", block.Index, block.Signer, inTurn)
+	}
+
+	if err := verifySignature(block); err != nil {
+		return err
+	}
+
+	for _, v := range block.Votes {
+		if _, err := keys.ParsePublic(v.Validator); err != nil {
+			return fmt.Errorf("Block %d votes on an invalid validator: %v", block.Index, err)
+		}
+	}
+	return nil
+}
+
+// snapshot is the state of the validators at a height
+type snapshot struct {
+	validators map[string]bool
+	tally      map[string]map[string]bool // voters for changing the validator status of a key
+}
+
+// snapshot replays the votes of the blocks before the height
+func (e *PoA) snapshot(chain Chain, height int32) (*snapshot, error) {
+	blocks := chain.GetBlocks()
+	if height < 1 || int(height) > len(blocks) {
+		return nil, fmt.Errorf("No parent of block %d in a chain of %d blocks", height, len(blocks))
+	}
+
+	s := &snapshot{validators: make(map[string]bool), tally: make(map[string]map[string]bool)}
+	for _, v := range chain.Spec().Consensus.Signers {
+		s.validators[v] = true
+	}
+	for _, block := range blocks[1:height] {
+		s.apply(block)
+	}
+	return s, nil
+}
+
+func (s *snapshot) apply(block *pb.Block) {
+	for _, v := range block.Votes {
+		if v.Add == s.validators[v.Validator] {
+			continue
+		}
+
+		voters, ok := s.tally[v.Validator]
+		if !ok {
+			voters = make(map[string]bool)
+			s.tally[v.Validator] = voters
+		}
+		voters[block.Signer] = true
+
+		// only the votes of the current validators count
+		n := 0
+		for voter := range voters {
+			if s.validators[voter] {
+				n++
+			}
+		}
+		if n <= len(s.validators)/2 {
+			continue
+		}
+
+		if v.Add {
+			s.validators[v.Validator] = true
+		} else if len(s.validators) > 1 {
+			delete(s.validators, v.Validator)
+		}
+		delete(s.tally, v.Validator)
+	}
+}
+
+// list returns the validators in turn order
+func (s *snapshot) list() []string {
+	list := make([]string, 0, len(s.validators))
+	for v := range s.validators {
+		list = append(list, v)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func (s *snapshot) inTurn(height int32) string {
+	list := s.list()
+	return list[int(height)%len(list)]
+}
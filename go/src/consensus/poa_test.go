@@ -0,0 +1,143 @@
+package consensus
+
+import (
+	"context"
+	"crypto/ed25519"
+	"genesis"
+	"keys"
+	"proto"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestValidators returns n validator keys in turn order, and a chain with them as PoA signers
+func newTestValidators(t *testing.T, n int) ([]ed25519.PrivateKey, *testChain) {
+	var privs []ed25519.PrivateKey
+	for i := 0; i < n; i++ {
+		priv, err := keys.Generate()
+		assert.Nil(t, err)
+		privs = append(privs, priv)
+	}
+	sort.Slice(privs, func(i, j int) bool { return keys.Public(privs[i]) < keys.Public(privs[j]) })
+
+	c := newTestChain("")
+	c.spec.Consensus.Engine = genesis.EnginePoA
+	for _, priv := range privs {
+		c.spec.Consensus.Signers = append(c.spec.Consensus.Signers, keys.Public(priv))
+	}
+	return privs, c
+}
+
+// turn returns the key of the validator in turn for the next block
+func turn(t *testing.T, privs []ed25519.PrivateKey, c *testChain) ed25519.PrivateKey {
+	validators, err := NewPoA(nil).Validators(c, int32(len(c.blocks)))
+	assert.Nil(t, err)
+	inTurn := validators[len(c.blocks)%len(validators)]
+	for _, priv := range privs {
+		if keys.Public(priv) == inTurn {
+			return priv
+		}
+	}
+	t.Fatalf("No key of the validator %s", inTurn)
+	return nil
+}
+
+func TestPoA(t *testing.T) {
+	privs, c := newTestValidators(t, 2)
+
+	// the validators take turns by height
+	block, st := makeBlock(t, NewPoA(privs[1]), c)
+	assert.Equal(t, keys.Public(privs[1]), block.Signer)
+	assert.Equal(t, credits{keys.Public(privs[1]): 10}, st)
+	assert.NotNil(t, NewPoA(privs[1]).Prepare(c, c.next()))
+	block, _ = makeBlock(t, NewPoA(privs[0]), c)
+	assert.Equal(t, keys.Public(privs[0]), block.Signer)
+
+	// only the validators make blocks
+	outsider, _ := keys.Generate()
+	assert.NotNil(t, NewPoA(outsider).Prepare(c, c.next()))
+	assert.NotNil(t, NewPoA(nil).Prepare(c, c.next()))
+}
+
+func TestPoAVerifySeal(t *testing.T) {
+	privs, c := newTestValidators(t, 2)
+	e := NewPoA(privs[1])
+	block := c.next()
+	assert.Nil(t, e.Prepare(c, block))
+	assert.Nil(t, e.Seal(context.Background(), c, block))
+	assert.Nil(t, e.VerifySeal(c, block))
+
+	// the signature covers the header
+	tampered := *block
+	tampered.Timestamp++
+	assert.NotNil(t, e.VerifySeal(c, &tampered))
+	tampered = *block
+	tampered.Votes = []*pb.Vote{{Validator: keys.Public(privs[0])}}
+	assert.NotNil(t, e.VerifySeal(c, &tampered))
+
+	// by the validator in turn
+	tampered = *block
+	tampered.Signer = keys.Public(privs[0])
+	payload, _ := SealPayload(&tampered)
+	tampered.Signature = keys.Sign(privs[0], payload)
+	assert.NotNil(t, e.VerifySeal(c, &tampered))
+
+	tampered = *block
+	tampered.Signature = nil
+	assert.NotNil(t, e.VerifySeal(c, &tampered))
+}
+
+func TestPoAVotes(t *testing.T) {
+	privs, c := newTestValidators(t, 3)
+	candidate, _ := keys.Generate()
+	pub := keys.Public(candidate)
+	c.proposals = []*pb.Vote{{Validator: pub, Add: true}}
+
+	// one vote of three isn't a majority
+	block, _ := makeBlock(t, NewPoA(turn(t, privs, c)), c)
+	assert.Equal(t, 1, len(block.Votes))
+	assert.Equal(t, pub, block.Votes[0].Validator)
+	assert.True(t, block.Votes[0].Add)
+	validators, _ := NewPoA(nil).Validators(c, int32(len(c.blocks)))
+	assert.Equal(t, 3, len(validators))
+
+	// two are, from the next block on
+	makeBlock(t, NewPoA(turn(t, privs, c)), c)
+	validators, _ = NewPoA(nil).Validators(c, int32(len(c.blocks)))
+	assert.Equal(t, 4, len(validators))
+	assert.Contains(t, validators, pub)
+	validators, _ = NewPoA(nil).Validators(c, int32(len(c.blocks)-1))
+	assert.Equal(t, 3, len(validators))
+
+	// the proposal is moot once in effect
+	privs = append(privs, candidate)
+	block, _ = makeBlock(t, NewPoA(turn(t, privs, c)), c)
+	assert.Empty(t, block.Votes)
+
+	// removing takes a majority of the new set, three of four
+	c.proposals = []*pb.Vote{{Validator: pub}}
+	signers := map[string]bool{}
+	for len(signers) < 3 {
+		block, _ = makeBlock(t, NewPoA(turn(t, privs, c)), c)
+		signers[block.Signer] = true
+		validators, _ = NewPoA(nil).Validators(c, int32(len(c.blocks)))
+		if len(signers) < 3 {
+			assert.Equal(t, 4, len(validators))
+		}
+	}
+	assert.Equal(t, 3, len(validators))
+	assert.NotContains(t, validators, pub)
+}
+
+func TestPoALastValidator(t *testing.T) {
+	privs, c := newTestValidators(t, 1)
+	c.proposals = []*pb.Vote{{Validator: keys.Public(privs[0])}}
+	makeBlock(t, NewPoA(privs[0]), c)
+	makeBlock(t, NewPoA(privs[0]), c)
+
+	validators, err := NewPoA(nil).Validators(c, int32(len(c.blocks)))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{keys.Public(privs[0])}, validators)
+}
@@ -0,0 +1,182 @@
+package consensus
+
+// Proof of stake
+// The validators are the accounts bonding stake in the balances, see genesis.StakeKey. The leader of every slot, i.e.
+// height, is drawn among them with a chance proportional to their stake, from the hash of the parent block, so every
+// node draws the same. The leader signs the block, which slashes the validators with evidence of double signing,
+// burning their whole stake.
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"keys"
+	"proto"
+	"sort"
+	"strconv"
+)
+
+type PoS struct {
+	key ed25519.PrivateKey // of the local validator, nil if the node only verifies
+}
+
+func NewPoS(key ed25519.PrivateKey) *PoS {
+	return &PoS{key: key}
+}
+
+// Validators returns the validators bonding stake before the block at the height, by public key
+func (e *PoS) Validators(chain Chain, height int32) ([]string, error) {
+	stakes, err := chain.Stakes(height)
+	if err != nil {
+		return nil, err
+	}
+
+	return validators(stakes), nil
+}
+
+func validators(stakes map[string]float64) []string {
+	list := make([]string, 0, len(stakes))
+	for v, stake := range stakes {
+		if stake > 0 {
+			list = append(list, v)
+		}
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Leader returns the validator of the block at the height
+func (e *PoS) Leader(chain Chain, height int32) (string, error) {
+	blocks := chain.GetBlocks()
+	if height < 1 || int(height) > len(blocks) {
+		return "", fmt.Errorf("No parent of block %d in a chain of %d blocks", height, len(blocks))
+	}
+
+	stakes, err := chain.Stakes(height)
+	if err != nil {
+		return "", err
+	}
+
+	list := validators(stakes)
+	if len(list) == 0 {
+		return "", fmt.Errorf("No validator bonds stake at height %d", height)
+	}
+
+	total := 0.0
+	for _, v := range list {
+		total += stakes[v]
+	}
+
+	// a point in [0, total) drawn from the parent and the slot, on the validators laid end to end
+	seed := sha256.Sum256([]byte(blocks[height-1].Hash + strconv.Itoa(int(height))))
+	point := float64(binary.BigEndian.Uint64(seed[:])>>11) / (1 << 53) * total
+	for _, v := range list {
+		if point -= stakes[v]; point < 0 {
+			return v, nil
+		}
+	}
+	return list[len(list)-1], nil
+}
+
+// Prepare names the local validator as signer of the block if it's the leader, and adds the pending evidence
+func (e *PoS) Prepare(chain Chain, block *pb.Block) error {
+	if e.key == nil {
+		return fmt.Errorf("No validator key to sign blocks")
+	}
+
+	leader, err := e.Leader(chain, block.Index)
+	if err != nil {
+		return err
+	}
+
+	signer := keys.Public(e.key)
+	if signer != leader {
+		return fmt.Errorf("Block %d is for the validator %s, not %s", block.Index, leader, signer)
+	}
+
+	block.Signer = signer
+	block.Evidence = nil
+	for _, ev := range chain.GetEvidence() {
+		if e.checkEvidence(chain, block, ev) == nil {
+			block.Evidence = append(block.Evidence, ev)
+		}
+	}
+	return nil
+}
+
+// Finalize pays the block reward to the signer, and slashes the double signers
+func (e *PoS) Finalize(chain Chain, block *pb.Block, state State) error {
+	payReward(chain, block, block.Signer, state)
+	for _, ev := range block.Evidence {
+		state.Slash(ev.A.Header.Signer)
+	}
+	return nil
+}
+
+// Seal signs the block
+func (e *PoS) Seal(ctx context.Context, chain Chain, block *pb.Block) error {
+	if e.key == nil {
+		return fmt.Errorf("No validator key to sign blocks")
+	}
+
+	return sign(e.key, block)
+}
+
+// VerifySeal checks that the block is signed by the leader, with valid evidence
+func (e *PoS) VerifySeal(chain Chain, block *pb.Block) error {
+	leader, err := e.Leader(chain, block.Index)
+	if err != nil {
+		return err
+	}
+	if block.Signer != leader {
+		return fmt.Errorf("Block %d is signed by %q, not the leader %s", block.Index, block.Signer, leader)
+	}
+	if err := verifySignature(block); err != nil {
+		return err
+	}
+
+	for _, ev := range block.Evidence {
+		if err := e.checkEvidence(chain, block, ev); err != nil {
+			return fmt.Errorf("Block %d: %v", block.Index, err)
+		}
+	}
+	return nil
+}
+
+// checkEvidence checks that the evidence can be slashed by the block: valid, of the chain and of a validator at
+// the height, and slashed neither before nor earlier in the block
+func (e *PoS) checkEvidence(chain Chain, block *pb.Block, ev *pb.Evidence) error {
+	if err := CheckEvidence(ev); err != nil {
+		return err
+	}
+	a := ev.A.Header
+	if a.ChainId != block.ChainId || a.Index >= block.Index {
+		return fmt.Errorf("Evidence of block %d isn't of a past block of the chain", a.Index)
+	}
+
+	stakes, err := chain.Stakes(a.Index)
+	if err != nil {
+		return err
+	}
+	if stakes[a.Signer] <= 0 {
+		return fmt.Errorf("Evidence against %s, who wasn't a validator at height %d", a.Signer, a.Index)
+	}
+
+	var slashed []*pb.Evidence
+	for _, b := range chain.GetBlocks()[:block.Index] {
+		slashed = append(slashed, b.Evidence...)
+	}
+	for _, prev := range block.Evidence {
+		if prev == ev {
+			break
+		}
+		slashed = append(slashed, prev)
+	}
+	for _, prev := range slashed {
+		if sameEvidence(prev, ev) {
+			return fmt.Errorf("Double signing of %s at height %d is already slashed", a.Signer, a.Index)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package consensus
+
+import (
+	"context"
+	"crypto/ed25519"
+	"genesis"
+	"keys"
+	"proto"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestStakers returns a chain with validators bonding the stakes, and their keys by public key
+func newTestStakers(t *testing.T, stakes ...float64) (map[string]ed25519.PrivateKey, *testChain) {
+	c := newTestChain("")
+	c.spec.Consensus.Engine = genesis.EnginePoS
+	c.stakes = make(map[string]float64)
+	privs := make(map[string]ed25519.PrivateKey)
+	for _, stake := range stakes {
+		priv, err := keys.Generate()
+		assert.Nil(t, err)
+		privs[keys.Public(priv)] = priv
+		c.stakes[keys.Public(priv)] = stake
+	}
+	return privs, c
+}
+
+// makeLeaderBlock makes the next block with the key of the leader
+func makeLeaderBlock(t *testing.T, privs map[string]ed25519.PrivateKey, c *testChain) (*pb.Block, credits) {
+	leader, err := NewPoS(nil).Leader(c, int32(len(c.blocks)))
+	assert.Nil(t, err)
+	return makeBlock(t, NewPoS(privs[leader]), c)
+}
+
+func TestPoSLeader(t *testing.T) {
+	privs, c := newTestStakers(t, 1, 3)
+	var small, big string
+	for pub := range privs {
+		if c.stakes[pub] == 1 {
+			small = pub
+		} else {
+			big = pub
+		}
+	}
+
+	// the leaders are drawn by stake, the same by every node
+	led := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		c.blocks[0].Hash = strconv.Itoa(i)
+		leader, err := NewPoS(nil).Leader(c, 1)
+		assert.Nil(t, err)
+		again, _ := NewPoS(nil).Leader(c, 1)
+		assert.Equal(t, leader, again)
+		led[leader]++
+	}
+	assert.InDelta(t, 250, led[small], 60)
+	assert.InDelta(t, 750, led[big], 60)
+
+	// unbonded validators don't lead
+	c.stakes[big] = 0
+	validators, _ := NewPoS(nil).Validators(c, 1)
+	assert.Equal(t, []string{small}, validators)
+	leader, _ := NewPoS(nil).Leader(c, 1)
+	assert.Equal(t, small, leader)
+
+	c.stakes[small] = 0
+	_, err := NewPoS(nil).Leader(c, 1)
+	assert.NotNil(t, err)
+	_, err = NewPoS(nil).Leader(c, 2)
+	assert.NotNil(t, err)
+}
+
+func TestPoS(t *testing.T) {
+	privs, c := newTestStakers(t, 1, 1, 1)
+	for i := 0; i < 5; i++ {
+		block, st := makeLeaderBlock(t, privs, c)
+		assert.Equal(t, credits{block.Signer: 10}, st)
+	}
+
+	// only the leader makes the block
+	leader, _ := NewPoS(nil).Leader(c, int32(len(c.blocks)))
+	for pub, priv := range privs {
+		if pub != leader {
+			assert.NotNil(t, NewPoS(priv).Prepare(c, c.next()))
+
+			block := c.next()
+			block.Signer = pub
+			assert.Nil(t, sign(priv, block))
+			assert.NotNil(t, NewPoS(nil).VerifySeal(c, block))
+		}
+	}
+	assert.NotNil(t, NewPoS(nil).Prepare(c, c.next()))
+
+	block := c.next()
+	assert.Nil(t, NewPoS(privs[leader]).Prepare(c, block))
+	assert.Nil(t, NewPoS(privs[leader]).Seal(context.Background(), c, block))
+	block.Proof++
+	assert.NotNil(t, NewPoS(nil).VerifySeal(c, block))
+}
+
+func TestPoSSlashing(t *testing.T) {
+	privs, c := newTestStakers(t, 1, 1)
+	first, _ := makeLeaderBlock(t, privs, c)
+
+	// the signer of the first block signs another one at the same height
+	other := &pb.Block{Index: 1, PrevHash: "other", Signer: first.Signer}
+	assert.Nil(t, sign(privs[first.Signer], other))
+	ev := NewEvidence(SignedHeader(first), SignedHeader(other))
+	assert.Nil(t, CheckEvidence(ev))
+	assert.Equal(t, ev, NewEvidence(SignedHeader(other), SignedHeader(first)))
+	c.evidence = []*pb.Evidence{ev, NewEvidence(SignedHeader(other), SignedHeader(first))}
+
+	// the next block slashes it once
+	block, st := makeLeaderBlock(t, privs, c)
+	assert.Equal(t, 1, len(block.Evidence))
+	assert.Equal(t, 0.0, st[genesis.StakeKey(first.Signer)])
+	assert.Contains(t, st, genesis.StakeKey(first.Signer))
+
+	block, _ = makeLeaderBlock(t, privs, c)
+	assert.Empty(t, block.Evidence)
+	c.blocks = c.blocks[:len(c.blocks)-1]
+	block.Evidence = []*pb.Evidence{ev}
+	assert.Nil(t, sign(privs[block.Signer], block))
+	assert.NotNil(t, NewPoS(nil).VerifySeal(c, block))
+}
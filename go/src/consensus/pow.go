@@ -0,0 +1,180 @@
+package consensus
+
+// Proof of work
+// The proof of a block is a number whose hash with the work of the block starts with difficulty '0's. The work is the
+// hash of the compact header without the proof, so a proof found for a block is worth nothing for another one, and
+// anyone given the header can search for it, e.g. an external miner. Workers search in parallel, each taking the next range
+// of numbers in turn, so the proof found is the smallest, the same as a single worker would find.
+import (
+	"context"
+	"fmt"
+	"math"
+	"proto"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+	"utils"
+)
+
+// proofRange is the count of numbers a worker tries at once, between checks for a proof found or a cancellation
+const proofRange = 1024
+
+type PoW struct {
+	Workers int // goroutines searching for the proof, one per CPU if 0
+
+	mu     sync.Mutex
+	hashes int64 // tried by the current or last seal
+	start  time.Time
+	end    time.Time // zero while sealing
+}
+
+func NewPoW() *PoW {
+	return &PoW{}
+}
+
+// Prepare sets the difficulty of the chain and the local user as the coinbase in the block, which its header commits to
+func (e *PoW) Prepare(chain Chain, block *pb.Block) error {
+	block.Difficulty = chain.GetDifficulty()
+	block.Coinbase = chain.GetUsr().GetAddr()
+	return nil
+}
+
+// Finalize pays the block reward to the coinbase of the block, the miner
+func (e *PoW) Finalize(chain Chain, block *pb.Block, state State) error {
+	payReward(chain, block, block.Coinbase, state)
+	return nil
+}
+
+// Seal searches for the proof of the block, until it's found or the context is done
+func (e *PoW) Seal(ctx context.Context, chain Chain, block *pb.Block) error {
+	if _, err := parent(chain, block); err != nil {
+		return err
+	}
+	work, err := Work(utils.BlockHeader(block))
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	atomic.StoreInt64(&e.hashes, 0)
+	e.start, e.end = time.Now(), time.Time{}
+	e.mu.Unlock()
+	proof, err := Search(ctx, work, int(block.Difficulty), e.Workers, &e.hashes)
+	e.mu.Lock()
+	e.end = time.Now()
+	e.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	block.Proof = proof
+	return nil
+}
+
+// HashRate returns the hashes per second of the current seal, else of the last one
+func (e *PoW) HashRate() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	end := e.end
+	if end.IsZero() {
+		end = time.Now()
+	}
+	if e.start.IsZero() || !end.After(e.start) {
+		return 0
+	}
+
+	return float64(atomic.LoadInt64(&e.hashes)) / end.Sub(e.start).Seconds()
+}
+
+func (e *PoW) VerifySeal(chain Chain, block *pb.Block) error {
+	if _, err := parent(chain, block); err != nil {
+		return err
+	}
+	if block.Difficulty != chain.GetDifficulty() {
+		return fmt.Errorf("Block %d has the difficulty %d, expected %d", block.Index, block.Difficulty,
+			chain.GetDifficulty())
+	}
+	work, err := Work(utils.BlockHeader(block))
+	if err != nil {
+		return err
+	}
+	if !IsValidProof(work, block.Proof, int(block.Difficulty)) {
+		return fmt.Errorf("Block %d has an invalid proof of work", block.Index)
+	}
+
+	return nil
+}
+
+// Work returns the hash the proof of a block is searched for, the hash of its header without the nonce
+func Work(header *pb.BlockHeader) (string, error) {
+	unsealed := *header
+	unsealed.Nonce = 0
+	return utils.Hash(&unsealed)
+}
+
+// Search returns the smallest proof of the work with the workers, one per CPU if 0, counting the hashes tried into
+// hashes if not nil. The workers take the ranges in order, and stop once the next range starts after a proof found,
+// so every range before the proof is searched.
+func Search(ctx context.Context, work string, difficulty, workers int, hashes *int64) (int64, error) {
+	if hashes == nil {
+		hashes = new(int64)
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	var next int64 = 0 // start of the next range
+	var found int64 = math.MaxInt64
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				start := atomic.AddInt64(&next, proofRange) - proofRange
+				if start > atomic.LoadInt64(&found) {
+					return
+				}
+
+				proof := start
+				for proof < start+proofRange && !IsValidProof(work, proof, difficulty) {
+					proof++
+				}
+				if proof == start+proofRange {
+					atomic.AddInt64(hashes, proofRange)
+					continue
+				}
+
+				atomic.AddInt64(hashes, proof-start+1)
+				for f := atomic.LoadInt64(&found); proof < f; f = atomic.LoadInt64(&found) {
+					if atomic.CompareAndSwapInt64(&found, f, proof) {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return found, nil
+}
+
+// IsValidProof tells if the hash of the work and the proof starts with difficulty '0's, the same on every node
+func IsValidProof(work string, proof int64, difficulty int) bool {
+	guess := fmt.Sprintf("%s%d", work, proof)
+	hash := utils.HashBytes([]byte(guess))
+	if difficulty > len(hash) {
+		return false
+	}
+	for i := 0; i < difficulty; i++ {
+		if hash[i] != '0' {
+			return false
+		}
+	}
+
+	return true
+}
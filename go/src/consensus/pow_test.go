@@ -0,0 +1,109 @@
+package consensus
+
+import (
+	"context"
+	"proto"
+	"testing"
+	"time"
+	"utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoW(t *testing.T) {
+	c := newTestChain("miner")
+	c.difficulty = 2
+	e := NewPoW()
+	block, st := makeBlock(t, e, c)
+	assert.Equal(t, int32(2), block.Difficulty)
+	work, err := Work(utils.BlockHeader(block))
+	assert.Nil(t, err)
+	assert.True(t, IsValidProof(work, block.Proof, 2))
+	assert.Equal(t, credits{"miner": 10}, st)
+
+	// the proof is only valid for the header it was found for
+	block, _ = makeBlock(t, e, c)
+	assert.Nil(t, e.VerifySeal(c, block))
+	tampered := *block
+	tampered.Timestamp++
+	for IsValidProof(workOf(&tampered), tampered.Proof, 2) {
+		tampered.Timestamp++
+	}
+	assert.NotNil(t, e.VerifySeal(c, &tampered))
+	tampered = *block
+	tampered.Difficulty = 0
+	assert.NotNil(t, e.VerifySeal(c, &tampered))
+	block.Proof++
+	for IsValidProof(workOf(block), block.Proof, 2) {
+		block.Proof++
+	}
+	assert.NotNil(t, e.VerifySeal(c, block))
+
+	// no parent
+	assert.NotNil(t, e.Seal(context.Background(), c, &pb.Block{Index: int32(len(c.blocks) + 1)}))
+	assert.NotNil(t, e.VerifySeal(c, c.blocks[0]))
+}
+
+// workOf returns the work of the block
+func workOf(block *pb.Block) string {
+	work, _ := Work(utils.BlockHeader(block))
+	return work
+}
+
+func TestWork(t *testing.T) {
+	// the work is the header without the nonce
+	header := &pb.BlockHeader{Index: 1, PrevHash: "prev", TxRoot: "txs", StateRoot: "state", Timestamp: 7}
+	work, err := Work(header)
+	assert.Nil(t, err)
+	header.Nonce = 42
+	same, _ := Work(header)
+	assert.Equal(t, work, same)
+	assert.Equal(t, int64(42), header.Nonce)
+	header.TxRoot = "other"
+	other, _ := Work(header)
+	assert.NotEqual(t, work, other)
+}
+
+func TestIsValidProof(t *testing.T) {
+	// deterministic, so that any node can verify it
+	proof, err := Search(context.Background(), "work", 2, 1, nil)
+	assert.Nil(t, err)
+	for i := 0; i < 10; i++ {
+		assert.True(t, IsValidProof("work", proof, 2))
+	}
+	assert.True(t, IsValidProof("work", 7, 0))
+	assert.False(t, IsValidProof("work", 7, 100))
+}
+
+func TestSearch(t *testing.T) {
+	// the workers find the smallest proof, as a single one does
+	var hashes int64
+	proof, err := Search(context.Background(), "work", 3, 4, &hashes)
+	assert.Nil(t, err)
+	for p := int64(0); p < proof; p++ {
+		assert.False(t, IsValidProof("work", p, 3))
+	}
+	assert.True(t, hashes > proof)
+	single, err := Search(context.Background(), "work", 3, 1, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, single, proof)
+
+	e := &PoW{Workers: 4}
+	c := newTestChain("miner")
+	c.difficulty = 2
+	makeBlock(t, e, c)
+	assert.True(t, e.HashRate() > 0)
+}
+
+func TestPoWCancel(t *testing.T) {
+	c := newTestChain("miner")
+	c.difficulty = 64 // never found
+	e := NewPoW()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	block := c.next()
+	assert.Nil(t, e.Prepare(c, block))
+	assert.Equal(t, context.DeadlineExceeded, e.Seal(ctx, c, block))
+	assert.Equal(t, int64(0), block.Proof)
+	assert.True(t, e.HashRate() > 0)
+}
@@ -0,0 +1,87 @@
+package consensus
+
+// Signed blocks, of the engines where validators make the blocks, and evidence of double signing
+// A validator must sign at most one block per height, two conflicting signed headers are the evidence that it
+// didn't, which the pos engine slashes.
+import (
+	"crypto/ed25519"
+	"fmt"
+	"keys"
+	"proto"
+	"utils"
+)
+
+// SealPayload returns the bytes of the block signed by its signer: the encoding of its compact header, which covers
+// the transactions and balances by their roots
+func SealPayload(block *pb.Block) ([]byte, error) {
+	return utils.Encode(utils.BlockHeader(block))
+}
+
+// SignedHeader returns the header of the block with its signature
+func SignedHeader(block *pb.Block) *pb.SignedHeader {
+	return &pb.SignedHeader{Header: utils.BlockHeader(block), Signature: block.Signature}
+}
+
+func sign(key ed25519.PrivateKey, block *pb.Block) error {
+	payload, err := SealPayload(block)
+	if err != nil {
+		return err
+	}
+
+	block.Signature = keys.Sign(key, payload)
+	return nil
+}
+
+func verifySignature(block *pb.Block) error {
+	return verifyHeader(SignedHeader(block))
+}
+
+// verifyHeader checks that the header is signed by its signer
+func verifyHeader(h *pb.SignedHeader) error {
+	if h.Header == nil {
+		return fmt.Errorf("Signed header lacks its header")
+	}
+
+	payload, err := utils.Encode(h.Header)
+	if err != nil {
+		return err
+	}
+	if !keys.Verify(h.Header.Signer, payload, h.Signature) {
+		return fmt.Errorf("Block %d has an invalid signature", h.Header.Index)
+	}
+
+	return nil
+}
+
+// NewEvidence returns the evidence of the two blocks, the same whatever their order
+func NewEvidence(a, b *pb.SignedHeader) *pb.Evidence {
+	if utils.HashHeader(a.Header) > utils.HashHeader(b.Header) {
+		a, b = b, a
+	}
+
+	return &pb.Evidence{A: a, B: b}
+}
+
+// CheckEvidence checks that the evidence holds two different blocks at the same height, signed by the same validator
+func CheckEvidence(ev *pb.Evidence) error {
+	a, b := ev.GetA().GetHeader(), ev.GetB().GetHeader()
+	if a == nil || b == nil {
+		return fmt.Errorf("Evidence lacks a block")
+	}
+	if a.Index != b.Index || a.Signer != b.Signer || a.ChainId != b.ChainId {
+		return fmt.Errorf("Evidence blocks aren't of the same signer, height and chain")
+	}
+	if utils.HashHeader(a) >= utils.HashHeader(b) {
+		return fmt.Errorf("Evidence blocks are the same or out of order")
+	}
+	if err := verifyHeader(ev.A); err != nil {
+		return err
+	}
+
+	return verifyHeader(ev.B)
+}
+
+// sameEvidence tells if the evidence is of the same double signing, i.e. of the same signer at the same height
+func sameEvidence(x, y *pb.Evidence) bool {
+	return x.A.Header.Signer == y.A.Header.Signer && x.A.Header.Index == y.A.Header.Index
+}
@@ -0,0 +1,35 @@
+package consensus
+
+import (
+	"crypto/ed25519"
+	"keys"
+	"proto"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckEvidence(t *testing.T) {
+	priv, _ := keys.Generate()
+	other, _ := keys.Generate()
+	signed := func(priv ed25519.PrivateKey, index int32, prev string) *pb.SignedHeader {
+		block := &pb.Block{Index: index, PrevHash: prev, Signer: keys.Public(priv)}
+		assert.Nil(t, sign(priv, block))
+		return SignedHeader(block)
+	}
+
+	assert.Nil(t, CheckEvidence(NewEvidence(signed(priv, 1, "a"), signed(priv, 1, "b"))))
+	assert.NotNil(t, CheckEvidence(&pb.Evidence{A: signed(priv, 1, "a")}))
+	assert.NotNil(t, CheckEvidence(NewEvidence(signed(priv, 1, "a"), signed(priv, 1, "a"))))
+	assert.NotNil(t, CheckEvidence(NewEvidence(signed(priv, 1, "a"), signed(priv, 2, "b"))))
+	assert.NotNil(t, CheckEvidence(NewEvidence(signed(priv, 1, "a"), signed(other, 1, "b"))))
+
+	forged := signed(priv, 1, "b")
+	forged.Signature = keys.Sign(other, []byte("b"))
+	assert.NotNil(t, CheckEvidence(NewEvidence(signed(priv, 1, "a"), forged)))
+
+	// the signature covers the whole header
+	forged = signed(priv, 1, "b")
+	forged.Header.Timestamp++
+	assert.NotNil(t, CheckEvidence(NewEvidence(signed(priv, 1, "a"), forged)))
+}
@@ -0,0 +1,84 @@
+package main
+
+// extminer is a sample external miner: it gets the work of the next block from a node, searches for the proof of the
+// work, and submits it, over the HTTP API of the node. The search is dropped as soon as the node has a new block.
+//   extminer [-node addr] [-workers n] [-blocks n] [-refresh duration]
+import (
+	"api"
+	"consensus"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+var errStale = errors.New("Work is stale")
+
+func main() {
+	node := flag.String("node", "localhost:8080", "address of the node")
+	workers := flag.Int("workers", 0, "goroutines searching for the proof, one per CPU if 0")
+	blocks := flag.Int("blocks", 0, "blocks to mine before exiting, no limit if 0")
+	refresh := flag.Duration("refresh", 2*time.Second, "how often to check the node for a new block")
+	flag.Parse()
+
+	c := api.NewClient(*node)
+	for mined := 0; *blocks == 0 || mined < *blocks; {
+		block, err := mine(c, *workers, *refresh)
+		if err == errStale {
+			continue
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "extminer:", err)
+			time.Sleep(*refresh)
+			continue
+		}
+
+		mined++
+		fmt.Printf("mined block %d %s with %d txs\n", block.Index, block.Hash, len(block.Txs))
+	}
+}
+
+// mine gets the work of the next block, checks that it's the work of its header, and submits its proof once found
+func mine(c *api.Client, workers int, refresh time.Duration) (*api.BlockInfo, error) {
+	work, err := c.GetWork()
+	if err != nil {
+		return nil, err
+	}
+	if w, err := consensus.Work(work.Header); err != nil || w != work.Work {
+		return nil, fmt.Errorf("Work %s isn't the one of its header", work.Work)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watch(ctx, cancel, c, work.Header.PrevHash, refresh)
+
+	var hashes int64
+	start := time.Now()
+	proof, err := consensus.Search(ctx, work.Work, int(work.Difficulty), workers, &hashes)
+	if err != nil {
+		return nil, errStale
+	}
+
+	fmt.Fprintf(os.Stderr, "found the proof of block %d after %d hashes, %.0f H/s\n", work.Header.Index, hashes,
+		float64(hashes)/time.Since(start).Seconds())
+	return c.SubmitWork(work.Work, proof)
+}
+
+// watch cancels the search once the last block of the node isn't the parent of the work anymore
+func watch(ctx context.Context, cancel context.CancelFunc, c *api.Client, parent string, refresh time.Duration) {
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if info, err := c.ChainInfo(); err == nil && info.LastHash != parent {
+				cancel()
+				return
+			}
+		}
+	}
+}
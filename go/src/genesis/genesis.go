@@ -0,0 +1,259 @@
+package genesis
+
+// Genesis spec, the parameters of a chain and its initial allocations, loaded from JSON
+// The hash of the canonical encoding of the spec identifies the network: it is the hash of the genesis block, so every
+// block links back to it, and two nodes agree on the network iff they agree on the hash.
+import (
+	"bytes"
+	"config"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"keys"
+	"merkle"
+	"proto"
+	"sort"
+	"strings"
+	"utils"
+)
+
+// DefaultChainID is the chain ID of a spec derived from a config, for development
+const DefaultChainID = "hawaii-dev"
+
+// Consensus engines, see consensus.Engine
+const (
+	EnginePoW = "pow" // proof of work, the default
+	EnginePoA = "poa" // proof of authority of the signers
+	EnginePoS = "pos" // proof of stake of the validators bonding stake
+	EngineBFT = "bft" // blocks committed by a byzantine fault tolerant vote of the signers
+	EngineDev = "dev" // blocks sealed without proof by anyone, one per block time, for development
+)
+
+// StakePrefix prefixes the accounts of the balances tree holding the stakes bonded by the validators of EnginePoS
+const StakePrefix = "stake:"
+
+type Spec struct {
+	ChainID      string          `json:"chainId"`
+	Timestamp    int64           `json:"timestamp"`              // of the genesis block, in unix nanoseconds
+	Difficulty   int32           `json:"difficulty"`             // initial difficulty of the proof of work
+	BlockTime    int64           `json:"blockTime"`              // target time between blocks, in seconds, kept by EngineDev
+	Rewards      []Reward        `json:"rewards,omitempty"`      // block reward schedule, by increasing height
+	MaxBlockSize int             `json:"maxBlockSize,omitempty"` // max encoded size of the txs of a block, 0 for no limit
+	StateTree    string          `json:"stateTree,omitempty"`    // kind of the balances tree, see merkle.StateTree
+	Alloc        config.Accounts `json:"alloc"`                  // initial balances
+	Consensus    Consensus       `json:"consensus"`
+}
+
+type Consensus struct {
+	Engine  string          `json:"engine,omitempty"`  // EnginePoW if empty
+	Signers []string        `json:"signers,omitempty"` // hex public keys of the validators of EnginePoA and EngineBFT
+	Stakes  config.Accounts `json:"stakes,omitempty"`  // initial stakes of the validators of EnginePoS, by public key
+}
+
+// StakeKey returns the account of the stake bonded by the address
+func StakeKey(addr string) string {
+	return StakePrefix + addr
+}
+
+// Reward is a stage of the reward schedule: the miner of every block from the height on is paid Val
+type Reward struct {
+	Height int32   `json:"height"`
+	Val    float64 `json:"val"`
+}
+
+// Default returns the spec of a development chain with the allocations, as created from a config without genesis
+func Default(alloc config.Accounts) *Spec {
+	return &Spec{
+		ChainID:    DefaultChainID,
+		Difficulty: 1,
+		BlockTime:  10,
+		Alloc:      alloc,
+	}
+}
+
+// Load reads and validates the spec in the JSON file at path
+func Load(path string) (*Spec, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("Genesis %s: %v", path, err)
+	}
+	return s, nil
+}
+
+// Parse decodes and validates a spec, rejecting unknown fields so that a typo can't silently change the network
+func Parse(data []byte) (*Spec, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	s := &Spec{}
+	if err := dec.Decode(s); err != nil {
+		return nil, err
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Spec) Validate() error {
+	if len(s.ChainID) == 0 {
+		return fmt.Errorf("No chain ID")
+	}
+	if s.Timestamp < 0 {
+		return fmt.Errorf("Negative timestamp %d", s.Timestamp)
+	}
+	if s.Difficulty < 0 {
+		return fmt.Errorf("Negative difficulty %d", s.Difficulty)
+	}
+	if s.BlockTime <= 0 {
+		return fmt.Errorf("Block time must be positive, got %d", s.BlockTime)
+	}
+	if s.MaxBlockSize < 0 {
+		return fmt.Errorf("Negative max block size %d", s.MaxBlockSize)
+	}
+	for i, r := range s.Rewards {
+		if r.Height < 1 {
+			return fmt.Errorf("Reward %d starts at height %d, before the first block", i, r.Height)
+		}
+		if i > 0 && r.Height <= s.Rewards[i-1].Height {
+			return fmt.Errorf("Reward %d starts at height %d, not after the previous one", i, r.Height)
+		}
+		if r.Val < 0 {
+			return fmt.Errorf("Reward %d is negative", i)
+		}
+	}
+	if _, err := merkle.NewStateTree(s.StateTree); err != nil {
+		return err
+	}
+	if err := s.Consensus.Validate(); err != nil {
+		return err
+	}
+	for _, acc := range s.Alloc {
+		if strings.HasPrefix(acc.Address, StakePrefix) {
+			return fmt.Errorf("Initial account %s is a stake, see the stakes of the consensus", acc.Address)
+		}
+	}
+
+	return s.Alloc.Validate()
+}
+
+func (c *Consensus) Validate() error {
+	switch c.Engine {
+	case "", EnginePoW, EngineDev, EnginePoA, EnginePoS, EngineBFT:
+	default:
+		return fmt.Errorf("Unknown consensus engine %q", c.Engine)
+	}
+	if (c.Engine == EnginePoA || c.Engine == EngineBFT) != (len(c.Signers) > 0) {
+		return fmt.Errorf("Signers are for the %s and %s engines, and required by them", EnginePoA, EngineBFT)
+	}
+	if (c.Engine == EnginePoS) != (len(c.Stakes) > 0) {
+		return fmt.Errorf("Stakes are for the %s engine, and required by it", EnginePoS)
+	}
+
+	for _, stake := range c.Stakes {
+		if _, err := keys.ParsePublic(stake.Address); err != nil {
+			return fmt.Errorf("Stake: %v", err)
+		}
+		if stake.Val <= 0 {
+			return fmt.Errorf("Stake of %s isn't positive", stake.Address)
+		}
+	}
+	if err := c.Stakes.Validate(); err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	for _, signer := range c.Signers {
+		if _, err := keys.ParsePublic(signer); err != nil {
+			return fmt.Errorf("Signer: %v", err)
+		}
+		if seen[signer] {
+			return fmt.Errorf("Signer %s is listed twice", signer)
+		}
+		seen[signer] = true
+	}
+	return nil
+}
+
+// RewardAt returns the reward of the block at the height
+func (s *Spec) RewardAt(height int32) float64 {
+	reward := 0.0
+	for _, r := range s.Rewards {
+		if r.Height > height {
+			break
+		}
+		reward = r.Val
+	}
+	return reward
+}
+
+// Encode returns the canonical JSON of the spec, with the allocations sorted by address and the defaults explicit
+func (s *Spec) Encode() ([]byte, error) {
+	c := *s
+	c.Alloc = sortAccounts(s.Alloc)
+	c.Consensus.Stakes = sortAccounts(s.Consensus.Stakes)
+	if len(c.Consensus.Engine) == 0 {
+		c.Consensus.Engine = EnginePoW
+	}
+	return json.Marshal(&c)
+}
+
+func sortAccounts(accs config.Accounts) config.Accounts {
+	sorted := append(config.Accounts(nil), accs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Address < sorted[j].Address })
+	return sorted
+}
+
+// Hash returns the hash of the canonical encoding, which identifies the network
+func (s *Spec) Hash() (string, error) {
+	data, err := s.Encode()
+	if err != nil {
+		return "", err
+	}
+
+	return utils.HashBytes(data), nil
+}
+
+// Write writes the spec to w as indented JSON
+func (s *Spec) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// Block builds the genesis block, holding the allocations, with the hash of the spec
+func (s *Spec) Block() (*pb.Block, error) {
+	hash, err := s.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := merkle.NewStateTree(s.StateTree)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, acc := range sortAccounts(s.Alloc) {
+		status.Upsert(acc.Address, fmt.Sprintf("%f", acc.Val))
+	}
+	for _, stake := range sortAccounts(s.Consensus.Stakes) {
+		status.Upsert(StakeKey(stake.Address), fmt.Sprintf("%f", stake.Val))
+	}
+	balances, err := status.ToTree()
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.Block{
+		Index:     0,
+		Hash:      hash,
+		Timestamp: s.Timestamp,
+		Balances:  balances,
+	}, nil
+}
@@ -0,0 +1,149 @@
+package genesis
+
+import (
+	"bytes"
+	"config"
+	"io/ioutil"
+	"merkle"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// public keys of the PoA signers
+const (
+	keyA = "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a"
+	keyB = "3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c"
+)
+
+func testSpec() *Spec {
+	s := Default(config.Accounts{{Address: "b", Val: 2}, {Address: "a", Val: 1}})
+	s.ChainID = "test"
+	s.Timestamp = 1546300800000000000
+	s.Rewards = []Reward{{Height: 1, Val: 50}, {Height: 10, Val: 25}}
+	return s
+}
+
+func TestHash(t *testing.T) {
+	s := testSpec()
+	hash, err := s.Hash()
+	assert.Nil(t, err)
+
+	// the allocations are hashed in canonical order
+	s.Alloc[0], s.Alloc[1] = s.Alloc[1], s.Alloc[0]
+	same, err := s.Hash()
+	assert.Nil(t, err)
+	assert.Equal(t, hash, same)
+
+	// so is the default engine
+	s.Consensus.Engine = EnginePoW
+	same, err = s.Hash()
+	assert.Nil(t, err)
+	assert.Equal(t, hash, same)
+
+	s.ChainID = "other"
+	other, err := s.Hash()
+	assert.Nil(t, err)
+	assert.NotEqual(t, hash, other)
+}
+
+func TestWriteAndLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "genesis")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "genesis.json")
+
+	s := testSpec()
+	var b bytes.Buffer
+	assert.Nil(t, s.Write(&b))
+	assert.Nil(t, ioutil.WriteFile(path, b.Bytes(), 0644))
+
+	loaded, err := Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, s, loaded)
+	h1, _ := s.Hash()
+	h2, _ := loaded.Hash()
+	assert.Equal(t, h1, h2)
+}
+
+func TestParseErrors(t *testing.T) {
+	for name, data := range map[string]string{
+		"malformed":  `{"chainId": `,
+		"unknown":    `{"chainId": "x", "blockTime": 10, "difficuly": 3}`,
+		"no chain":   `{"blockTime": 10}`,
+		"block time": `{"chainId": "x"}`,
+		"difficulty": `{"chainId": "x", "blockTime": 10, "difficulty": -1}`,
+		"max size":   `{"chainId": "x", "blockTime": 10, "maxBlockSize": -1}`,
+		"reward 0":   `{"chainId": "x", "blockTime": 10, "rewards": [{"height": 0, "val": 1}]}`,
+		"rewards":    `{"chainId": "x", "blockTime": 10, "rewards": [{"height": 5, "val": 1}, {"height": 5, "val": 2}]}`,
+		"negative":   `{"chainId": "x", "blockTime": 10, "rewards": [{"height": 1, "val": -1}]}`,
+		"state":      `{"chainId": "x", "blockTime": 10, "stateTree": "unknown"}`,
+		"alloc":      `{"chainId": "x", "blockTime": 10, "alloc": [{"address": "a", "val": 1}, {"address": "a"}]}`,
+		"engine":     `{"chainId": "x", "blockTime": 10, "consensus": {"engine": "pow2"}}`,
+		"no signers": `{"chainId": "x", "blockTime": 10, "consensus": {"engine": "poa"}}`,
+		"signers":    `{"chainId": "x", "blockTime": 10, "consensus": {"engine": "poa", "signers": ["` + keyA + `", "` + keyA + `"]}}`,
+		"signer key": `{"chainId": "x", "blockTime": 10, "consensus": {"engine": "poa", "signers": ["a"]}}`,
+		"pow signer": `{"chainId": "x", "blockTime": 10, "consensus": {"signers": ["` + keyA + `"]}}`,
+		"bft":        `{"chainId": "x", "blockTime": 10, "consensus": {"engine": "bft"}}`,
+		"no stakes":  `{"chainId": "x", "blockTime": 10, "consensus": {"engine": "pos"}}`,
+		"stake key":  `{"chainId": "x", "blockTime": 10, "consensus": {"engine": "pos", "stakes": [{"address": "a", "val": 1}]}}`,
+		"stake 0":    `{"chainId": "x", "blockTime": 10, "consensus": {"engine": "pos", "stakes": [{"address": "` + keyA + `"}]}}`,
+		"poa stakes": `{"chainId": "x", "blockTime": 10, "consensus": {"engine": "poa", "signers": ["` + keyA + `"], "stakes": [{"address": "` + keyA + `", "val": 1}]}}`,
+		"stake acc":  `{"chainId": "x", "blockTime": 10, "alloc": [{"address": "stake:a", "val": 1}]}`,
+	} {
+		_, err := Parse([]byte(data))
+		assert.NotNil(t, err, name)
+	}
+
+	_, err := Parse([]byte(`{"chainId": "x", "blockTime": 10}`))
+	assert.Nil(t, err)
+	_, err = Parse([]byte(`{"chainId": "x", "blockTime": 10, "consensus": {"engine": "poa", "signers": ["` + keyA + `", "` + keyB + `"]}}`))
+	assert.Nil(t, err)
+	_, err = Parse([]byte(`{"chainId": "x", "blockTime": 10, "consensus": {"engine": "bft", "signers": ["` + keyA + `"]}}`))
+	assert.Nil(t, err)
+	_, err = Parse([]byte(`{"chainId": "x", "blockTime": 10, "consensus": {"engine": "pos", "stakes": [{"address": "` + keyA + `", "val": 5}]}}`))
+	assert.Nil(t, err)
+}
+
+func TestRewardAt(t *testing.T) {
+	s := testSpec()
+	assert.Equal(t, 0.0, s.RewardAt(0))
+	assert.Equal(t, 50.0, s.RewardAt(1))
+	assert.Equal(t, 50.0, s.RewardAt(9))
+	assert.Equal(t, 25.0, s.RewardAt(10))
+	assert.Equal(t, 25.0, s.RewardAt(1000))
+	assert.Equal(t, 0.0, Default(nil).RewardAt(5))
+}
+
+func TestBlock(t *testing.T) {
+	s := testSpec()
+	s.StateTree = merkle.StateSparse
+	block, err := s.Block()
+	assert.Nil(t, err)
+	hash, _ := s.Hash()
+	assert.Equal(t, hash, block.Hash)
+	assert.Equal(t, s.Timestamp, block.Timestamp)
+	assert.Equal(t, merkle.StateSparse, block.Balances.Kind)
+
+	balances, err := merkle.LoadStateTree(block.Balances)
+	assert.Nil(t, err)
+	bal, ok := balances.GetFloat("b")
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, bal)
+
+	// the stakes are accounts of the balances
+	s.Consensus = Consensus{Engine: EnginePoS, Stakes: config.Accounts{{Address: keyB, Val: 3}, {Address: keyA, Val: 4}}}
+	block, err = s.Block()
+	assert.Nil(t, err)
+	balances, _ = merkle.LoadStateTree(block.Balances)
+	bal, ok = balances.GetFloat(StakeKey(keyA))
+	assert.True(t, ok)
+	assert.Equal(t, 4.0, bal)
+
+	// in any order
+	s.Consensus.Stakes[0], s.Consensus.Stakes[1] = s.Consensus.Stakes[1], s.Consensus.Stakes[0]
+	other, _ := s.Block()
+	assert.Equal(t, block.Hash, other.Hash)
+}
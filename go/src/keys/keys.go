@@ -0,0 +1,65 @@
+package keys
+
+// Ed25519 keys, e.g. of the validators, written as hex
+// A key file holds the hex of the 32 bytes seed of the private key, a public key is known by its hex.
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+func Generate() (ed25519.PrivateKey, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	return priv, err
+}
+
+// Public returns the hex of the public key of the private key
+func Public(priv ed25519.PrivateKey) string {
+	return hex.EncodeToString(priv.Public().(ed25519.PublicKey))
+}
+
+// ParsePublic decodes the hex of a public key
+func ParsePublic(s string) (ed25519.PublicKey, error) {
+	data, err := hex.DecodeString(s)
+	if err != nil || len(data) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("Invalid public key %q", s)
+	}
+
+	return ed25519.PublicKey(data), nil
+}
+
+// Load reads the private key in the file at path
+func Load(path string) (ed25519.PrivateKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	seed, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("Key file %s doesn't hold the hex of a %d bytes seed", path, ed25519.SeedSize)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// Save writes the private key to the file at path, readable by the owner only
+func Save(path string, priv ed25519.PrivateKey) error {
+	return ioutil.WriteFile(path, []byte(hex.EncodeToString(priv.Seed())+"\n"), 0600)
+}
+
+func Sign(priv ed25519.PrivateKey, msg []byte) []byte {
+	return ed25519.Sign(priv, msg)
+}
+
+// Verify checks the signature of the message by the hex public key
+func Verify(pub string, msg, sig []byte) bool {
+	key, err := ParsePublic(pub)
+	if err != nil {
+		return false
+	}
+
+	return ed25519.Verify(key, msg, sig)
+}
@@ -0,0 +1,44 @@
+package keys
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	priv, err := Generate()
+	assert.Nil(t, err)
+	pub := Public(priv)
+	assert.Equal(t, 64, len(pub))
+
+	sig := Sign(priv, []byte("block"))
+	assert.True(t, Verify(pub, []byte("block"), sig))
+	assert.False(t, Verify(pub, []byte("other"), sig))
+	assert.False(t, Verify("not hex", []byte("block"), sig))
+
+	other, _ := Generate()
+	assert.False(t, Verify(Public(other), []byte("block"), sig))
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keys")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "validator.key")
+
+	priv, _ := Generate()
+	assert.Nil(t, Save(path, priv))
+	loaded, err := Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, priv, loaded)
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte("abcd"), 0600))
+	_, err = Load(path)
+	assert.NotNil(t, err)
+	_, err = ParsePublic("abcd")
+	assert.NotNil(t, err)
+}
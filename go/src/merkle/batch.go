@@ -0,0 +1,222 @@
+package merkle
+
+// Batch writes for the patricia trie and the sparse merkle tree
+// A batch collects puts/deletes and applies them in one pass on Commit, so every touched node is rehashed once
+// instead of once per key. The trie is rolled back to its previous state if the commit fails.
+import (
+	"fmt"
+	"proto"
+	"utils"
+)
+
+type Batch struct {
+	w   batchWriter
+	ops []batchOp
+}
+
+// batchWriter is a tree that applies the ops of a batch atomically
+type batchWriter interface {
+	commitBatch(ops []batchOp) error
+}
+
+type batchOp struct {
+	key  string
+	path []byte
+	val  string // empty val is a delete
+}
+
+// batchGroup is the set of ops sharing the same child (or encoded path) of a node
+type batchGroup struct {
+	encoded bool
+	epKey   string
+	epHash  string
+	nibble  byte
+	ops     []batchOp
+}
+
+// NewBatch creates an empty write batch for the trie
+func (t *PatriciaTrie) NewBatch() *Batch {
+	return &Batch{w: t}
+}
+
+// Put queues an update or add of a kv pair
+func (b *Batch) Put(key, val string) {
+	b.ops = append(b.ops, batchOp{key: key, val: val})
+}
+
+// PutFloat queues an update or add of a kv pair, where v is a float
+func (b *Batch) PutFloat(key string, val float64) {
+	b.Put(key, fmt.Sprintf("%f", val))
+}
+
+// Delete queues the deletion of a key
+func (b *Batch) Delete(key string) {
+	b.ops = append(b.ops, batchOp{key: key})
+}
+
+// Len returns the number of queued ops
+func (b *Batch) Len() int {
+	return len(b.ops)
+}
+
+// Reset drops all the queued ops
+func (b *Batch) Reset() {
+	b.ops = nil
+}
+
+// Commit applies all the queued ops to the trie atomically.
+// Ops on the same key are applied in the order they were queued, so the last write wins.
+func (b *Batch) Commit() error {
+	if err := b.w.commitBatch(b.ops); err != nil {
+		return err
+	}
+
+	b.ops = nil
+	return nil
+}
+
+func (t *PatriciaTrie) commitBatch(in []batchOp) error {
+	t.Lock()
+	defer t.Unlock()
+	ops := make([]batchOp, 0, len(in))
+	for _, op := range in {
+		key, err := t.encodeKey(op.key)
+		if err != nil {
+			return err
+		}
+
+		val, err := t.encodeVal(op.val)
+		if err != nil {
+			return err
+		}
+
+		ops = append(ops, batchOp{path: utils.ToNibbles(key), val: val})
+	}
+
+	j := newJournal(t)
+	if _, err := t.applyBatch(t.Root, ops, true, j); err != nil {
+		j.rollback()
+		return err
+	}
+
+	t.publish()
+	return nil
+}
+
+// applyBatch applies the ops under a copy of n and rehashes it once all of its children are done
+func (t *PatriciaTrie) applyBatch(n *pb.Node, ops []batchOp, isRoot bool, j *journal) (string, error) {
+	n = copyNode(n)
+	var groups []*batchGroup
+	index := make(map[string]*batchGroup)
+	for _, op := range ops {
+		if len(op.path) == 0 {
+			n.Val = op.val
+			continue
+		}
+
+		// Note: same as upsertWithPath, no partial update of an encoded path is supported
+		epKey, epHash, path, isEncodedPath := getEncodedPath(n, op.path)
+		gk := "n" + string(op.path[:1])
+		if isEncodedPath {
+			gk = "e" + epKey
+		} else {
+			path = op.path[1:]
+		}
+
+		g, ok := index[gk]
+		if !ok {
+			g = &batchGroup{
+				encoded: isEncodedPath,
+				epKey:   epKey,
+				epHash:  epHash,
+				nibble:  op.path[0],
+			}
+			index[gk] = g
+			groups = append(groups, g)
+		}
+		g.ops = append(g.ops, batchOp{path: path, val: op.val})
+	}
+
+	for _, g := range groups {
+		var next *pb.Node
+		if g.encoded {
+			next = t.Ht[g.epHash]
+		} else if hasChild(n, g.nibble) {
+			next = t.Ht[n.Next[g.nibble]]
+		}
+
+		isNew := next == nil
+		if isNew {
+			next = &pb.Node{
+				EncodedPaths: make(map[string]string),
+			}
+		}
+
+		nextHash, err := t.applyBatch(next, g.ops, false, j)
+		if err != nil {
+			return "", err
+		}
+
+		// deleting a key that doesn't exist leaves the branch untouched
+		if isNew && len(nextHash) == 0 {
+			continue
+		}
+
+		if g.encoded {
+			updateEncodedPath(n, g.epKey, nextHash)
+		} else {
+			updateChild(n, int(g.nibble), nextHash)
+		}
+	}
+
+	return t.updateHash(n, isRoot, j)
+}
+
+// journal records the changes made to a trie so that they can be undone
+// Nodes are copied on write, so restoring the root, the hash table and the orphan count is enough.
+// A nil journal applies the changes without recording them.
+type journal struct {
+	t       *PatriciaTrie
+	root    *pb.Node
+	orphans int
+	ht      []htChange
+}
+
+type htChange struct {
+	key     string
+	prev    *pb.Node
+	existed bool
+}
+
+func newJournal(t *PatriciaTrie) *journal {
+	return &journal{
+		t:       t,
+		root:    t.Root,
+		orphans: t.orphans,
+	}
+}
+
+func (j *journal) set(t *PatriciaTrie, key string, n *pb.Node) {
+	if j != nil {
+		prev, existed := t.Ht[key]
+		j.ht = append(j.ht, htChange{key: key, prev: prev, existed: existed})
+	}
+
+	t.setNode(key, n)
+}
+
+// rollback undoes all the recorded changes in reverse order
+func (j *journal) rollback() {
+	for i := len(j.ht) - 1; i >= 0; i-- {
+		c := j.ht[i]
+		if c.existed {
+			j.t.setNode(c.key, c.prev)
+		} else {
+			j.t.deleteNode(c.key)
+		}
+	}
+
+	j.t.Root = j.root
+	j.t.orphans = j.orphans
+	j.ht = nil
+}
@@ -0,0 +1,130 @@
+package merkle
+
+import (
+	"testing"
+	"utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchCommit(t *testing.T) {
+	trie := NewPatriciaTrie()
+	b := trie.NewBatch()
+	b.Put("key1", "val1")
+	b.Put("key2", "val2")
+	b.Put("key3", "val2")
+	assert.Equal(t, 3, b.Len())
+	assert.Nil(t, b.Commit())
+	assert.Equal(t, 0, b.Len())
+
+	rst, _ := trie.Get("key1")
+	assert.Equal(t, "val1", rst)
+	rst, _ = trie.Get("key2")
+	assert.Equal(t, "val2", rst)
+	rst, _ = trie.Get("key3")
+	assert.Equal(t, "val2", rst)
+}
+
+func TestBatchLastWriteWins(t *testing.T) {
+	trie := NewPatriciaTrie()
+	b := trie.NewBatch()
+	b.Put("key1", "val1")
+	b.Put("key1", "val2")
+	b.Put("key2", "val2")
+	b.Delete("key2")
+	assert.Nil(t, b.Commit())
+
+	rst, _ := trie.Get("key1")
+	assert.Equal(t, "val2", rst)
+	_, ok := trie.Get("key2")
+	assert.False(t, ok)
+}
+
+func TestBatchOnExistingTrie(t *testing.T) {
+	trie := NewPatriciaTrie()
+	trie.Upsert("key1", "val1")
+	trie.Upsert("key2", "val2")
+	trie.compress()
+
+	b := trie.NewBatch()
+	b.Put("key1", "val3")
+	b.Delete("key2")
+	b.Delete("nokey")
+	b.Put("key4", "val4")
+	assert.Nil(t, b.Commit())
+
+	rst, _ := trie.Get("key1")
+	assert.Equal(t, "val3", rst)
+	_, ok := trie.Get("key2")
+	assert.False(t, ok)
+	rst, _ = trie.Get("key4")
+	assert.Equal(t, "val4", rst)
+}
+
+func TestBatchMatchesUpsert(t *testing.T) {
+	trie, batched := NewPatriciaTrie(), NewPatriciaTrie()
+	b := batched.NewBatch()
+	keys := []string{}
+	for i := 0; i < 500; i++ {
+		k, v := utils.RandStringBytesMaskImprSrc(32), utils.RandStringBytesMaskImprSrc(32)
+		trie.Upsert(k, v)
+		b.Put(k, v)
+		keys = append(keys, k)
+	}
+	assert.Nil(t, b.Commit())
+
+	for _, k := range keys {
+		expected, _ := trie.Get(k)
+		rst, ok := batched.Get(k)
+		assert.True(t, ok)
+		assert.Equal(t, expected, rst)
+	}
+}
+
+func TestBatchRollback(t *testing.T) {
+	trie := NewPatriciaTrie()
+	trie.Upsert("key1", "val1")
+	trie.Upsert("key2", "val2")
+	root, count, orphans := trie.Root.Hash, trie.Count(), trie.orphans
+
+	ops := []batchOp{}
+	for _, k := range []string{"key1", "key3"} {
+		key, _ := trie.encodeKey(k)
+		ops = append(ops, batchOp{path: utils.ToNibbles(key), val: "changed"})
+	}
+	j := newJournal(trie)
+	_, err := trie.applyBatch(trie.Root, ops, true, j)
+	assert.Nil(t, err)
+	assert.NotEqual(t, root, trie.Root.Hash)
+	assert.NotEqual(t, orphans, trie.orphans)
+
+	j.rollback()
+	assert.Equal(t, root, trie.Root.Hash)
+	assert.Equal(t, count, trie.Count())
+	assert.Equal(t, orphans, trie.orphans)
+	rst, _ := trie.Get("key1")
+	assert.Equal(t, "val1", rst)
+	_, ok := trie.Get("key3")
+	assert.False(t, ok)
+}
+
+func BenchmarkBatchCommit1000(b *testing.B) {
+	trie := NewPatriciaTrie()
+	trie.BatchSize = 1000
+	batchAll(trie, b)
+}
+
+func BenchmarkBatchCommit4000(b *testing.B) {
+	trie := NewPatriciaTrie()
+	trie.BatchSize = 4000
+	batchAll(trie, b)
+}
+
+func batchAll(trie *PatriciaTrie, b *testing.B) {
+	batch := trie.NewBatch()
+	for i := 0; i < b.N; i++ {
+		k, v := utils.RandStringBytesMaskImprSrc(32), utils.RandStringBytesMaskImprSrc(32)
+		batch.Put(k, v)
+	}
+	batch.Commit()
+}
@@ -0,0 +1,146 @@
+package merkle
+
+// Codecs for the keys and values stored in the trie
+// The codec of a trie is persisted by name in pb.Tree, so a deserialized trie decodes its own keys and values.
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/golang/snappy"
+)
+
+const (
+	CodecIdentity = "identity"
+	CodecHex      = "hex"
+	CodecZlib     = "zlib"
+	CodecSnappy   = "snappy"
+	CodecDict     = "dict"
+)
+
+// Codec encodes a key or a value before it is stored in the trie and decodes it back
+type Codec interface {
+	Name() string
+	Encode(s string) (string, error)
+	Decode(s string) (string, error)
+}
+
+var (
+	Identity Codec = identityCodec{}
+	Hex      Codec = hexCodec{}
+	Zlib     Codec = zlibCodec{}
+	Snappy   Codec = snappyCodec{}
+)
+
+// codecs are looked up by the tries concurrently, so registering a codec takes the write lock
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]Codec{
+		CodecIdentity: Identity,
+		CodecHex:      Hex,
+		CodecZlib:     Zlib,
+		CodecSnappy:   Snappy,
+	}
+)
+
+// RegisterCodec makes a codec available to tries by its name
+func RegisterCodec(c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[c.Name()] = c
+}
+
+// GetCodec returns the codec with the name. dict is only used by the dict codec.
+func GetCodec(name string, dict []byte) (Codec, error) {
+	if name == CodecDict {
+		return NewDictCodec(dict), nil
+	}
+
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	if c, ok := codecs[name]; ok {
+		return c, nil
+	}
+
+	return nil, fmt.Errorf("Unknown codec %s", name)
+}
+
+type identityCodec struct{}
+
+func (identityCodec) Name() string                    { return CodecIdentity }
+func (identityCodec) Encode(s string) (string, error) { return s, nil }
+func (identityCodec) Decode(s string) (string, error) { return s, nil }
+
+type hexCodec struct{}
+
+func (hexCodec) Name() string { return CodecHex }
+
+func (hexCodec) Encode(s string) (string, error) {
+	return hex.EncodeToString([]byte(s)), nil
+}
+
+func (hexCodec) Decode(s string) (string, error) {
+	bs, err := hex.DecodeString(s)
+	return string(bs), err
+}
+
+type zlibCodec struct{}
+
+func (zlibCodec) Name() string                    { return CodecZlib }
+func (zlibCodec) Encode(s string) (string, error) { return ZipString(s) }
+func (zlibCodec) Decode(s string) (string, error) { return UnzipString(s) }
+
+type snappyCodec struct{}
+
+func (snappyCodec) Name() string { return CodecSnappy }
+
+func (snappyCodec) Encode(s string) (string, error) {
+	return string(snappy.Encode(nil, []byte(s))), nil
+}
+
+func (snappyCodec) Decode(s string) (string, error) {
+	bs, err := snappy.Decode(nil, []byte(s))
+	return string(bs), err
+}
+
+// DictCodec is zlib with a preset dictionary of the substrings common to the keys or values,
+// e.g. the address prefix of accounts, so that short inputs shrink instead of growing
+type DictCodec struct {
+	Dict []byte
+}
+
+func NewDictCodec(dict []byte) *DictCodec {
+	return &DictCodec{Dict: dict}
+}
+
+func (c *DictCodec) Name() string { return CodecDict }
+
+func (c *DictCodec) Encode(s string) (string, error) {
+	var b bytes.Buffer
+	w, err := zlib.NewWriterLevelDict(&b, zlib.BestCompression, c.Dict)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = w.Write([]byte(s))
+	w.Close()
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func (c *DictCodec) Decode(s string) (string, error) {
+	r, err := zlib.NewReaderDict(bytes.NewReader([]byte(s)), c.Dict)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,127 @@
+package merkle
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodecsRoundTrip(t *testing.T) {
+	orig := "00000000000000000000000000000001"
+	for _, name := range []string{CodecIdentity, CodecHex, CodecZlib, CodecSnappy, CodecDict} {
+		c, err := GetCodec(name, []byte("0000000000000000"))
+		assert.Nil(t, err)
+		assert.Equal(t, name, c.Name())
+		enc, err := c.Encode(orig)
+		assert.Nil(t, err)
+		dec, err := c.Decode(enc)
+		assert.Nil(t, err)
+		assert.Equal(t, orig, dec, name)
+	}
+}
+
+func TestUnknownCodec(t *testing.T) {
+	_, err := GetCodec("nope", nil)
+	assert.NotNil(t, err)
+}
+
+type namedCodec struct {
+	identityCodec
+	name string
+}
+
+func (c namedCodec) Name() string { return c.name }
+
+func TestRegisterCodecConcurrently(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			RegisterCodec(namedCodec{name: "test"})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			GetCodec(CodecHex, nil)
+		}
+	}()
+	wg.Wait()
+
+	c, err := GetCodec("test", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "test", c.Name())
+}
+
+func TestDictCodecShrinksKeys(t *testing.T) {
+	key := "00000000000000000000000000000001"
+	zipped, _ := Zlib.Encode(key)
+	enc, _ := NewDictCodec([]byte(key)).Encode(key)
+	assert.True(t, len(enc) < len(zipped))
+}
+
+func TestTrieWithCodec(t *testing.T) {
+	for _, c := range []Codec{Identity, Hex, Zlib, Snappy, NewDictCodec([]byte("key"))} {
+		trie := NewPatriciaTrieWithCodec(c, c)
+		trie.Upsert("key1", "val1")
+		b := trie.NewBatch()
+		b.Put("key2", "val2")
+		b.Commit()
+		rst, _ := trie.Get("key1")
+		assert.Equal(t, "val1", rst, c.Name())
+		rst, _ = trie.Get("key2")
+		assert.Equal(t, "val2", rst, c.Name())
+	}
+}
+
+func TestDictPerCodec(t *testing.T) {
+	// the keys and values each have their own dictionary
+	keys, vals := NewDictCodec([]byte("key")), NewDictCodec([]byte("value"))
+	trie := NewPatriciaTrieWithCodec(keys, vals)
+	assert.Equal(t, []byte("key"), trie.Dict)
+	assert.Equal(t, []byte("value"), trie.ValDict)
+	trie.Upsert("key1", "value1")
+	bs, err := trie.Serialize()
+	assert.Nil(t, err)
+
+	loaded := NewPatriciaTrie()
+	assert.Nil(t, loaded.Deserialize(bs))
+	rst, _ := loaded.Get("key1")
+	assert.Equal(t, "value1", rst)
+	c, err := loaded.keyCodec()
+	assert.Nil(t, err)
+	assert.Equal(t, keys, c)
+	c, err = loaded.valCodec()
+	assert.Nil(t, err)
+	assert.Equal(t, vals, c)
+
+	// tries from before share the dictionary of the keys
+	loaded.ValDict = nil
+	c, _ = loaded.valCodec()
+	assert.Equal(t, keys, c)
+}
+
+func TestCodecPersistedInTree(t *testing.T) {
+	trie := NewPatriciaTrieWithCodec(Identity, Snappy)
+	trie.Upsert("key1", "val1")
+	bs, err := trie.Serialize()
+	assert.Nil(t, err)
+
+	trie2 := NewPatriciaTrie()
+	trie2.Deserialize(bs)
+	assert.Equal(t, CodecIdentity, trie2.KeyCodec)
+	assert.Equal(t, CodecSnappy, trie2.ValCodec)
+	rst, _ := trie2.Get("key1")
+	assert.Equal(t, "val1", rst)
+}
+
+func TestZippedTrieWithoutCodec(t *testing.T) {
+	trie := NewPatriciaTrie()
+	trie.Upsert("key1", "val1")
+	c, _ := trie.keyCodec()
+	assert.Equal(t, CodecZlib, c.Name())
+	rst, _ := trie.Get("key1")
+	assert.Equal(t, "val1", rst)
+}
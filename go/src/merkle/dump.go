@@ -0,0 +1,172 @@
+package merkle
+
+// Structured dumps of the patricia trie, as JSON for tooling and diffs and as Graphviz DOT for visualization
+// Children are listed by nibble then by encoded path, so the dumps of equal tries are equal.
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"proto"
+	"sort"
+	"strings"
+	"unicode/utf8"
+	"utils"
+)
+
+// DumpNode is a node of a dump. The key and value of the nodes holding one are decoded with the codecs of the trie,
+// values that aren't valid UTF-8 once decoded, or that start with 0x themselves, are rendered as 0x-prefixed hex.
+type DumpNode struct {
+	Hash     string     `json:"hash"`
+	Key      string     `json:"key,omitempty"`
+	Value    string     `json:"value,omitempty"`
+	Children []DumpEdge `json:"children,omitempty"`
+}
+
+// DumpEdge links a node to a child, labeled by the hex nibbles leading to it
+type DumpEdge struct {
+	Label   string    `json:"label"`
+	Encoded bool      `json:"encoded,omitempty"` // the edge is an encoded path
+	Node    *DumpNode `json:"node"`
+}
+
+// Dump returns the trie as a tree of DumpNode
+func (t *PatriciaTrie) Dump() (*DumpNode, error) {
+	t.RLock()
+	defer t.RUnlock()
+	kc, err := t.keyCodec()
+	if err != nil {
+		return nil, err
+	}
+
+	vc, err := t.valCodec()
+	if err != nil {
+		return nil, err
+	}
+
+	return t.dumpNode(t.Root, nil, kc, vc)
+}
+
+// DumpJSON writes the trie to w as indented JSON
+func (t *PatriciaTrie) DumpJSON(w io.Writer) error {
+	root, err := t.Dump()
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(root)
+}
+
+// DumpDOT writes the trie to w as a Graphviz digraph, the encoded paths as dashed edges
+func (t *PatriciaTrie) DumpDOT(w io.Writer) error {
+	root, err := t.Dump()
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph trie {\n\tnode [shape=box];\n")
+	ids := 0
+	var write func(n *DumpNode) string
+	write = func(n *DumpNode) string {
+		id := fmt.Sprintf("n%d", ids)
+		ids++
+		label := shortHash(n.Hash)
+		if len(n.Value) > 0 {
+			label += fmt.Sprintf("\nkey: %s\nvalue: %s", n.Key, n.Value)
+		}
+		fmt.Fprintf(&b, "\t%s [label=%q];\n", id, label)
+
+		for _, e := range n.Children {
+			child := write(e.Node)
+			style := ""
+			if e.Encoded {
+				style = ", style=dashed"
+			}
+			fmt.Fprintf(&b, "\t%s -> %s [label=%q%s];\n", id, child, e.Label, style)
+		}
+		return id
+	}
+	write(root)
+	b.WriteString("}\n")
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// dumpNode dumps n, found at the nibble path, and its descendants
+func (t *PatriciaTrie) dumpNode(n *pb.Node, path []byte, kc, vc Codec) (*DumpNode, error) {
+	d := &DumpNode{Hash: n.Hash}
+	if len(n.Val) > 0 {
+		key, err := kc.Decode(string(utils.FromNibbles(path)))
+		if err != nil {
+			return nil, err
+		}
+
+		val, err := vc.Decode(n.Val)
+		if err != nil {
+			return nil, err
+		}
+
+		d.Key, d.Value = printable(key), printable(val)
+	}
+
+	for i, nextHash := range n.Next {
+		if next, ok := t.Ht[nextHash]; ok && len(nextHash) > 0 {
+			child, err := t.dumpNode(next, append(path[:len(path):len(path)], byte(i)), kc, vc)
+			if err != nil {
+				return nil, err
+			}
+
+			d.Children = append(d.Children, DumpEdge{Label: nibblesToHex([]byte{byte(i)}), Node: child})
+		}
+	}
+
+	eps := make([]string, 0, len(n.EncodedPaths))
+	for ep := range n.EncodedPaths {
+		eps = append(eps, ep)
+	}
+	sort.Strings(eps)
+	for _, ep := range eps {
+		if next, ok := t.Ht[n.EncodedPaths[ep]]; ok {
+			child, err := t.dumpNode(next, append(path[:len(path):len(path)], ep...), kc, vc)
+			if err != nil {
+				return nil, err
+			}
+
+			d.Children = append(d.Children, DumpEdge{Label: nibblesToHex([]byte(ep)), Encoded: true, Node: child})
+		}
+	}
+
+	return d, nil
+}
+
+// nibblesToHex renders nibbles as hex digits, e.g. {6, 11} as "6b"
+func nibblesToHex(nibbles []byte) string {
+	const digits = "0123456789abcdef"
+	b := make([]byte, len(nibbles))
+	for i, nibble := range nibbles {
+		b[i] = digits[nibble&0xf]
+	}
+	return string(b)
+}
+
+// printable renders s as is, or as 0x-prefixed hex when it isn't text. Text starting with 0x is rendered as hex as
+// well, so that a rendered 0x prefix always means hex.
+func printable(s string) string {
+	if utf8.ValidString(s) && !strings.HasPrefix(s, "0x") {
+		return s
+	}
+
+	return "0x" + hex.EncodeToString([]byte(s))
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 8 {
+		return hash[:8]
+	}
+
+	return hash
+}
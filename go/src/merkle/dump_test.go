@@ -0,0 +1,93 @@
+package merkle
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func collectDump(n *DumpNode, kvs map[string]string) {
+	if len(n.Value) > 0 {
+		kvs[n.Key] = n.Value
+	}
+	for _, e := range n.Children {
+		collectDump(e.Node, kvs)
+	}
+}
+
+func TestDumpJSON(t *testing.T) {
+	trie := NewPatriciaTrie()
+	trie.Upsert("key1", "val1")
+	trie.Upsert("key2", "val2")
+	trie.Upsert("other", "\xff\x00")
+	trie.Upsert("text", "0xff00")
+
+	var buf bytes.Buffer
+	assert.Nil(t, trie.DumpJSON(&buf))
+	var root DumpNode
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &root))
+	assert.Equal(t, trie.Root.Hash, root.Hash)
+
+	// the zipped keys are dumped decoded
+	kvs := make(map[string]string)
+	collectDump(&root, kvs)
+	// a text value is told apart from the hex of a binary one
+	assert.Equal(t, map[string]string{"key1": "val1", "key2": "val2", "other": "0xff00", "text": "0x307866663030"},
+		kvs)
+}
+
+func TestDumpIsDeterministic(t *testing.T) {
+	a, b := NewPatriciaTrieWithCodec(Identity, Identity), NewPatriciaTrieWithCodec(Identity, Identity)
+	keys := []string{"ab", "ac", "b", "abc"}
+	for i := range keys {
+		a.Upsert(keys[i], "v"+keys[i])
+		b.Upsert(keys[len(keys)-1-i], "v"+keys[len(keys)-1-i])
+	}
+	a.compress()
+	b.compress()
+
+	var da, db bytes.Buffer
+	assert.Nil(t, a.DumpJSON(&da))
+	assert.Nil(t, b.DumpJSON(&db))
+	assert.Equal(t, da.String(), db.String())
+}
+
+func TestDumpEncodedPaths(t *testing.T) {
+	trie := NewPatriciaTrieWithCodec(Identity, Identity)
+	trie.Upsert("ab", "1")
+	trie.Upsert("ac", "2")
+	trie.compress()
+
+	root, err := trie.Dump()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(root.Children))
+	e := root.Children[0]
+	assert.True(t, e.Encoded)
+	// 'a' is 0x61 and the next nibble of 'b' and 'c' is 6
+	assert.Equal(t, "616", e.Label)
+	assert.Equal(t, 2, len(e.Node.Children))
+	assert.Equal(t, "2", e.Node.Children[0].Label)
+	assert.Equal(t, "ab", e.Node.Children[0].Node.Key)
+	assert.Equal(t, "3", e.Node.Children[1].Label)
+	assert.Equal(t, "2", e.Node.Children[1].Node.Value)
+}
+
+func TestDumpDOT(t *testing.T) {
+	trie := NewPatriciaTrieWithCodec(Identity, Identity)
+	trie.Upsert("ab", "1")
+	trie.Upsert("ac", "2")
+	trie.compress()
+
+	var buf bytes.Buffer
+	assert.Nil(t, trie.DumpDOT(&buf))
+	dot := buf.String()
+	assert.True(t, strings.HasPrefix(dot, "digraph trie {"))
+	assert.True(t, strings.HasSuffix(dot, "}\n"))
+	assert.Contains(t, dot, `\nkey: ab\nvalue: 1"`)
+	assert.Contains(t, dot, `n0 -> n1 [label="616", style=dashed];`)
+	assert.Contains(t, dot, `n1 -> n2 [label="2"];`)
+	assert.Equal(t, 4, strings.Count(dot, "[label="+`"`)-strings.Count(dot, "->"))
+}
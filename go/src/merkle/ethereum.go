@@ -0,0 +1,174 @@
+package merkle
+
+// Ethereum compatible root of the trie, for export only
+// EthCompatRoot computes the Yellow Paper root of the kv pairs of the trie, as geth would for the same pairs: nodes
+// are branch, extension or leaf, paths are hex-prefix encoded, nodes are RLP encoded and referenced by their
+// Keccak-256 hash, or inlined when their encoding is shorter than 32 bytes. It is recomputed from all the kv pairs on
+// every call and doesn't change how the trie hashes its own nodes, so RootHash, proofs and stored trees stay native.
+// The keys and values are hashed as stored, so the trie must use the identity codec.
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"utils"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// EmptyEthRoot is the root hash of an empty Ethereum trie, i.e. keccak256(rlp(""))
+var EmptyEthRoot = Keccak256([]byte{0x80})
+
+// RootHash returns the native root hash of the trie
+func (t *PatriciaTrie) RootHash() (string, error) {
+	t.RLock()
+	defer t.RUnlock()
+	return t.Root.Hash, nil
+}
+
+// EthCompatRoot computes the Ethereum root hash of the kv pairs of the trie, as a 0x-prefixed hex string
+func (t *PatriciaTrie) EthCompatRoot() (string, error) {
+	t.RLock()
+	defer t.RUnlock()
+	kc, err := t.keyCodec()
+	if err != nil {
+		return "", err
+	}
+
+	vc, err := t.valCodec()
+	if err != nil {
+		return "", err
+	}
+
+	if kc.Name() != CodecIdentity || vc.Name() != CodecIdentity {
+		return "", fmt.Errorf("Ethereum roots need identity codecs, got %s/%s", kc.Name(), vc.Name())
+	}
+
+	var kvs []ethKV
+	err = t.forEach(func(key, val string) error {
+		kvs = append(kvs, ethKV{path: utils.ToNibbles(key), val: []byte(val)})
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return "0x" + hex.EncodeToString(ethRootOf(kvs)), nil
+}
+
+type ethKV struct {
+	path []byte // key nibbles
+	val  []byte
+}
+
+// ethRootOf computes the Ethereum root hash of the kv pairs
+func ethRootOf(kvs []ethKV) []byte {
+	sort.Slice(kvs, func(i, j int) bool {
+		return bytes.Compare(kvs[i].path, kvs[j].path) < 0
+	})
+	return Keccak256(ethNode(kvs, 0))
+}
+
+// ethNode returns the RLP encoding of the node holding the sorted kvs, whose paths are equal up to depth
+func ethNode(kvs []ethKV, depth int) []byte {
+	switch {
+	case len(kvs) == 0:
+		return rlpBytes(nil)
+	case len(kvs) == 1:
+		return rlpList(rlpBytes(hexPrefix(kvs[0].path[depth:], true)), rlpBytes(kvs[0].val))
+	}
+
+	// since the kvs are sorted, the common prefix of all of them is the one of the first and the last
+	first, last := kvs[0].path, kvs[len(kvs)-1].path
+	prefix := 0
+	for depth+prefix < len(first) && depth+prefix < len(last) && first[depth+prefix] == last[depth+prefix] {
+		prefix++
+	}
+
+	if prefix > 0 {
+		return rlpList(rlpBytes(hexPrefix(first[depth:depth+prefix], false)), ethRef(ethNode(kvs, depth+prefix)))
+	}
+
+	items := make([][]byte, 17)
+	items[16] = rlpBytes(nil)
+	if len(first) == depth {
+		items[16] = rlpBytes(kvs[0].val)
+		kvs = kvs[1:]
+	}
+
+	for nibble := byte(0); nibble < 16; nibble++ {
+		i := 0
+		for i < len(kvs) && kvs[i].path[depth] == nibble {
+			i++
+		}
+
+		items[nibble] = ethRef(ethNode(kvs[:i], depth+1))
+		kvs = kvs[i:]
+	}
+
+	return rlpList(items...)
+}
+
+// ethRef returns how a parent refers to the encoded node: inline if shorter than a hash, by hash otherwise
+func ethRef(node []byte) []byte {
+	if len(node) < 32 {
+		return node
+	}
+
+	return rlpBytes(Keccak256(node))
+}
+
+// hexPrefix is the compact encoding of a nibble path, with a flag for odd lengths and leaf nodes
+func hexPrefix(nibbles []byte, leaf bool) []byte {
+	var flag byte
+	if leaf {
+		flag = 2
+	}
+
+	if len(nibbles)%2 == 1 {
+		return append([]byte{(flag+1)<<4 | nibbles[0]}, utils.FromNibbles(nibbles[1:])...)
+	}
+
+	return append([]byte{flag << 4}, utils.FromNibbles(nibbles)...)
+}
+
+func Keccak256(data []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// rlpBytes is the RLP encoding of a byte string
+func rlpBytes(b []byte) []byte {
+	if len(b) == 1 && b[0] < 0x80 {
+		return []byte{b[0]}
+	}
+
+	return append(rlpLength(len(b), 0x80), b...)
+}
+
+// rlpList is the RLP encoding of a list of already encoded items
+func rlpList(items ...[]byte) []byte {
+	size := 0
+	for _, item := range items {
+		size += len(item)
+	}
+
+	rst := rlpLength(size, 0xc0)
+	for _, item := range items {
+		rst = append(rst, item...)
+	}
+	return rst
+}
+
+func rlpLength(size int, offset byte) []byte {
+	if size < 56 {
+		return []byte{offset + byte(size)}
+	}
+
+	var be []byte
+	for n := size; n > 0; n >>= 8 {
+		be = append([]byte{byte(n)}, be...)
+	}
+	return append([]byte{offset + 55 + byte(len(be))}, be...)
+}
@@ -0,0 +1,96 @@
+package merkle
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Vectors from trieanyorder.json and trietest.json of github.com/ethereum/tests
+var ethTrieTests = []struct {
+	name string
+	in   [][2]string // an empty value deletes the key
+	root string
+}{
+	{"singleItem", [][2]string{{"A", strings.Repeat("a", 50)}},
+		"0xd23786fb4a010da3ce639d66d5e904a11dbc02746d1ce25029e53290cabf28ab"},
+	{"dogs", [][2]string{{"doe", "reindeer"}, {"dog", "puppy"}, {"dogglesworth", "cat"}},
+		"0x8aad789dff2f538bca5d8ea56e8abe10f4c7ba3a5dea95fea4cd6e7c3a1168d3"},
+	{"puppy", [][2]string{{"do", "verb"}, {"horse", "stallion"}, {"doge", "coin"}, {"dog", "puppy"}},
+		"0x5991bb8c6514148a29db676a14ac506cd2cd5775ace63c30a4fe457715e9ac84"},
+	{"foo", [][2]string{{"foo", "bar"}, {"food", "bass"}},
+		"0x17beaa1648bafa633cda809c90c04af50fc8aed3cb40d16efbddee6fdf63c4c3"},
+	{"smallValues", [][2]string{{"be", "e"}, {"dog", "puppy"}, {"bed", "d"}},
+		"0x3f67c7a47520f79faa29255d2d3c084a7a6df0453116ed7232ff10277a8be68b"},
+	{"testy", [][2]string{{"test", "test"}, {"te", "testy"}},
+		"0x8452568af70d8d140f58d941338542f645fcca50094b20f3c3d8c3df49337928"},
+	{"hex", [][2]string{{"0x0045", "0x0123456789"}, {"0x4500", "0x9876543210"}},
+		"0x285505fcabe84badc8aa310e2aae17eddc7d120aabec8a476902c8184b3a3503"},
+	{"emptyValues", [][2]string{{"do", "verb"}, {"ether", "wookiedoo"}, {"horse", "stallion"}, {"shaman", "horse"},
+		{"doge", "coin"}, {"ether", ""}, {"dog", "puppy"}, {"shaman", ""}},
+		"0x5991bb8c6514148a29db676a14ac506cd2cd5775ace63c30a4fe457715e9ac84"},
+	{"empty", nil,
+		"0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421"},
+}
+
+func ethTestString(s string) string {
+	if strings.HasPrefix(s, "0x") {
+		bs, _ := hex.DecodeString(s[2:])
+		return string(bs)
+	}
+
+	return s
+}
+
+func TestEthRootVectors(t *testing.T) {
+	for _, test := range ethTrieTests {
+		trie := NewPatriciaTrieWithCodec(Identity, Identity)
+		for _, kv := range test.in {
+			if len(kv[1]) == 0 {
+				assert.Nil(t, trie.Delete(ethTestString(kv[0])))
+			} else {
+				assert.Nil(t, trie.Upsert(ethTestString(kv[0]), ethTestString(kv[1])))
+			}
+		}
+
+		root, err := trie.EthCompatRoot()
+		assert.Nil(t, err)
+		assert.Equal(t, test.root, root, test.name)
+	}
+}
+
+func TestEthRootIgnoresCompression(t *testing.T) {
+	trie := NewPatriciaTrieWithCodec(Identity, Identity)
+	for _, kv := range ethTrieTests[1].in {
+		trie.Upsert(kv[0], kv[1])
+	}
+	trie.compress()
+	root, _ := trie.EthCompatRoot()
+	assert.Equal(t, ethTrieTests[1].root, root)
+
+	// the native hashing is left alone
+	native, _ := trie.RootHash()
+	assert.Equal(t, trie.Root.Hash, native)
+}
+
+func TestEthRootNeedsIdentityCodec(t *testing.T) {
+	trie := NewPatriciaTrie()
+	trie.Upsert("dog", "puppy")
+	_, err := trie.EthCompatRoot()
+	assert.NotNil(t, err)
+}
+
+func TestEmptyEthRoot(t *testing.T) {
+	assert.Equal(t, "56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421", hex.EncodeToString(EmptyEthRoot))
+}
+
+func TestRlp(t *testing.T) {
+	assert.Equal(t, []byte{0x80}, rlpBytes(nil))
+	assert.Equal(t, []byte{0x0f}, rlpBytes([]byte{0x0f}))
+	assert.Equal(t, []byte{0x83, 'd', 'o', 'g'}, rlpBytes([]byte("dog")))
+	assert.Equal(t, []byte{0xc8, 0x83, 'c', 'a', 't', 0x83, 'd', 'o', 'g'}, rlpList(rlpBytes([]byte("cat")), rlpBytes([]byte("dog"))))
+	long := rlpBytes([]byte("Lorem ipsum dolor sit amet, consectetur adipisicing elit"))
+	assert.Equal(t, []byte{0xb8, 0x38}, long[:2])
+}
@@ -3,12 +3,15 @@ package merkle
 // This is a Go thread-safe implementation of the merkle patricia trie
 // It features automatic batch compression of nodes into encoded paths to improve insert/update/delete efficiency
 // The design goal SLA is to support over 5k inserts/sec and 200k gets/sec
+// Nodes are copied on write, so Get reads the last published version without locking while a single writer,
+// holding the write lock, prepares the next one. See view.go.
 import (
 	"fmt"
 	"proto"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"utils"
 
 	"github.com/gogo/protobuf/proto"
@@ -17,6 +20,9 @@ import (
 type PatriciaTrie struct {
 	pb.Tree
 	sync.RWMutex
+	view    atomic.Value // *trieView read by Get, published on the first Get
+	nodes   *sync.Map    // index of Ht shared by the views, nil until the first view
+	orphans int          // nodes of Ht left unreachable by the updates since the last sweep
 }
 
 func NewPatriciaTrie() *PatriciaTrie {
@@ -32,10 +38,41 @@ func NewPatriciaTrie() *PatriciaTrie {
 	return t
 }
 
+// LoadPatriciaTrie wraps a stored tree, e.g. the one of a block, into a trie
+func LoadPatriciaTrie(tree *pb.Tree) *PatriciaTrie {
+	t := &PatriciaTrie{}
+	t.Tree = *tree
+	return t
+}
+
+// NewPatriciaTrieWithCodec creates a trie storing its keys and values with the given codecs
+func NewPatriciaTrieWithCodec(key, val Codec) *PatriciaTrie {
+	t := NewPatriciaTrie()
+	t.Zipped = false
+	t.KeyCodec = key.Name()
+	t.ValCodec = val.Name()
+	// each dict codec keeps its own dictionary
+	if dc, ok := key.(*DictCodec); ok {
+		t.Dict = dc.Dict
+	}
+	if dc, ok := val.(*DictCodec); ok {
+		t.ValDict = dc.Dict
+	}
+	return t
+}
+
+// Count returns the number of keys in the trie, as SparseMerkleTree.Count does
 func (t *PatriciaTrie) Count() int {
 	t.RLock()
 	defer t.RUnlock()
-	return len(t.Ht)
+	count := 0
+	t.walk(t.Root, nil, func(path []byte, n *pb.Node) error {
+		if len(n.Val) > 0 {
+			count++
+		}
+		return nil
+	})
+	return count
 }
 
 // Print will output the trie in dfs order to stdout for debugging
@@ -62,26 +99,27 @@ func (t *PatriciaTrie) Deserialize(bs []byte) error {
 	}
 
 	t.Tree = nodes
+	t.orphans = 0
+	t.resetView()
 	return nil
 }
 
 // Upsert will update or add a kv pair to the trie
 func (t *PatriciaTrie) Upsert(key, val string) error {
-	if t.Zipped {
-		k, err := ZipString(key)
-		if err != nil {
-			return err
-		}
-
-		key = k
-	}
-
 	t.Lock()
 	defer t.Unlock()
-	_, err := t.upsertWithPath(t.Root, utils.ToNibbles(key), val, true)
-	if int64(len(t.Ht))-t.LastRadixCompression > t.BatchSize {
-		t.compress()
+	key, err := t.encodeKey(key)
+	if err != nil {
+		return err
 	}
+
+	val, err = t.encodeVal(val)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.upsertWithPath(t.Root, utils.ToNibbles(key), val, true)
+	t.publish()
 	return err
 }
 
@@ -92,37 +130,71 @@ func (t *PatriciaTrie) UpsertFloat(key string, val float64) error {
 
 // Delete will delete a value and update/delete its corresponding branch
 func (t *PatriciaTrie) Delete(key string) error {
-	if t.Zipped {
-		k, err := ZipString(key)
-		if err != nil {
-			return err
-		}
-
-		key = k
-	}
-
 	t.Lock()
 	defer t.Unlock()
-	_, err := t.upsertWithPath(t.Root, utils.ToNibbles(key), "", true)
+	key, err := t.encodeKey(key)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.upsertWithPath(t.Root, utils.ToNibbles(key), "", true)
+	t.publish()
 	return err
 }
 
 // Get returns the value to the key. No duplicate is allowed.
 // rtype - string, error
 func (t *PatriciaTrie) Get(key string) (string, bool) {
-	if t.Zipped {
-		k, err := ZipString(key)
+	rst, err := t.Lookup(key)
+	return rst, err == nil
+}
+
+// Lookup returns the value to the key, ErrNotFound if the key is missing
+// It doesn't lock the trie, the value is the one of the last published version, read with the codecs of that version.
+func (t *PatriciaTrie) Lookup(key string) (string, error) {
+	for i := 0; i < maxViewRetries; i++ {
+		v := t.loadView()
+		if v.err != nil {
+			return "", v.err
+		}
+
+		encoded, err := v.keys.Encode(key)
 		if err != nil {
-			return "", false
+			return "", err
 		}
 
-		key = k
+		if rst, ok, err := getWithPath(v.root, utils.ToNibbles(encoded), v.lookup, nil); err == nil {
+			return decodeFound(rst, ok, v.vals)
+		}
 	}
 
+	// the nodes of the views read kept being swept, wait for the writer
 	t.RLock()
 	defer t.RUnlock()
-	rst, err := t.getWithPath(t.Root, utils.ToNibbles(key))
-	return rst, err == nil
+	encoded, err := t.encodeKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	rst, ok, err := getWithPath(t.Root, utils.ToNibbles(encoded), t.lookup, nil)
+	if err != nil {
+		return "", err
+	}
+
+	vc, err := t.valCodec()
+	if err != nil {
+		return "", err
+	}
+	return decodeFound(rst, ok, vc)
+}
+
+// decodeFound decodes the value found by a lookup, ErrNotFound if there was none
+func decodeFound(val string, ok bool, vc Codec) (string, error) {
+	if !ok {
+		return "", ErrNotFound
+	}
+
+	return vc.Decode(val)
 }
 
 // GetFloat returns the value to the key. No duplicate is allowed.
@@ -137,61 +209,205 @@ func (t *PatriciaTrie) GetFloat(key string) (float64, bool) {
 	return 0.0, false
 }
 
+// ForEach calls fn with every kv pair of the trie, in no particular order. It stops at the first error of fn.
+func (t *PatriciaTrie) ForEach(fn func(key, val string) error) error {
+	t.RLock()
+	defer t.RUnlock()
+	return t.forEach(fn)
+}
+
+// forEach is ForEach for a caller holding the lock
+func (t *PatriciaTrie) forEach(fn func(key, val string) error) error {
+	kc, err := t.keyCodec()
+	if err != nil {
+		return err
+	}
+
+	vc, err := t.valCodec()
+	if err != nil {
+		return err
+	}
+
+	return t.walk(t.Root, nil, func(path []byte, n *pb.Node) error {
+		if len(n.Val) == 0 {
+			return nil
+		}
+
+		key, err := kc.Decode(string(utils.FromNibbles(path)))
+		if err != nil {
+			return err
+		}
+
+		val, err := vc.Decode(n.Val)
+		if err != nil {
+			return err
+		}
+
+		return fn(key, val)
+	})
+}
+
+// walk visits n and its descendants in dfs order, with the nibble path leading to each node
+func (t *PatriciaTrie) walk(n *pb.Node, path []byte, fn func(path []byte, n *pb.Node) error) error {
+	if err := fn(path, n); err != nil {
+		return err
+	}
+
+	for i, nextHash := range n.Next {
+		if next, ok := t.Ht[nextHash]; ok && len(nextHash) > 0 {
+			if err := t.walk(next, append(path[:len(path):len(path)], byte(i)), fn); err != nil {
+				return err
+			}
+		}
+	}
+	for ep, nextHash := range n.EncodedPaths {
+		if next, ok := t.Ht[nextHash]; ok {
+			if err := t.walk(next, append(path[:len(path):len(path)], ep...), fn); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// keyCodec returns the codec of the keys. Tries from before codecs existed zip their keys with zlib.
+// The codecs are read from the fields of the trie, so the caller of keyCodec, valCodec and the encoding functions must
+// hold the lock. Lookup uses the codecs of the published view instead.
+func (t *PatriciaTrie) keyCodec() (Codec, error) {
+	name := t.KeyCodec
+	if len(name) == 0 {
+		name = CodecIdentity
+		if t.Zipped {
+			name = CodecZlib
+		}
+	}
+
+	return GetCodec(name, t.Dict)
+}
+
+// valCodec returns the codec of the values. Tries from before ValDict share Dict between their keys and values.
+func (t *PatriciaTrie) valCodec() (Codec, error) {
+	if len(t.ValCodec) == 0 {
+		return GetCodec(CodecIdentity, nil)
+	}
+
+	dict := t.ValDict
+	if len(dict) == 0 {
+		dict = t.Dict
+	}
+	return GetCodec(t.ValCodec, dict)
+}
+
+// encodeKey returns the key as it is stored in the trie
+func (t *PatriciaTrie) encodeKey(key string) (string, error) {
+	c, err := t.keyCodec()
+	if err != nil {
+		return "", err
+	}
+
+	return c.Encode(key)
+}
+
+// encodeVal returns the value as it is stored in the trie. The empty value marks a deletion and is kept as is.
+func (t *PatriciaTrie) encodeVal(val string) (string, error) {
+	if len(val) == 0 {
+		return val, nil
+	}
+
+	c, err := t.valCodec()
+	if err != nil {
+		return "", err
+	}
+
+	return c.Encode(val)
+}
+
+func (t *PatriciaTrie) decodeVal(val string) (string, error) {
+	c, err := t.valCodec()
+	if err != nil {
+		return "", err
+	}
+
+	return c.Decode(val)
+}
+
 // compress with fold all the nodes with Count==1 into one encoded path to save space and reduce search time
 func (t *PatriciaTrie) compress() {
-	t.foldNode(t.Root, ' ')
-	t.LastRadixCompression = int64(len(t.Ht))
+	t.foldNode(t.Root, ' ', true)
+	t.LastRadixCompression = int64(len(t.Ht) - t.orphans)
+	t.setView()
 }
 
-func (t *PatriciaTrie) foldNode(n *pb.Node, nibble byte) ([]byte, string) {
-	seq, target := []byte{}, n.Hash
+// foldNode folds the branches under a copy of n
+// rtype - encoded path and target to fold n into its parent, new hash of n
+func (t *PatriciaTrie) foldNode(n *pb.Node, nibble byte, isRoot bool) ([]byte, string, string) {
+	n = copyNode(n)
+	seq, target := []byte{}, ""
 	for i, nextHash := range n.Next {
 		if len(nextHash) > 0 {
 			next, _ := t.Ht[nextHash]
-			seq, target = t.foldNode(next, byte(i))
+			var hash string
+			seq, target, hash = t.foldNode(next, byte(i), false)
 			if len(seq) > 0 && len(next.Val) == 0 {
 				n.EncodedPaths[string(seq)] = target
 				n.Next[i] = ""
+			} else {
+				n.Next[i] = hash
 			}
 		}
 	}
+
+	// a single child behind an encoded path folded by a previous compression extends the path
+	if n.Count == 1 && len(seq) == 0 {
+		for ep, nh := range n.EncodedPaths {
+			seq, target = []byte(ep), nh
+		}
+	}
+
+	// folding changes the children of the node, so its hash has to follow
+	t.updateHash(n, isRoot, nil)
+	if len(target) == 0 {
+		target = n.Hash
+	}
 	if n.Count > 1 {
-		return []byte{nibble}, n.Hash
+		return []byte{nibble}, n.Hash, n.Hash
 	}
 
-	if n.Count == 1 && len(n.Val) == 0 && t.Root != n {
-		delete(t.Ht, n.Hash)
+	// a node with a single child and no value is folded into its parent and left to the sweep
+	if n.Count == 1 && len(n.Val) == 0 && !isRoot {
+		t.orphans++
 	}
 
-	return append([]byte{nibble}, seq...), target
+	return append([]byte{nibble}, seq...), target, n.Hash
 }
 
-func (t *PatriciaTrie) getWithPath(n *pb.Node, path []byte) (string, error) {
-	if len(path) == 0 {
-		if len(n.Val) == 0 {
-			return "", fmt.Errorf("No value found")
+// getWithPath follows the path down from n, looking the children up by hash, and calls visit on every node if not nil
+// rtype - value at the end of the path, whether there is one, error if a node on the path is missing
+func getWithPath(n *pb.Node, path []byte, lookup func(hash string) (*pb.Node, bool), visit func(n *pb.Node)) (string, bool, error) {
+	for {
+		if visit != nil {
+			visit(n)
+		}
+		if len(path) == 0 {
+			return n.Val, len(n.Val) > 0, nil
 		}
 
-		return n.Val, nil
-	}
+		// get the encoded path if any, or the child if exists
+		_, nextHash, rest, ok := getEncodedPath(n, path)
+		if !ok {
+			if !hasChild(n, path[0]) {
+				return "", false, nil
+			}
 
-	// get the encoded path if any
-	var nibble byte
-	_, nextHash, path, ok := getEncodedPath(n, path)
-	if !ok {
-		// get the child if exsits
-		if hasChild(n, path[0]) {
-			nextHash = n.Next[path[0]]
-			path = path[1:]
+			nextHash, rest = n.Next[path[0]], path[1:]
 		}
-	}
 
-	next, ok := t.Ht[nextHash]
-	if len(nextHash) == 0 || !ok {
-		return "", fmt.Errorf("No child at node %s for the nibble %d", n.Hash, nibble)
+		if n, ok = lookup(nextHash); !ok {
+			return "", false, fmt.Errorf("Node %s is missing", nextHash)
+		}
+		path = rest
 	}
-
-	return t.getWithPath(next, path)
 }
 
 // getEncodedPath returns:
@@ -199,19 +415,26 @@ func (t *PatriciaTrie) getWithPath(n *pb.Node, path []byte) (string, error) {
 // encoded_path_val
 // new_path
 // is_ok
+// The longest matching encoded path wins, since a shorter one may lead to a branch folded later from a sibling.
 func getEncodedPath(n *pb.Node, path []byte) (string, string, []byte, bool) {
+	key, val, ok := "", "", false
 	for ep, nh := range n.EncodedPaths {
 		// Get using the shortcurt
-		if strings.HasPrefix(string(path), ep) {
-			return ep, nh, path[len(ep):], true
+		if strings.HasPrefix(string(path), ep) && (!ok || len(ep) > len(key)) {
+			key, val, ok = ep, nh, true
 		}
 	}
 
-	return "", "", path, false
+	if !ok {
+		return "", "", path, false
+	}
+
+	return key, val, path[len(key):], true
 }
 
-// upsertPath adds or updates a path of bytes as a branch to the current node
+// upsertPath adds or updates a path of bytes as a branch to a copy of the current node
 func (t *PatriciaTrie) upsertWithPath(n *pb.Node, path []byte, val string, isRoot bool) (string, error) {
+	n = copyNode(n)
 	if len(path) == 0 {
 		n.Val = val
 	} else {
@@ -249,7 +472,7 @@ func (t *PatriciaTrie) upsertWithPath(n *pb.Node, path []byte, val string, isRoo
 	}
 
 	// finally, update the hash on the current node
-	return t.updateHash(n, isRoot)
+	return t.updateHash(n, isRoot, nil)
 }
 
 func hasChild(n *pb.Node, b byte) bool {
@@ -262,7 +485,10 @@ func updateChild(n *pb.Node, key int, val string) {
 	}
 
 	if len(val) == 0 {
-		n.Count--
+		// deleting a key that was never there leaves the count alone
+		if len(n.Next[key]) > 0 {
+			n.Count--
+		}
 	} else if len(n.Next[key]) == 0 {
 		n.Count++
 	}
@@ -280,7 +506,9 @@ func updateEncodedPath(n *pb.Node, key, val string) {
 	n.EncodedPaths[key] = val
 }
 
-func (t *PatriciaTrie) updateHash(n *pb.Node, isRoot bool) (string, error) {
+// updateHash rehashes the new copy n of a node and adds it to the hash table, as the root if isRoot.
+// Changes to the hash table are recorded in j if it is not nil.
+func (t *PatriciaTrie) updateHash(n *pb.Node, isRoot bool, j *journal) (string, error) {
 	if n == nil {
 		return "", nil
 	}
@@ -291,15 +519,20 @@ func (t *PatriciaTrie) updateHash(n *pb.Node, isRoot bool) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	if len(prev) > 0 {
-		delete(t.Ht, prev)
-	}
 
-	if len(newHash) > 0 || isRoot {
-		t.Ht[newHash] = n
+	// the previous version stays in the hash table until the next sweep, since another branch with the same
+	// content may share it and readers may still be on it
+	if len(prev) > 0 && prev != newHash {
+		t.orphans++
 	}
 
 	n.Hash = newHash
+	if len(newHash) > 0 || isRoot {
+		j.set(t, newHash, n)
+	}
+	if isRoot {
+		t.Root = n
+	}
 	return newHash, nil
 }
 
@@ -1,6 +1,7 @@
 package merkle
 
 import (
+	"fmt"
 	"testing"
 	"utils"
 
@@ -176,3 +177,67 @@ func getAll(trie *PatriciaTrie, keys []string) {
 		trie.Get(k)
 	}
 }
+
+func TestCompressWithUpdatesAndDeletes(t *testing.T) {
+	for _, batchSize := range []int64{5, 20, 100} {
+		trie := NewPatriciaTrie()
+		trie.BatchSize = batchSize
+		kvs := map[string]string{}
+		var keys []string
+		for i := 0; i < 500; i++ {
+			if i%4 == 3 {
+				k := keys[i%len(keys)]
+				if i%8 == 3 {
+					trie.Delete(k)
+					delete(kvs, k)
+				} else {
+					kvs[k] = utils.RandStringBytesMaskImprSrc(8)
+					trie.Upsert(k, kvs[k])
+				}
+				continue
+			}
+
+			k := utils.RandStringBytesMaskImprSrc(12)
+			kvs[k] = utils.RandStringBytesMaskImprSrc(8)
+			trie.Upsert(k, kvs[k])
+			keys = append(keys, k)
+		}
+
+		trie.Delete("nokey")
+		for k, v := range kvs {
+			rst, _ := trie.Get(k)
+			assert.Equal(t, v, rst)
+		}
+	}
+}
+
+func TestForEach(t *testing.T) {
+	trie := NewPatriciaTrie()
+	trie.Upsert("key1", "val1")
+	trie.Upsert("key2", "val2")
+	trie.compress()
+	trie.Upsert("key3", "val3")
+
+	kvs := map[string]string{}
+	err := trie.ForEach(func(key, val string) error {
+		kvs[key] = val
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"key1": "val1", "key2": "val2", "key3": "val3"}, kvs)
+}
+
+func TestCount(t *testing.T) {
+	// Count is the number of keys, whatever the number of nodes, as for the sparse merkle tree
+	trie := NewPatriciaTrie()
+	tree := NewSparseMerkleTree()
+	for i := 0; i < 20; i++ {
+		for _, st := range []StateTree{trie, tree} {
+			st.Upsert(fmt.Sprintf("key%d", i%15), fmt.Sprint(i))
+		}
+	}
+	trie.Delete("key0")
+	tree.Delete("key0")
+	assert.Equal(t, 14, trie.Count())
+	assert.Equal(t, 14, tree.Count())
+}
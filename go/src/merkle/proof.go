@@ -0,0 +1,122 @@
+package merkle
+
+// Multi-proofs of the patricia trie
+// A multi-proof is the union of the nodes on the paths of a set of keys, so the nodes shared by the paths, the root
+// first, are carried once. Nodes are verified by their content hash, so the verifier keys them by the hash it computes.
+import (
+	"bytes"
+	"fmt"
+	"proto"
+	"utils"
+)
+
+// GetMultiProof returns the nodes proving the values of the keys, or their absence
+func (t *PatriciaTrie) GetMultiProof(keys []string) (*pb.MultiProof, error) {
+	t.RLock()
+	defer t.RUnlock()
+	paths := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		key, err := t.encodeKey(key)
+		if err != nil {
+			return nil, err
+		}
+
+		paths = append(paths, utils.ToNibbles(key))
+	}
+
+	proof := &pb.MultiProof{
+		Root:     t.Root.Hash,
+		Zipped:   t.Zipped,
+		KeyCodec: t.KeyCodec,
+		ValCodec: t.ValCodec,
+		Dict:     t.Dict,
+		ValDict:  t.ValDict,
+	}
+	if isEmptyRoot(t.Root.Hash) {
+		return proof, nil
+	}
+
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		_, _, err := getWithPath(t.Root, path, t.lookup, func(n *pb.Node) {
+			if !seen[n.Hash] {
+				seen[n.Hash] = true
+				proof.Nodes = append(proof.Nodes, copyNode(n))
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return proof, nil
+}
+
+// VerifyMultiProof checks the proof against the root hash and returns the values of the keys it proves present.
+// The keys missing from the result are proven absent. The codecs are the ones of the trie, e.g. a new trie made with
+// the codecs of the one proven, and a proof with other codecs is rejected.
+func (t *PatriciaTrie) VerifyMultiProof(root string, keys []string, proof *pb.MultiProof) (map[string]string, error) {
+	t.RLock()
+	defer t.RUnlock()
+	if proof.Root != root {
+		return nil, fmt.Errorf("Proof is for root %s, not %s", proof.Root, root)
+	}
+	if proof.Zipped != t.Zipped || proof.KeyCodec != t.KeyCodec || proof.ValCodec != t.ValCodec ||
+		!bytes.Equal(proof.Dict, t.Dict) || !bytes.Equal(proof.ValDict, t.ValDict) {
+		return nil, fmt.Errorf("Proof codecs %s/%s don't match the codecs %s/%s of the trie", proof.KeyCodec,
+			proof.ValCodec, t.KeyCodec, t.ValCodec)
+	}
+
+	rst := make(map[string]string)
+	if isEmptyRoot(root) {
+		return rst, nil
+	}
+
+	ht := make(map[string]*pb.Node, len(proof.Nodes))
+	lookup := func(hash string) (*pb.Node, bool) {
+		n, ok := ht[hash]
+		return n, ok
+	}
+	for _, n := range proof.Nodes {
+		hash, err := utils.GetHash(n)
+		if err != nil {
+			return nil, err
+		}
+		if len(hash) == 0 {
+			return nil, fmt.Errorf("Empty node in the proof")
+		}
+
+		ht[hash] = n
+	}
+
+	rootNode, ok := ht[root]
+	if !ok {
+		return nil, fmt.Errorf("Root %s is missing from the proof", root)
+	}
+
+	for _, key := range keys {
+		encoded, err := t.encodeKey(key)
+		if err != nil {
+			return nil, err
+		}
+
+		val, ok, err := getWithPath(rootNode, utils.ToNibbles(encoded), lookup, nil)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		if rst[key], err = t.decodeVal(val); err != nil {
+			return nil, err
+		}
+	}
+
+	return rst, nil
+}
+
+// isEmptyRoot tells if the root hash is the one of an empty trie, either new or emptied by deletes
+func isEmptyRoot(hash string) bool {
+	return hash == "" || hash == "0"
+}
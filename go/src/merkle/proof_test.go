@@ -0,0 +1,117 @@
+package merkle
+
+import (
+	"fmt"
+	"proto"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiProof(t *testing.T) {
+	for _, trie := range []*PatriciaTrie{NewPatriciaTrie(), NewPatriciaTrieWithCodec(Identity, Snappy)} {
+		for i := 0; i < 1000; i++ {
+			trie.Upsert(fmt.Sprintf("acc%d", i), fmt.Sprintf("%d", i))
+		}
+		trie.Delete("acc3")
+
+		var keys []string
+		for i := 0; i < 100; i++ {
+			keys = append(keys, fmt.Sprintf("acc%d", i*7))
+		}
+		keys = append(keys, "acc3", "missing")
+
+		proof, err := trie.GetMultiProof(keys)
+		assert.Nil(t, err)
+		vals, err := trie.VerifyMultiProof(trie.Root.Hash, keys, proof)
+		assert.Nil(t, err)
+		assert.Equal(t, 100, len(vals))
+		for i := 0; i < 100; i++ {
+			assert.Equal(t, fmt.Sprintf("%d", i*7), vals[fmt.Sprintf("acc%d", i*7)])
+		}
+		_, ok := vals["acc3"]
+		assert.False(t, ok)
+
+		// shared nodes are only carried once
+		single := 0
+		for _, key := range keys {
+			p, err := trie.GetMultiProof([]string{key})
+			assert.Nil(t, err)
+			single += len(p.Nodes)
+		}
+		assert.True(t, len(proof.Nodes) < single)
+
+		// the proof survives serialization
+		data, err := proto.Marshal(proof)
+		assert.Nil(t, err)
+		var decoded pb.MultiProof
+		assert.Nil(t, proto.Unmarshal(data, &decoded))
+		vals, err = trie.VerifyMultiProof(trie.Root.Hash, keys, &decoded)
+		assert.Nil(t, err)
+		assert.Equal(t, 100, len(vals))
+
+		// keys outside of the proof can't be verified
+		_, err = trie.VerifyMultiProof(trie.Root.Hash, []string{"acc999"}, proof)
+		assert.NotNil(t, err)
+
+		_, err = trie.VerifyMultiProof("other", keys, proof)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestMultiProofCodecs(t *testing.T) {
+	trie := NewPatriciaTrieWithCodec(Identity, Snappy)
+	trie.Upsert("a", "1")
+	proof, err := trie.GetMultiProof([]string{"a"})
+	assert.Nil(t, err)
+
+	// any trie with the same codecs verifies the proof
+	vals, err := NewPatriciaTrieWithCodec(Identity, Snappy).VerifyMultiProof(trie.Root.Hash, []string{"a"}, proof)
+	assert.Nil(t, err)
+	assert.Equal(t, "1", vals["a"])
+
+	_, err = NewPatriciaTrie().VerifyMultiProof(trie.Root.Hash, []string{"a"}, proof)
+	assert.NotNil(t, err)
+
+	// the codecs of the proof are not trusted
+	proof.ValCodec = Identity.Name()
+	_, err = trie.VerifyMultiProof(trie.Root.Hash, []string{"a"}, proof)
+	assert.NotNil(t, err)
+}
+
+func TestMultiProofTampered(t *testing.T) {
+	trie := NewPatriciaTrieWithCodec(Identity, Identity)
+	for i := 0; i < 100; i++ {
+		trie.Upsert(fmt.Sprintf("acc%d", i), "10")
+	}
+
+	keys := []string{"acc1", "acc2", "acc50"}
+	proof, err := trie.GetMultiProof(keys)
+	assert.Nil(t, err)
+	for _, n := range proof.Nodes {
+		if len(n.Val) > 0 {
+			n.Val = "1000"
+		}
+	}
+
+	_, err = trie.VerifyMultiProof(trie.Root.Hash, keys, proof)
+	assert.NotNil(t, err)
+}
+
+func TestMultiProofEmpty(t *testing.T) {
+	trie := NewPatriciaTrie()
+	proof, err := trie.GetMultiProof([]string{"a"})
+	assert.Nil(t, err)
+	vals, err := trie.VerifyMultiProof(trie.Root.Hash, []string{"a"}, proof)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(vals))
+
+	trie.Upsert("a", "1")
+	trie.Delete("a")
+	proof, err = trie.GetMultiProof([]string{"a"})
+	assert.Nil(t, err)
+	vals, err = trie.VerifyMultiProof(trie.Root.Hash, []string{"a"}, proof)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(vals))
+}
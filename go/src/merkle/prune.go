@@ -0,0 +1,192 @@
+package merkle
+
+// Pruner is a mark-and-sweep garbage collector for a NodeStore
+// It keeps the nodes reachable from the last Keep committed roots and deletes everything else.
+// PruneOnline only takes the store's write lock for short sweeps of ChunkSize nodes, so reads and commits
+// carry on while it runs; nodes committed during an online prune are always kept.
+import "proto"
+
+type Pruner struct {
+	Store     *NodeStore
+	Keep      int // number of most recent roots to keep
+	ChunkSize int // number of nodes deleted per write lock in online mode
+}
+
+func NewPruner(s *NodeStore, keep int) *Pruner {
+	return &Pruner{
+		Store:     s,
+		Keep:      keep,
+		ChunkSize: 1000,
+	}
+}
+
+// Prune deletes all the unreachable nodes while holding the write lock of the store
+// rtype - number of deleted nodes
+func (p *Pruner) Prune() int {
+	s := p.Store
+	s.Lock()
+	defer s.Unlock()
+	if s.pruning {
+		return 0
+	}
+
+	roots := p.trimRoots()
+	marked := make(map[string]bool)
+	for _, root := range roots {
+		mark(root, s.nodes, marked)
+	}
+
+	deleted := 0
+	for hash := range s.nodes {
+		if !marked[hash] {
+			delete(s.nodes, hash)
+			deleted++
+		}
+	}
+
+	return deleted
+}
+
+// PruneOnline deletes the unreachable nodes without blocking readers
+// rtype - number of deleted nodes
+func (p *Pruner) PruneOnline() int {
+	roots, ok := p.beginOnline()
+	if !ok {
+		return 0
+	}
+	defer p.endOnline()
+
+	marked, candidates := p.markOnline(roots)
+	chunk := p.ChunkSize
+	if chunk <= 0 {
+		chunk = len(candidates)
+	}
+
+	deleted := 0
+	for len(candidates) > 0 {
+		n := chunk
+		if n > len(candidates) {
+			n = len(candidates)
+		}
+
+		deleted += p.sweepChunk(candidates[:n], marked)
+		candidates = candidates[n:]
+	}
+
+	return deleted
+}
+
+// beginOnline flags the store as pruning and trims its roots
+// rtype - kept roots, false if a prune is already running
+func (p *Pruner) beginOnline() ([]string, bool) {
+	s := p.Store
+	s.Lock()
+	defer s.Unlock()
+	if s.pruning {
+		return nil, false
+	}
+
+	s.pruning = true
+	s.fresh = nil
+	return p.trimRoots(), true
+}
+
+func (p *Pruner) endOnline() {
+	s := p.Store
+	s.Lock()
+	defer s.Unlock()
+	s.pruning = false
+	s.fresh = nil
+}
+
+// markOnline marks everything reachable from the kept roots. Only the pruner deletes nodes, so the marked
+// branches stay in place while the read lock is released between roots.
+// rtype - marked nodes, unmarked nodes to delete
+func (p *Pruner) markOnline(roots []string) (map[string]bool, []string) {
+	s := p.Store
+	marked := make(map[string]bool)
+	for _, root := range roots {
+		s.RLock()
+		mark(root, s.nodes, marked)
+		s.RUnlock()
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+	var candidates []string
+	for hash := range s.nodes {
+		if !marked[hash] {
+			candidates = append(candidates, hash)
+		}
+	}
+
+	return marked, candidates
+}
+
+// sweepChunk deletes the candidates that are still unmarked under a single write lock
+// rtype - number of deleted nodes
+func (p *Pruner) sweepChunk(candidates []string, marked map[string]bool) int {
+	s := p.Store
+	s.Lock()
+	defer s.Unlock()
+	// nodes written or reused by a commit since the mark are reachable from a new root
+	for _, hash := range s.fresh {
+		mark(hash, s.nodes, marked)
+	}
+	s.fresh = s.fresh[:0]
+
+	deleted := 0
+	for _, hash := range candidates {
+		if _, ok := s.nodes[hash]; ok && !marked[hash] {
+			delete(s.nodes, hash)
+			deleted++
+		}
+	}
+
+	return deleted
+}
+
+// trimRoots drops all but the last Keep roots from the store and returns the kept ones
+// The caller must hold the write lock of the store.
+func (p *Pruner) trimRoots() []string {
+	s := p.Store
+	if p.Keep >= 0 && len(s.roots) > p.Keep {
+		s.roots = append([]string(nil), s.roots[len(s.roots)-p.Keep:]...)
+	}
+
+	// a dropped root may have been committed again later, so only drop the settings of roots that are gone
+	for root := range s.trees {
+		if !containsString(s.roots, root) {
+			delete(s.trees, root)
+		}
+	}
+
+	return append([]string(nil), s.roots...)
+}
+
+// mark flags the node and all of its descendants in marked
+func mark(hash string, nodes map[string]*pb.Node, marked map[string]bool) {
+	if marked[hash] {
+		return
+	}
+
+	n, ok := nodes[hash]
+	if !ok {
+		return
+	}
+
+	marked[hash] = true
+	for _, next := range children(n) {
+		mark(next, nodes, marked)
+	}
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
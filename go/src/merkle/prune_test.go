@@ -0,0 +1,130 @@
+package merkle
+
+import (
+	"sync"
+	"testing"
+	"utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func commitVersions(s *NodeStore, n int) (*PatriciaTrie, []string) {
+	trie := NewPatriciaTrie()
+	var roots []string
+	for i := 0; i < n; i++ {
+		trie.Upsert("key1", utils.RandStringBytesMaskImprSrc(8))
+		trie.Upsert(utils.RandStringBytesMaskImprSrc(16), "val")
+		root, _ := s.Commit(trie)
+		roots = append(roots, root)
+	}
+
+	return trie, roots
+}
+
+func TestPruneKeepsLastRoots(t *testing.T) {
+	s := NewNodeStore()
+	_, roots := commitVersions(s, 10)
+	before := s.Len()
+	deleted := NewPruner(s, 3).Prune()
+	assert.True(t, deleted > 0)
+	assert.Equal(t, before-deleted, s.Len())
+	assert.Equal(t, roots[7:], s.Roots())
+
+	for _, root := range roots[7:] {
+		_, err := s.Load(root)
+		assert.Nil(t, err)
+	}
+	for _, root := range roots[:7] {
+		assert.False(t, s.Has(root))
+	}
+}
+
+func TestPruneNothingToDelete(t *testing.T) {
+	s := NewNodeStore()
+	commitVersions(s, 3)
+	assert.Equal(t, 0, NewPruner(s, 3).Prune())
+}
+
+func TestPruneOnline(t *testing.T) {
+	s := NewNodeStore()
+	_, roots := commitVersions(s, 10)
+	p := NewPruner(s, 2)
+	p.ChunkSize = 2
+	deleted := p.PruneOnline()
+	assert.True(t, deleted > 0)
+	assert.Equal(t, roots[8:], s.Roots())
+	for _, root := range roots[8:] {
+		_, err := s.Load(root)
+		assert.Nil(t, err)
+	}
+}
+
+func TestPruneOnlineReusedBranch(t *testing.T) {
+	// a commit between two chunks reuses an unmarked node whose descendants the first chunk deleted
+	s := NewNodeStore()
+	_, roots := commitVersions(s, 3)
+	old, err := s.Load(roots[0])
+	assert.Nil(t, err)
+
+	p := NewPruner(s, 1)
+	kept, ok := p.beginOnline()
+	assert.True(t, ok)
+	marked, candidates := p.markOnline(kept)
+	var first []string
+	for _, hash := range candidates {
+		if hash != roots[0] {
+			first = append(first, hash)
+		}
+	}
+	assert.True(t, p.sweepChunk(first, marked) > 0)
+	assert.True(t, s.Has(roots[0]))
+
+	root, err := s.Commit(old)
+	assert.Nil(t, err)
+	assert.Equal(t, roots[0], root)
+	assert.Equal(t, 0, p.sweepChunk([]string{roots[0]}, marked))
+	p.endOnline()
+
+	loaded, err := s.Load(roots[0])
+	assert.Nil(t, err)
+	rst, _ := loaded.Get("key1")
+	expected, _ := old.Get("key1")
+	assert.Equal(t, expected, rst)
+}
+
+func TestPruneOnlineWithConcurrentCommits(t *testing.T) {
+	s := NewNodeStore()
+	trie, roots := commitVersions(s, 20)
+	// keep enough roots for all the concurrent commits, only the old versions are pruned
+	p := NewPruner(s, 20)
+	p.ChunkSize = 1
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	var committed []string
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			trie.Upsert(utils.RandStringBytesMaskImprSrc(16), "val")
+			root, err := s.Commit(trie)
+			assert.Nil(t, err)
+			committed = append(committed, root)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for _, root := range roots[15:] {
+			s.Load(root)
+		}
+	}()
+	p.PruneOnline()
+	wg.Wait()
+
+	// every root committed while pruning must still be complete
+	for _, root := range committed {
+		loaded, err := s.Load(root)
+		assert.Nil(t, err)
+		rst, _ := loaded.Get("key1")
+		assert.True(t, len(rst) > 0)
+	}
+}
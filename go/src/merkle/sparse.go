@@ -0,0 +1,497 @@
+package merkle
+
+// Sparse merkle tree, an alternative state commitment to the patricia trie
+// Keys are placed by the bits of their sha256, so the tree has a fixed depth of 256 and the same kv pairs always
+// give the same root, whatever the order of the writes. Empty subtrees hash to defaults precomputed per depth, and a
+// subtree holding a single leaf is stored as that leaf, hashed with its path so that it needs no hashing per level.
+// Proofs list the 256 siblings of a key with a bitmap of the non-default ones, so only those are carried.
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"proto"
+	"strconv"
+	"sync"
+)
+
+const smtDepth = 256
+
+// smtDefaults[d] is the hash of an empty subtree at depth d, an empty leaf being all zeros
+var smtDefaults = func() [][]byte {
+	defaults := make([][]byte, smtDepth+1)
+	defaults[smtDepth] = make([]byte, sha256.Size)
+	for d := smtDepth - 1; d >= 0; d-- {
+		defaults[d] = smtHash(1, defaults[d+1], defaults[d+1])
+	}
+	return defaults
+}()
+
+type SparseMerkleTree struct {
+	sync.RWMutex
+	root *smtNode
+}
+
+// smtNode is either a branch or a leaf standing for the whole subtree it is the only key of
+// A nil child is an empty subtree, hashing to the default of its depth. The hash is nil until computed.
+type smtNode struct {
+	hash        []byte
+	left, right *smtNode
+	leaf        bool
+	path        []byte // sha256 of the key
+	key, val    string
+}
+
+// SparseProof proves the value of a key, or its absence, against a root hash
+type SparseProof struct {
+	Depth    int      // depth where the walk to the key ended
+	Bitmap   []byte   // bit d is set when the sibling at depth d+1 is not the default hash
+	Siblings [][]byte // the non-default siblings, from the root down
+	// the leaf found at Depth when the key is missing, by its path and value hash
+	OtherPath    []byte
+	OtherValHash []byte
+}
+
+func NewSparseMerkleTree() *SparseMerkleTree {
+	return &SparseMerkleTree{}
+}
+
+// Upsert will update or add a kv pair to the tree, an empty value deletes the key
+func (t *SparseMerkleTree) Upsert(key, val string) error {
+	return t.commitBatch([]batchOp{{key: key, val: val}})
+}
+
+// UpsertFloat will update or add a kv pair to the tree, where v is a float
+func (t *SparseMerkleTree) UpsertFloat(key string, val float64) error {
+	return t.Upsert(key, fmt.Sprintf("%f", val))
+}
+
+// Delete will delete a key and collapse the branches left with a single leaf
+func (t *SparseMerkleTree) Delete(key string) error {
+	return t.commitBatch([]batchOp{{key: key}})
+}
+
+// NewBatch creates an empty write batch for the tree
+func (t *SparseMerkleTree) NewBatch() *Batch {
+	return &Batch{w: t}
+}
+
+// commitBatch applies the ops then computes the hashes of the touched nodes, each one once
+func (t *SparseMerkleTree) commitBatch(ops []batchOp) error {
+	t.Lock()
+	defer t.Unlock()
+	for _, op := range ops {
+		path := smtPath(op.key)
+		if len(op.val) == 0 {
+			t.root, _ = smtRemove(t.root, 0, path)
+		} else {
+			t.root = smtInsert(t.root, 0, &smtNode{leaf: true, path: path, key: op.key, val: op.val})
+		}
+	}
+
+	// readers only take the read lock, so no hash is left to be computed lazily
+	t.root.hashAt(0)
+	return nil
+}
+
+// Get returns the value to the key
+func (t *SparseMerkleTree) Get(key string) (string, bool) {
+	rst, err := t.Lookup(key)
+	return rst, err == nil
+}
+
+// Lookup returns the value to the key, ErrNotFound if the key is missing
+func (t *SparseMerkleTree) Lookup(key string) (string, error) {
+	path := smtPath(key)
+	t.RLock()
+	defer t.RUnlock()
+	n, _ := t.find(path)
+	if n != nil && n.leaf && bytes.Equal(n.path, path) {
+		return n.val, nil
+	}
+
+	return "", ErrNotFound
+}
+
+// GetFloat returns the value to the key
+// rtype - float64, error
+func (t *SparseMerkleTree) GetFloat(key string) (float64, bool) {
+	if v, ok := t.Get(key); ok {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f, true
+		}
+	}
+
+	return 0.0, false
+}
+
+// ForEach calls fn with every kv pair of the tree, in the order of their paths. It stops at the first error of fn.
+func (t *SparseMerkleTree) ForEach(fn func(key, val string) error) error {
+	t.RLock()
+	defer t.RUnlock()
+	return smtWalk(t.root, fn)
+}
+
+// Count returns the number of keys in the tree
+func (t *SparseMerkleTree) Count() int {
+	count := 0
+	t.ForEach(func(key, val string) error {
+		count++
+		return nil
+	})
+	return count
+}
+
+// Root returns the root hash of the tree
+func (t *SparseMerkleTree) Root() []byte {
+	t.RLock()
+	defer t.RUnlock()
+	return t.root.hashAt(0)
+}
+
+// RootHash returns the root hash of the tree as a hex string
+func (t *SparseMerkleTree) RootHash() (string, error) {
+	return hex.EncodeToString(t.Root()), nil
+}
+
+// GetProof returns the proof of the value of the key, or of its absence
+func (t *SparseMerkleTree) GetProof(key string) *SparseProof {
+	path := smtPath(key)
+	t.RLock()
+	defer t.RUnlock()
+	p := &SparseProof{Bitmap: make([]byte, smtDepth/8)}
+	n := t.root
+	for n != nil && !n.leaf {
+		next, sibling := n.left, n.right
+		if smtBit(path, p.Depth) == 1 {
+			next, sibling = n.right, n.left
+		}
+
+		if sibling != nil {
+			p.Bitmap[p.Depth/8] |= 0x80 >> uint(p.Depth%8)
+			p.Siblings = append(p.Siblings, sibling.hashAt(p.Depth+1))
+		}
+		n = next
+		p.Depth++
+	}
+
+	if n != nil && !bytes.Equal(n.path, path) {
+		valHash := sha256.Sum256([]byte(n.val))
+		p.OtherPath = n.path
+		p.OtherValHash = valHash[:]
+	}
+	return p
+}
+
+// VerifySparseProof checks the proof of the kv pair against the root hash. An empty val proves that the key is missing.
+func VerifySparseProof(root []byte, key, val string, p *SparseProof) bool {
+	if p == nil || p.Depth < 0 || p.Depth > smtDepth || len(p.Bitmap) != smtDepth/8 {
+		return false
+	}
+
+	path := smtPath(key)
+	var h []byte
+	switch {
+	case len(val) > 0:
+		if p.OtherPath != nil {
+			return false
+		}
+		h = smtLeafHash(path, val)
+	case p.OtherPath != nil:
+		// the other leaf must share the path of the key down to where the walk ended
+		if len(p.OtherPath) != len(path) || bytes.Equal(p.OtherPath, path) {
+			return false
+		}
+		for d := 0; d < p.Depth; d++ {
+			if smtBit(p.OtherPath, d) != smtBit(path, d) {
+				return false
+			}
+		}
+		h = smtHash(0, p.OtherPath, p.OtherValHash)
+	default:
+		h = smtDefaults[p.Depth]
+	}
+
+	siblings := p.Siblings
+	for d := p.Depth - 1; d >= 0; d-- {
+		sibling := smtDefaults[d+1]
+		if p.Bitmap[d/8]&(0x80>>uint(d%8)) != 0 {
+			if len(siblings) == 0 {
+				return false
+			}
+			sibling = siblings[len(siblings)-1]
+			siblings = siblings[:len(siblings)-1]
+		}
+		h = smtParent(path, d, h, sibling)
+	}
+
+	return len(siblings) == 0 && bytes.Equal(h, root)
+}
+
+// ToTree stores the tree in a pb.Tree, the nodes keyed by their hex hash with the children of branches in Next
+func (t *SparseMerkleTree) ToTree() (*pb.Tree, error) {
+	t.RLock()
+	defer t.RUnlock()
+	tree := &pb.Tree{
+		Ht:   make(map[string]*pb.Node),
+		Kind: StateSparse,
+	}
+	tree.Root = smtToNode(t.root, 0, tree.Ht)
+	if tree.Root == nil {
+		tree.Root = &pb.Node{Hash: hex.EncodeToString(smtDefaults[0])}
+	}
+	return tree, nil
+}
+
+// LoadSparseMerkleTree rebuilds a tree stored by ToTree, checking the hashes of all the nodes
+func LoadSparseMerkleTree(tree *pb.Tree) (*SparseMerkleTree, error) {
+	return loadSparseMerkleTree(tree, true)
+}
+
+// OpenSparseMerkleTree rebuilds a tree stored by ToTree that was checked before, e.g. when its block was added,
+// trusting the stored hashes
+func OpenSparseMerkleTree(tree *pb.Tree) (*SparseMerkleTree, error) {
+	return loadSparseMerkleTree(tree, false)
+}
+
+func loadSparseMerkleTree(tree *pb.Tree, verify bool) (*SparseMerkleTree, error) {
+	if tree.Kind != StateSparse {
+		return nil, fmt.Errorf("Not a sparse merkle tree: %s", tree.Kind)
+	}
+
+	t := NewSparseMerkleTree()
+	if tree.Root == nil || len(tree.Root.Next) == 0 && len(tree.Root.Val) == 0 {
+		if hash := hex.EncodeToString(smtDefaults[0]); verify && tree.Root != nil && tree.Root.Hash != hash {
+			return nil, fmt.Errorf("Hash mismatch for the empty root %s, got %s", tree.Root.Hash, hash)
+		}
+		return t, nil
+	}
+
+	root, err := smtFromNode(tree.Root, 0, tree.Ht, verify)
+	if err != nil {
+		return nil, err
+	}
+
+	t.root = root
+	return t, nil
+}
+
+func smtToNode(n *smtNode, d int, ht map[string]*pb.Node) *pb.Node {
+	if n == nil {
+		return nil
+	}
+
+	pn := &pb.Node{Hash: hex.EncodeToString(n.hashAt(d))}
+	if n.leaf {
+		pn.Key = n.key
+		pn.Val = n.val
+	} else {
+		pn.Next = make([]string, 2)
+		for i, child := range []*smtNode{n.left, n.right} {
+			if c := smtToNode(child, d+1, ht); c != nil {
+				pn.Next[i] = c.Hash
+				pn.Count++
+			}
+		}
+	}
+
+	ht[pn.Hash] = pn
+	return pn
+}
+
+func smtFromNode(pn *pb.Node, d int, ht map[string]*pb.Node, verify bool) (*smtNode, error) {
+	if d > smtDepth {
+		return nil, fmt.Errorf("Node %s is deeper than the tree", pn.Hash)
+	}
+
+	n := &smtNode{}
+	if len(pn.Next) == 0 {
+		n.leaf = true
+		n.key = pn.Key
+		n.val = pn.Val
+		n.path = smtPath(pn.Key)
+	} else {
+		if len(pn.Next) != 2 {
+			return nil, fmt.Errorf("Branch %s has %d children", pn.Hash, len(pn.Next))
+		}
+
+		for i, hash := range pn.Next {
+			if len(hash) == 0 {
+				continue
+			}
+
+			next, ok := ht[hash]
+			if !ok {
+				return nil, fmt.Errorf("Node %s refers to unknown node %s", pn.Hash, hash)
+			}
+
+			child, err := smtFromNode(next, d+1, ht, verify)
+			if err != nil {
+				return nil, err
+			}
+
+			if i == 0 {
+				n.left = child
+			} else {
+				n.right = child
+			}
+		}
+	}
+
+	if !verify {
+		hash, err := hex.DecodeString(pn.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("Node %s: %v", pn.Hash, err)
+		}
+
+		n.hash = hash
+		return n, nil
+	}
+
+	if hash := hex.EncodeToString(n.hashAt(d)); hash != pn.Hash {
+		return nil, fmt.Errorf("Hash mismatch for node %s, got %s", pn.Hash, hash)
+	}
+	return n, nil
+}
+
+// smtInsert puts the leaf under n at depth d and clears the hashes of the branches along the way
+func smtInsert(n *smtNode, d int, leaf *smtNode) *smtNode {
+	if n == nil {
+		return leaf
+	}
+
+	if n.leaf {
+		if bytes.Equal(n.path, leaf.path) {
+			return leaf
+		}
+
+		// both leaves move below a new branch, which splits again while their paths agree
+		return smtInsert(smtInsert(&smtNode{}, d, n), d, leaf)
+	}
+
+	n.hash = nil
+	if smtBit(leaf.path, d) == 0 {
+		n.left = smtInsert(n.left, d+1, leaf)
+	} else {
+		n.right = smtInsert(n.right, d+1, leaf)
+	}
+	return n
+}
+
+// smtRemove deletes the leaf of the path under n at depth d
+// rtype - the new subtree, whether the leaf was found
+func smtRemove(n *smtNode, d int, path []byte) (*smtNode, bool) {
+	if n == nil {
+		return nil, false
+	}
+
+	if n.leaf {
+		if bytes.Equal(n.path, path) {
+			return nil, true
+		}
+		return n, false
+	}
+
+	var found bool
+	if smtBit(path, d) == 0 {
+		n.left, found = smtRemove(n.left, d+1, path)
+	} else {
+		n.right, found = smtRemove(n.right, d+1, path)
+	}
+	if !found {
+		return n, false
+	}
+
+	// a branch left with a single leaf is replaced by the leaf
+	n.hash = nil
+	switch {
+	case n.left == nil && n.right == nil:
+		return nil, true
+	case n.left == nil && n.right.leaf:
+		return n.right, true
+	case n.right == nil && n.left.leaf:
+		return n.left, true
+	}
+	return n, true
+}
+
+// find walks down the path to the first leaf or empty subtree
+func (t *SparseMerkleTree) find(path []byte) (*smtNode, int) {
+	n, d := t.root, 0
+	for n != nil && !n.leaf {
+		if smtBit(path, d) == 0 {
+			n = n.left
+		} else {
+			n = n.right
+		}
+		d++
+	}
+
+	return n, d
+}
+
+func smtWalk(n *smtNode, fn func(key, val string) error) error {
+	if n == nil {
+		return nil
+	}
+
+	if n.leaf {
+		return fn(n.key, n.val)
+	}
+
+	if err := smtWalk(n.left, fn); err != nil {
+		return err
+	}
+	return smtWalk(n.right, fn)
+}
+
+// hashAt returns the hash of n at depth d, computing the missing ones below
+func (n *smtNode) hashAt(d int) []byte {
+	if n == nil {
+		return smtDefaults[d]
+	}
+
+	if n.hash == nil {
+		if n.leaf {
+			n.hash = smtLeafHash(n.path, n.val)
+		} else {
+			n.hash = smtHash(1, n.left.hashAt(d+1), n.right.hashAt(d+1))
+		}
+	}
+	return n.hash
+}
+
+// smtParent hashes the node at depth d of the path from its child h on the path and the sibling of h
+func smtParent(path []byte, d int, h, sibling []byte) []byte {
+	if smtBit(path, d) == 0 {
+		return smtHash(1, h, sibling)
+	}
+
+	return smtHash(1, sibling, h)
+}
+
+// smtLeafHash is the hash of the subtree holding only the leaf, at any depth, the path binding it to its place
+func smtLeafHash(path []byte, val string) []byte {
+	valHash := sha256.Sum256([]byte(val))
+	return smtHash(0, path, valHash[:])
+}
+
+// smtHash is the sha256 of the parts behind a prefix, 0 for leaves and 1 for branches
+func smtHash(prefix byte, parts ...[]byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{prefix})
+	for _, part := range parts {
+		h.Write(part)
+	}
+	return h.Sum(nil)
+}
+
+func smtPath(key string) []byte {
+	path := sha256.Sum256([]byte(key))
+	return path[:]
+}
+
+// smtBit is the bit of the path at depth d, 0 going left and 1 going right
+func smtBit(path []byte, d int) int {
+	return int(path[d/8]>>uint(7-d%8)) & 1
+}
@@ -0,0 +1,213 @@
+package merkle
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSparseUpsertGetDelete(t *testing.T) {
+	tree := NewSparseMerkleTree()
+	empty := tree.Root()
+	assert.Equal(t, smtDefaults[0], empty)
+	_, ok := tree.Get("a")
+	assert.False(t, ok)
+
+	for i := 0; i < 200; i++ {
+		assert.Nil(t, tree.Upsert(fmt.Sprintf("key%d", i), fmt.Sprintf("val%d", i)))
+	}
+	assert.Equal(t, 200, tree.Count())
+	for i := 0; i < 200; i++ {
+		v, ok := tree.Get(fmt.Sprintf("key%d", i))
+		assert.True(t, ok)
+		assert.Equal(t, fmt.Sprintf("val%d", i), v)
+	}
+
+	assert.Nil(t, tree.UpsertFloat("key7", 1.5))
+	f, ok := tree.GetFloat("key7")
+	assert.True(t, ok)
+	assert.Equal(t, 1.5, f)
+
+	for i := 0; i < 200; i++ {
+		assert.Nil(t, tree.Delete(fmt.Sprintf("key%d", i)))
+	}
+	assert.Equal(t, 0, tree.Count())
+	assert.Equal(t, empty, tree.Root())
+	assert.Nil(t, tree.root)
+}
+
+func TestSparseRootIsOrderIndependent(t *testing.T) {
+	keys := rand.Perm(300)
+	a, b := NewSparseMerkleTree(), NewSparseMerkleTree()
+	for _, k := range keys {
+		a.Upsert(fmt.Sprint(k), fmt.Sprint(k*k))
+	}
+	for i := len(keys) - 1; i >= 0; i-- {
+		b.Upsert(fmt.Sprint(keys[i]), "tmp")
+		b.Upsert(fmt.Sprint(keys[i]), fmt.Sprint(keys[i]*keys[i]))
+	}
+	b.Upsert("extra", "1")
+	b.Delete("extra")
+	assert.Equal(t, a.Root(), b.Root())
+
+	// the tree after the deletes hashes the same as one built from scratch
+	for _, k := range keys[:50] {
+		a.Delete(fmt.Sprint(k))
+	}
+	c := NewSparseMerkleTree()
+	for _, k := range keys[50:] {
+		c.Upsert(fmt.Sprint(k), fmt.Sprint(k*k))
+	}
+	assert.Equal(t, c.Root(), a.Root())
+}
+
+func TestSparseSingleLeafHash(t *testing.T) {
+	tree := NewSparseMerkleTree()
+	tree.Upsert("a", "1")
+	path := smtPath("a")
+	assert.Equal(t, smtLeafHash(path, "1"), tree.Root())
+
+	// the leaf is bound to its path, wherever it is stored
+	tree.Upsert("b", "1")
+	pathB := smtPath("b")
+	assert.NotEqual(t, smtLeafHash(path, "1"), smtLeafHash(pathB, "1"))
+	d := 0
+	for smtBit(path, d) == smtBit(pathB, d) {
+		d++
+	}
+	h := smtParent(path, d, smtLeafHash(path, "1"), smtLeafHash(pathB, "1"))
+	for d--; d >= 0; d-- {
+		h = smtParent(path, d, h, smtDefaults[d+1])
+	}
+	assert.Equal(t, h, tree.Root())
+}
+
+func TestSparseProof(t *testing.T) {
+	tree := NewSparseMerkleTree()
+	for i := 0; i < 100; i++ {
+		tree.Upsert(fmt.Sprintf("key%d", i), fmt.Sprintf("val%d", i))
+	}
+	root := tree.Root()
+
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%d", i)
+		p := tree.GetProof(key)
+		assert.True(t, VerifySparseProof(root, key, fmt.Sprintf("val%d", i), p))
+		assert.False(t, VerifySparseProof(root, key, "wrong", p))
+		assert.False(t, VerifySparseProof(root, key, "", p))
+		// compressed, only the siblings on the path down to the leaf are carried
+		assert.True(t, len(p.Siblings) <= p.Depth)
+		assert.True(t, len(p.Siblings) < 20)
+	}
+
+	// absence, ending at an empty subtree or at another leaf
+	others := 0
+	for i := 100; i < 300; i++ {
+		key := fmt.Sprintf("key%d", i)
+		p := tree.GetProof(key)
+		if p.OtherPath != nil {
+			others++
+		}
+		assert.True(t, VerifySparseProof(root, key, "", p))
+		assert.False(t, VerifySparseProof(root, key, "val", p))
+		assert.False(t, VerifySparseProof(root, "key1", "", p))
+	}
+	assert.True(t, others > 0)
+
+	p := tree.GetProof("key1")
+	p.Siblings[0] = smtDefaults[0]
+	assert.False(t, VerifySparseProof(root, "key1", "val1", p))
+
+	empty := NewSparseMerkleTree()
+	assert.True(t, VerifySparseProof(empty.Root(), "a", "", empty.GetProof("a")))
+}
+
+func TestSparseBatch(t *testing.T) {
+	a, b := NewSparseMerkleTree(), NewSparseMerkleTree()
+	batch := b.NewBatch()
+	for i := 0; i < 100; i++ {
+		a.Upsert(fmt.Sprint(i), "v")
+		batch.Put(fmt.Sprint(i), "v")
+	}
+	a.Delete("5")
+	batch.Delete("5")
+	assert.Nil(t, batch.Commit())
+	assert.Equal(t, 0, batch.Len())
+	assert.Equal(t, a.Root(), b.Root())
+}
+
+func TestSparseToTree(t *testing.T) {
+	tree := NewSparseMerkleTree()
+	for i := 0; i < 50; i++ {
+		tree.Upsert(fmt.Sprint(i), fmt.Sprint(i))
+	}
+
+	stored, err := tree.ToTree()
+	assert.Nil(t, err)
+	hash, _ := tree.RootHash()
+	assert.Equal(t, hash, stored.Root.Hash)
+
+	loaded, err := LoadSparseMerkleTree(stored)
+	assert.Nil(t, err)
+	assert.Equal(t, tree.Root(), loaded.Root())
+	v, ok := loaded.Get("42")
+	assert.True(t, ok)
+	assert.Equal(t, "42", v)
+
+	stored.Root.Next[0], stored.Root.Next[1] = stored.Root.Next[1], stored.Root.Next[0]
+	_, err = LoadSparseMerkleTree(stored)
+	assert.NotNil(t, err)
+
+	stored.Root.Next[0], stored.Root.Next[1] = stored.Root.Next[1], stored.Root.Next[0]
+	opened, err := OpenSparseMerkleTree(stored)
+	assert.Nil(t, err)
+	assert.Equal(t, tree.Root(), opened.Root())
+	v, ok = opened.Get("42")
+	assert.True(t, ok)
+	assert.Equal(t, "42", v)
+
+	stored, _ = NewSparseMerkleTree().ToTree()
+	loaded, err = LoadSparseMerkleTree(stored)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, loaded.Count())
+
+	// an empty root only has the default hash
+	stored.Root.Hash = hash
+	_, err = LoadSparseMerkleTree(stored)
+	assert.NotNil(t, err)
+}
+
+func TestStateTree(t *testing.T) {
+	for _, kind := range []string{StatePatricia, StateSparse} {
+		s, err := NewStateTree(kind)
+		assert.Nil(t, err)
+		s.UpsertFloat("acc1", 10)
+		batch := s.NewBatch()
+		batch.PutFloat("acc2", 20)
+		assert.Nil(t, batch.Commit())
+
+		tree, err := s.ToTree()
+		assert.Nil(t, err)
+		assert.Equal(t, kind, tree.Kind)
+		loaded, err := LoadStateTree(tree)
+		assert.Nil(t, err)
+		f, ok := loaded.GetFloat("acc2")
+		assert.True(t, ok)
+		assert.Equal(t, 20.0, f)
+		h1, _ := s.RootHash()
+		h2, _ := loaded.RootHash()
+		assert.Equal(t, h1, h2)
+	}
+
+	_, err := NewStateTree("btree")
+	assert.NotNil(t, err)
+}
+
+func BenchmarkSparseUpsert(b *testing.B) {
+	tree := NewSparseMerkleTree()
+	for i := 0; i < b.N; i++ {
+		tree.Upsert(fmt.Sprint(i), "val")
+	}
+}
@@ -0,0 +1,75 @@
+package merkle
+
+// StateTree is the authenticated kv store of the state committed by the blocks, e.g. the balances
+// Both the patricia trie and the sparse merkle tree implement it, and pb.Tree.Kind records which one a stored tree is.
+import (
+	"errors"
+	"fmt"
+	"proto"
+)
+
+// ErrNotFound is returned by the lookups of a missing key
+var ErrNotFound = errors.New("Key not found")
+
+const (
+	StatePatricia = ""
+	StateSparse   = "smt"
+)
+
+type StateTree interface {
+	Get(key string) (string, bool)
+	Lookup(key string) (string, error)
+	GetFloat(key string) (float64, bool)
+	Upsert(key, val string) error
+	UpsertFloat(key string, val float64) error
+	Delete(key string) error
+	NewBatch() *Batch
+	ForEach(fn func(key, val string) error) error
+	RootHash() (string, error)
+	ToTree() (*pb.Tree, error) // the stored form of the tree, e.g. for pb.Block.Balances
+}
+
+// NewStateTree creates an empty state tree of the kind, the patricia trie storing its keys and values as is
+func NewStateTree(kind string) (StateTree, error) {
+	switch kind {
+	case StatePatricia:
+		return NewPatriciaTrieWithCodec(Identity, Identity), nil
+	case StateSparse:
+		return NewSparseMerkleTree(), nil
+	}
+
+	return nil, fmt.Errorf("Unknown state tree %s", kind)
+}
+
+// LoadStateTree wraps a stored tree into the state tree of its kind
+func LoadStateTree(tree *pb.Tree) (StateTree, error) {
+	switch tree.Kind {
+	case StatePatricia:
+		return LoadPatriciaTrie(tree), nil
+	case StateSparse:
+		return LoadSparseMerkleTree(tree)
+	}
+
+	return nil, fmt.Errorf("Unknown state tree %s", tree.Kind)
+}
+
+// OpenStateTree wraps a stored tree checked before, e.g. the balances of a block of the chain, without rehashing it
+func OpenStateTree(tree *pb.Tree) (StateTree, error) {
+	switch tree.Kind {
+	case StatePatricia:
+		return LoadPatriciaTrie(tree), nil
+	case StateSparse:
+		return OpenSparseMerkleTree(tree)
+	}
+
+	return nil, fmt.Errorf("Unknown state tree %s", tree.Kind)
+}
+
+// ToTree returns the underlying pb.Tree of the trie, which is shared with the trie. The orphans of the updates are
+// swept first, so that Ht only holds the nodes reachable from the root.
+func (t *PatriciaTrie) ToTree() (*pb.Tree, error) {
+	t.Lock()
+	defer t.Unlock()
+	t.sweep()
+	return &t.Tree, nil
+}
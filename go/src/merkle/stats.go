@@ -0,0 +1,172 @@
+package merkle
+
+// Health inspection of the patricia trie
+// Stats describes the shape of the trie, e.g. to tune BatchSize, and Verify rehashes every node to catch corruption.
+import (
+	"proto"
+	"utils"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+type TrieStats struct {
+	Nodes        int         // nodes reachable from the root, counted once per path leading to them
+	Depths       map[int]int // number of nodes at each depth, in edges from the root
+	MaxDepth     int
+	Branches     int     // nodes with children by nibble
+	EncodedPaths int     // nodes with children by encoded path
+	Leaves       int     // nodes without children
+	AvgFanOut    float64 // average number of children of the nodes that have some
+	Bytes        int     // encoded size of the reachable nodes
+	AvgNodeBytes float64
+	Values       int // number of kv pairs
+	ValueBytes   int // size of the stored values, i.e. after the value codec
+	MinValue     int
+	MaxValue     int
+	AvgValue     float64
+	HtEntries    int // entries of Ht
+	Orphans      int // entries of Ht unreachable from the root
+}
+
+// Mismatch is an entry of Ht, or the root, whose node doesn't hash to its key
+type Mismatch struct {
+	Key    string // key of the entry in Ht, the hash of the root for the root
+	Hash   string // hash recorded in the node
+	Actual string // hash of the content of the node
+}
+
+// VerifyReport lists the problems found by Verify
+type VerifyReport struct {
+	Checked    int        // number of nodes rehashed
+	Mismatches []Mismatch // nodes whose content changed
+	Missing    []string   // hashes referred to by a node but missing from Ht
+}
+
+// OK tells if no problem was found
+func (r *VerifyReport) OK() bool {
+	return len(r.Mismatches) == 0 && len(r.Missing) == 0
+}
+
+// Stats walks the trie from the root and reports its shape
+func (t *PatriciaTrie) Stats() *TrieStats {
+	t.RLock()
+	defer t.RUnlock()
+	s := &TrieStats{
+		Depths:    make(map[int]int),
+		HtEntries: len(t.Ht),
+	}
+
+	reachable := make(map[string]bool)
+	edges := 0
+	var visit func(n *pb.Node, depth int)
+	visit = func(n *pb.Node, depth int) {
+		reachable[n.Hash] = true
+		s.Nodes++
+		s.Depths[depth]++
+		if depth > s.MaxDepth {
+			s.MaxDepth = depth
+		}
+		s.Bytes += proto.Size(n)
+
+		if len(n.Val) > 0 {
+			size := len(n.Val)
+			if s.Values == 0 || size < s.MinValue {
+				s.MinValue = size
+			}
+			if size > s.MaxValue {
+				s.MaxValue = size
+			}
+			s.Values++
+			s.ValueBytes += size
+		}
+
+		fanOut := 0
+		for _, nextHash := range n.Next {
+			if next, ok := t.Ht[nextHash]; ok && len(nextHash) > 0 {
+				fanOut++
+				visit(next, depth+1)
+			}
+		}
+		if fanOut > 0 {
+			s.Branches++
+		}
+		if len(n.EncodedPaths) > 0 {
+			s.EncodedPaths++
+		}
+		for _, nextHash := range n.EncodedPaths {
+			if next, ok := t.Ht[nextHash]; ok {
+				fanOut++
+				visit(next, depth+1)
+			}
+		}
+
+		if fanOut == 0 {
+			s.Leaves++
+		}
+		edges += fanOut
+	}
+	visit(t.Root, 0)
+
+	if inner := s.Nodes - s.Leaves; inner > 0 {
+		s.AvgFanOut = float64(edges) / float64(inner)
+	}
+	s.AvgNodeBytes = float64(s.Bytes) / float64(s.Nodes)
+	if s.Values > 0 {
+		s.AvgValue = float64(s.ValueBytes) / float64(s.Values)
+	}
+	for hash := range t.Ht {
+		if !reachable[hash] {
+			s.Orphans++
+		}
+	}
+
+	return s
+}
+
+// Verify rehashes the root and every node of Ht, reachable or not, and checks that the children of the reachable ones
+// exist. The walks start from the root, so it must also be the node stored under its hash, if any.
+func (t *PatriciaTrie) Verify() *VerifyReport {
+	t.RLock()
+	defer t.RUnlock()
+	r := &VerifyReport{}
+	for key, n := range t.Ht {
+		r.Checked++
+		actual, err := utils.GetHash(n)
+		// an empty node has no hash of its own, which is only valid for the root of an empty trie
+		if err == nil && len(actual) == 0 && isEmptyRoot(key) && isEmptyRoot(n.Hash) {
+			continue
+		}
+		if err != nil || actual != key || n.Hash != key {
+			r.Mismatches = append(r.Mismatches, Mismatch{Key: key, Hash: n.Hash, Actual: actual})
+		}
+	}
+
+	if n, ok := t.Ht[t.Root.Hash]; !ok || n != t.Root {
+		r.Checked++
+		actual, err := utils.GetHash(t.Root)
+		empty := err == nil && len(actual) == 0 && isEmptyRoot(t.Root.Hash)
+		if err != nil || (actual != t.Root.Hash && !empty) || (ok && !proto.Equal(n, t.Root)) {
+			r.Mismatches = append(r.Mismatches, Mismatch{Key: t.Root.Hash, Hash: t.Root.Hash, Actual: actual})
+		}
+	}
+
+	marked := make(map[string]bool)
+	var check func(n *pb.Node)
+	check = func(n *pb.Node) {
+		for _, next := range children(n) {
+			if marked[next] {
+				continue
+			}
+
+			marked[next] = true
+			if c, ok := t.Ht[next]; ok {
+				check(c)
+			} else {
+				r.Missing = append(r.Missing, next)
+			}
+		}
+	}
+	check(t.Root)
+
+	return r
+}
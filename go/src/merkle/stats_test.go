@@ -0,0 +1,130 @@
+package merkle
+
+import (
+	"fmt"
+	"proto"
+	"testing"
+	"utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	trie := NewPatriciaTrieWithCodec(Identity, Identity)
+	s := trie.Stats()
+	assert.Equal(t, 1, s.Nodes)
+	assert.Equal(t, 0, s.Values)
+	assert.Equal(t, 0, s.Orphans)
+
+	trie.Upsert("ab", "1")
+	trie.Upsert("ac", "22")
+	trie.Upsert("b", "333")
+	s = trie.Stats()
+	assert.Equal(t, 3, s.Values)
+	assert.Equal(t, 6, s.ValueBytes)
+	assert.Equal(t, 1, s.MinValue)
+	assert.Equal(t, 3, s.MaxValue)
+	assert.Equal(t, 2.0, s.AvgValue)
+	assert.Equal(t, 3, s.Leaves)
+	assert.Equal(t, 4, s.MaxDepth)
+	assert.Equal(t, 1, s.Depths[0])
+	assert.Equal(t, 0, s.EncodedPaths)
+	assert.True(t, s.Bytes > 0)
+
+	// the updates leave the previous versions of the nodes behind until the next sweep
+	assert.True(t, s.Orphans > 0)
+	assert.Equal(t, s.HtEntries, len(trie.Ht))
+	trie.sweep()
+	assert.Equal(t, 0, trie.Stats().Orphans)
+
+	trie.compress()
+	s = trie.Stats()
+	assert.True(t, s.EncodedPaths > 0)
+	assert.True(t, s.MaxDepth < 4)
+	assert.Equal(t, 3, s.Values)
+}
+
+func TestStatsFanOut(t *testing.T) {
+	trie := NewPatriciaTrieWithCodec(Identity, Identity)
+	for i := 0; i < 16; i++ {
+		trie.Upsert(string([]byte{byte(i << 4)}), "v")
+	}
+
+	// the root has 16 children, each of them a single child
+	s := trie.Stats()
+	assert.Equal(t, 33, s.Nodes)
+	assert.Equal(t, 16, s.Leaves)
+	assert.Equal(t, 17, s.Branches)
+	assert.Equal(t, 32.0/17.0, s.AvgFanOut)
+	assert.Equal(t, map[int]int{0: 1, 1: 16, 2: 16}, s.Depths)
+}
+
+func TestVerify(t *testing.T) {
+	trie := NewPatriciaTrie()
+	assert.True(t, trie.Verify().OK())
+	for i := 0; i < 100; i++ {
+		trie.Upsert(fmt.Sprintf("key%d", i), fmt.Sprintf("val%d", i))
+	}
+	r := trie.Verify()
+	assert.True(t, r.OK())
+	assert.Equal(t, len(trie.Ht), r.Checked)
+
+	// corrupt a leaf in place
+	var leaf *pb.Node
+	for _, n := range trie.Ht {
+		if len(n.Val) > 0 && n != trie.Root {
+			leaf = n
+			break
+		}
+	}
+	leaf.Val = "tampered"
+	r = trie.Verify()
+	assert.False(t, r.OK())
+	assert.Equal(t, 1, len(r.Mismatches))
+	assert.Equal(t, leaf.Hash, r.Mismatches[0].Key)
+	assert.NotEqual(t, leaf.Hash, r.Mismatches[0].Actual)
+
+	// and drop a reachable node
+	delete(trie.Ht, leaf.Hash)
+	r = trie.Verify()
+	assert.Equal(t, 0, len(r.Mismatches))
+	assert.Equal(t, []string{leaf.Hash}, r.Missing)
+}
+
+func TestVerifyRoot(t *testing.T) {
+	trie := NewPatriciaTrieWithCodec(Identity, Identity)
+	for i := 0; i < 100; i++ {
+		trie.UpsertFloat(fmt.Sprintf("key%d", i), float64(i))
+	}
+	trie.compress()
+	assert.True(t, trie.Verify().OK())
+
+	// a root loaded apart from Ht, e.g. from a block, is checked as well
+	trie.Root = copyNode(trie.Root)
+	r := trie.Verify()
+	assert.True(t, r.OK())
+	assert.Equal(t, len(trie.Ht)+1, r.Checked)
+
+	// a shortcut to a forged leaf added to the root, its hash left unchanged
+	leaf := &pb.Node{Val: "1000000.000000", EncodedPaths: make(map[string]string)}
+	hash, err := utils.GetHash(leaf)
+	assert.Nil(t, err)
+	leaf.Hash = hash
+	trie.Ht[hash] = leaf
+	trie.Root.EncodedPaths[string(utils.ToNibbles("mallory"))] = hash
+	f, ok := trie.GetFloat("mallory")
+	assert.True(t, ok)
+	assert.Equal(t, 1000000.0, f)
+
+	r = trie.Verify()
+	assert.False(t, r.OK())
+	assert.Equal(t, []Mismatch{{Key: trie.Root.Hash, Hash: trie.Root.Hash, Actual: r.Mismatches[0].Actual}},
+		r.Mismatches)
+	assert.NotEqual(t, trie.Root.Hash, r.Mismatches[0].Actual)
+
+	// or the same in place, the root still being the node of Ht
+	trie.Root = trie.Ht[trie.Root.Hash]
+	assert.True(t, trie.Verify().OK())
+	trie.Root.EncodedPaths[string(utils.ToNibbles("mallory"))] = hash
+	assert.False(t, trie.Verify().OK())
+}
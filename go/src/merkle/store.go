@@ -0,0 +1,173 @@
+package merkle
+
+// NodeStore is a content-addressed store of trie nodes shared across the state roots of many tries
+// Committing a trie only writes the nodes that aren't already in the store, so consecutive roots share
+// their unchanged branches. Nodes that are no longer reachable from a kept root are deleted by a Pruner.
+import (
+	"fmt"
+	"proto"
+	"sync"
+)
+
+type NodeStore struct {
+	sync.RWMutex
+	nodes map[string]*pb.Node
+	trees map[string]*pb.Tree // trie settings of each committed root
+	roots []string            // committed roots, oldest first
+
+	// set while a prune is running, see Pruner.PruneOnline
+	pruning bool
+	fresh   []string
+}
+
+func NewNodeStore() *NodeStore {
+	return &NodeStore{
+		nodes: make(map[string]*pb.Node),
+		trees: make(map[string]*pb.Tree),
+	}
+}
+
+// Len returns the number of nodes in the store
+func (s *NodeStore) Len() int {
+	s.RLock()
+	defer s.RUnlock()
+	return len(s.nodes)
+}
+
+// Roots returns the committed roots, oldest first
+func (s *NodeStore) Roots() []string {
+	s.RLock()
+	defer s.RUnlock()
+	return append([]string(nil), s.roots...)
+}
+
+// Has returns whether the node with the hash is in the store
+func (s *NodeStore) Has(hash string) bool {
+	s.RLock()
+	defer s.RUnlock()
+	_, ok := s.nodes[hash]
+	return ok
+}
+
+// Commit writes all the nodes reachable from the root of the trie to the store and records the root
+// rtype - root hash, error
+func (s *NodeStore) Commit(t *PatriciaTrie) (string, error) {
+	t.RLock()
+	defer t.RUnlock()
+	s.Lock()
+	defer s.Unlock()
+	if err := s.putNode(t.Root.Hash, t.Ht); err != nil {
+		return "", err
+	}
+
+	tree := t.Tree
+	tree.Root, tree.Ht = nil, nil
+	s.trees[t.Root.Hash] = &tree
+	if len(s.roots) == 0 || s.roots[len(s.roots)-1] != t.Root.Hash {
+		s.roots = append(s.roots, t.Root.Hash)
+	}
+	return t.Root.Hash, nil
+}
+
+// putNode copies the node and its missing descendants from ht to the store
+// A node already in the store has all of its descendants in the store too, so its branch is skipped. While an
+// online prune runs, an earlier chunk may already have deleted some descendants of a node it hasn't reached yet,
+// so the branch is walked anyway and the missing nodes are put back.
+func (s *NodeStore) putNode(hash string, ht map[string]*pb.Node) error {
+	_, stored := s.nodes[hash]
+	if stored && !s.pruning {
+		return nil
+	}
+
+	n, ok := ht[hash]
+	if !ok {
+		if stored {
+			return nil
+		}
+		return fmt.Errorf("Node %s is missing from the trie", hash)
+	}
+
+	for _, next := range children(n) {
+		if err := s.putNode(next, ht); err != nil {
+			return err
+		}
+	}
+
+	if !stored {
+		s.nodes[hash] = copyNode(n)
+	}
+	if s.pruning {
+		s.fresh = append(s.fresh, hash)
+	}
+	return nil
+}
+
+// Load rebuilds the trie committed with the root hash
+func (s *NodeStore) Load(root string) (*PatriciaTrie, error) {
+	s.RLock()
+	defer s.RUnlock()
+	tree, ok := s.trees[root]
+	if !ok {
+		return nil, fmt.Errorf("Unknown root %s", root)
+	}
+
+	t := LoadPatriciaTrie(tree)
+	t.Ht = make(map[string]*pb.Node)
+	if err := s.loadNode(root, t.Ht); err != nil {
+		return nil, err
+	}
+
+	t.Root = t.Ht[root]
+	return t, nil
+}
+
+func (s *NodeStore) loadNode(hash string, ht map[string]*pb.Node) error {
+	if _, ok := ht[hash]; ok {
+		return nil
+	}
+
+	n, ok := s.nodes[hash]
+	if !ok {
+		return fmt.Errorf("Node %s is missing from the store", hash)
+	}
+
+	ht[hash] = copyNode(n)
+	for _, next := range children(n) {
+		if err := s.loadNode(next, ht); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// children returns the hashes of the child nodes and encoded path targets of n
+func children(n *pb.Node) []string {
+	var rst []string
+	for _, next := range n.Next {
+		if len(next) > 0 {
+			rst = append(rst, next)
+		}
+	}
+	for _, next := range n.EncodedPaths {
+		rst = append(rst, next)
+	}
+
+	return rst
+}
+
+// copyNode returns a deep copy of n, e.g. the copy of a node about to be updated
+func copyNode(n *pb.Node) *pb.Node {
+	c := &pb.Node{
+		Hash:         n.Hash,
+		Next:         append([]string(nil), n.Next...),
+		Val:          n.Val,
+		Count:        n.Count,
+		EncodedPaths: make(map[string]string, len(n.EncodedPaths)),
+	}
+	for k, v := range n.EncodedPaths {
+		c.EncodedPaths[k] = v
+	}
+
+	return c
+}
@@ -0,0 +1,62 @@
+package merkle
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreCommitAndLoad(t *testing.T) {
+	s := NewNodeStore()
+	trie := NewPatriciaTrie()
+	trie.Upsert("key1", "val1")
+	trie.Upsert("key2", "val2")
+	root, err := s.Commit(trie)
+	assert.Nil(t, err)
+	assert.Equal(t, trie.Root.Hash, root)
+	assert.Equal(t, []string{root}, s.Roots())
+
+	loaded, err := s.Load(root)
+	assert.Nil(t, err)
+	rst, _ := loaded.Get("key1")
+	assert.Equal(t, "val1", rst)
+	rst, _ = loaded.Get("key2")
+	assert.Equal(t, "val2", rst)
+}
+
+func TestStoreKeepsOldRoots(t *testing.T) {
+	s := NewNodeStore()
+	trie := NewPatriciaTrie()
+	trie.Upsert("key1", "val1")
+	root1, _ := s.Commit(trie)
+	trie.Upsert("key1", "val2")
+	root2, _ := s.Commit(trie)
+	assert.NotEqual(t, root1, root2)
+
+	// updating the trie in place must not affect the committed nodes
+	old, err := s.Load(root1)
+	assert.Nil(t, err)
+	rst, _ := old.Get("key1")
+	assert.Equal(t, "val1", rst)
+	cur, err := s.Load(root2)
+	assert.Nil(t, err)
+	rst, _ = cur.Get("key1")
+	assert.Equal(t, "val2", rst)
+}
+
+func TestStoreSharesNodes(t *testing.T) {
+	s := NewNodeStore()
+	trie := NewPatriciaTrie()
+	trie.Upsert("key1", "val1")
+	s.Commit(trie)
+	n := s.Len()
+	s.Commit(trie)
+	assert.Equal(t, n, s.Len())
+	assert.Equal(t, 1, len(s.Roots()))
+}
+
+func TestStoreLoadUnknownRoot(t *testing.T) {
+	s := NewNodeStore()
+	_, err := s.Load("nope")
+	assert.NotNil(t, err)
+}
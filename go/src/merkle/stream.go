@@ -0,0 +1,226 @@
+package merkle
+
+// Streaming export/import of a trie for backups and state transfer
+// The format is the magic bytes, a length-prefixed pb.TreeHeader, then the node records in chunks.
+// Each chunk is a uvarint record count followed by that many length-prefixed pb.Node records, and an empty chunk
+// ends the stream. Nodes are written children first, so the importer verifies every node hash and every child
+// reference as it reads, and the root comes last.
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"proto"
+	"utils"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+const (
+	streamMagic     = "HWTRIE\x01"
+	streamChunkSize = 256     // node records per chunk
+	maxRecordSize   = 1 << 26 // upper bound of a header or node record
+)
+
+// ExportTo writes the nodes reachable from the root to w in the streaming format
+func (t *PatriciaTrie) ExportTo(w io.Writer) error {
+	t.RLock()
+	defer t.RUnlock()
+	var order []*pb.Node
+	if err := t.postOrder(t.Root.Hash, make(map[string]bool), &order); err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(streamMagic); err != nil {
+		return err
+	}
+
+	header := &pb.TreeHeader{
+		RootHash:             t.Root.Hash,
+		LastRadixCompression: t.LastRadixCompression,
+		BatchSize:            t.BatchSize,
+		Zipped:               t.Zipped,
+		KeyCodec:             t.KeyCodec,
+		ValCodec:             t.ValCodec,
+		Dict:                 t.Dict,
+		ValDict:              t.ValDict,
+		NodeCount:            int64(len(order)),
+	}
+	if err := writeRecord(bw, header); err != nil {
+		return err
+	}
+
+	for len(order) > 0 {
+		n := streamChunkSize
+		if n > len(order) {
+			n = len(order)
+		}
+
+		writeUvarint(bw, uint64(n))
+		for _, node := range order[:n] {
+			if err := writeRecord(bw, node); err != nil {
+				return err
+			}
+		}
+		order = order[n:]
+	}
+
+	writeUvarint(bw, 0)
+	return bw.Flush()
+}
+
+// postOrder appends the nodes under hash to order, children first
+func (t *PatriciaTrie) postOrder(hash string, seen map[string]bool, order *[]*pb.Node) error {
+	if seen[hash] {
+		return nil
+	}
+
+	n, ok := t.Ht[hash]
+	if !ok {
+		return fmt.Errorf("Node %s is missing from the trie", hash)
+	}
+
+	seen[hash] = true
+	for _, next := range children(n) {
+		if err := t.postOrder(next, seen, order); err != nil {
+			return err
+		}
+	}
+
+	*order = append(*order, n)
+	return nil
+}
+
+// ImportFrom replaces the content of the trie with the trie read from r
+// Every node is rehashed and checked against its recorded hash, and every child must have been read before its
+// parent. The trie is left untouched if the stream is invalid.
+func (t *PatriciaTrie) ImportFrom(r io.Reader) error {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(streamMagic))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return err
+	}
+	if string(magic) != streamMagic {
+		return fmt.Errorf("Not a trie stream")
+	}
+
+	var header pb.TreeHeader
+	if err := readRecord(br, &header); err != nil {
+		return err
+	}
+
+	ht := make(map[string]*pb.Node)
+	var last *pb.Node
+	var count int64
+	for {
+		n, err := binary.ReadUvarint(br)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+
+		for ; n > 0; n-- {
+			node := &pb.Node{}
+			if err := readRecord(br, node); err != nil {
+				return err
+			}
+			// the root comes last
+			if err := verifyNode(node, ht, count == header.NodeCount-1); err != nil {
+				return err
+			}
+
+			if node.EncodedPaths == nil {
+				node.EncodedPaths = make(map[string]string)
+			}
+			ht[node.Hash] = node
+			last = node
+			count++
+		}
+	}
+
+	if count != header.NodeCount {
+		return fmt.Errorf("Expected %d nodes, read %d", header.NodeCount, count)
+	}
+	if last == nil || last.Hash != header.RootHash {
+		return fmt.Errorf("Root %s is not the last node of the stream", header.RootHash)
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	t.Root = last
+	t.Ht = ht
+	t.LastRadixCompression = header.LastRadixCompression
+	t.BatchSize = header.BatchSize
+	t.Zipped = header.Zipped
+	t.KeyCodec = header.KeyCodec
+	t.ValCodec = header.ValCodec
+	t.Dict = header.Dict
+	t.ValDict = header.ValDict
+	t.orphans = 0
+	t.resetView()
+	return nil
+}
+
+// verifyNode checks the hash of the node and that all of its children are known
+// An empty node has no hash of its own, which is only valid for the root of an empty trie, without children.
+func verifyNode(n *pb.Node, ht map[string]*pb.Node, root bool) error {
+	hash, err := utils.GetHash(n)
+	if err != nil {
+		return err
+	}
+
+	if len(hash) == 0 {
+		if !root || len(children(n)) > 0 {
+			return fmt.Errorf("Empty node %s that isn't the root of an empty trie", n.Hash)
+		}
+		if n.Hash != "" && n.Hash != "0" {
+			return fmt.Errorf("Empty node with hash %s", n.Hash)
+		}
+	} else if hash != n.Hash {
+		return fmt.Errorf("Hash mismatch for node %s, got %s", n.Hash, hash)
+	}
+
+	for _, next := range children(n) {
+		if _, ok := ht[next]; !ok {
+			return fmt.Errorf("Node %s refers to unknown node %s", n.Hash, next)
+		}
+	}
+
+	return nil
+}
+
+func writeUvarint(w *bufio.Writer, v uint64) {
+	buf := make([]byte, binary.MaxVarintLen64)
+	w.Write(buf[:binary.PutUvarint(buf, v)])
+}
+
+func writeRecord(w *bufio.Writer, msg proto.Message) error {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	writeUvarint(w, uint64(len(data)))
+	_, err = w.Write(data)
+	return err
+}
+
+func readRecord(r *bufio.Reader, msg proto.Message) error {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return err
+	}
+	if size > maxRecordSize {
+		return fmt.Errorf("Record of %d bytes is too large", size)
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return err
+	}
+
+	return proto.Unmarshal(data, msg)
+}
@@ -0,0 +1,108 @@
+package merkle
+
+import (
+	"bufio"
+	"bytes"
+	"proto"
+	"testing"
+	"utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportImport(t *testing.T) {
+	trie := NewPatriciaTrieWithCodec(Identity, Snappy)
+	trie.BatchSize = 100
+	keys := map[string]string{}
+	for i := 0; i < 1000; i++ {
+		k, v := utils.RandStringBytesMaskImprSrc(32), utils.RandStringBytesMaskImprSrc(32)
+		trie.Upsert(k, v)
+		keys[k] = v
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, trie.ExportTo(&buf))
+
+	trie2 := NewPatriciaTrie()
+	assert.Nil(t, trie2.ImportFrom(&buf))
+	assert.Equal(t, trie.Root.Hash, trie2.Root.Hash)
+	assert.Equal(t, CodecSnappy, trie2.ValCodec)
+	assert.Equal(t, int64(100), trie2.BatchSize)
+	for k, v := range keys {
+		rst, _ := trie2.Get(k)
+		assert.Equal(t, v, rst)
+	}
+}
+
+func TestExportImportEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, NewPatriciaTrie().ExportTo(&buf))
+	trie := NewPatriciaTrie()
+	assert.Nil(t, trie.ImportFrom(&buf))
+	assert.Equal(t, "0", trie.Root.Hash)
+}
+
+func TestImportDetectsTampering(t *testing.T) {
+	trie := NewPatriciaTrie()
+	trie.Upsert("key1", "value1")
+	trie.Upsert("key2", "value2")
+	var buf bytes.Buffer
+	assert.Nil(t, trie.ExportTo(&buf))
+
+	bs := bytes.Replace(buf.Bytes(), []byte("value1"), []byte("value9"), 1)
+	trie2 := NewPatriciaTrie()
+	assert.NotNil(t, trie2.ImportFrom(bytes.NewReader(bs)))
+	assert.Equal(t, "0", trie2.Root.Hash)
+}
+
+func TestImportEmptyNode(t *testing.T) {
+	stream := func(nodes ...*pb.Node) *bytes.Buffer {
+		var buf bytes.Buffer
+		w := bufio.NewWriter(&buf)
+		w.WriteString(streamMagic)
+		writeRecord(w, &pb.TreeHeader{RootHash: nodes[len(nodes)-1].Hash, NodeCount: int64(len(nodes))})
+		writeUvarint(w, uint64(len(nodes)))
+		for _, n := range nodes {
+			writeRecord(w, n)
+		}
+		writeUvarint(w, 0)
+		w.Flush()
+		return &buf
+	}
+	root := &pb.Node{Val: "value"}
+	root.Hash, _ = utils.GetHash(root)
+	assert.Nil(t, NewPatriciaTrie().ImportFrom(stream(root)))
+	assert.Nil(t, NewPatriciaTrie().ImportFrom(stream(&pb.Node{Hash: "0"})))
+
+	// only the root of an empty trie may be empty
+	assert.NotNil(t, NewPatriciaTrie().ImportFrom(stream(&pb.Node{Hash: "0"}, root)))
+	empty := &pb.Node{Hash: "0", EncodedPaths: map[string]string{"a": root.Hash}}
+	assert.NotNil(t, NewPatriciaTrie().ImportFrom(stream(root, empty)))
+}
+
+func TestImportTruncated(t *testing.T) {
+	trie := NewPatriciaTrie()
+	trie.Upsert("key1", "value1")
+	var buf bytes.Buffer
+	assert.Nil(t, trie.ExportTo(&buf))
+
+	trie2 := NewPatriciaTrie()
+	assert.NotNil(t, trie2.ImportFrom(bytes.NewReader(buf.Bytes()[:buf.Len()-3])))
+	assert.NotNil(t, trie2.ImportFrom(bytes.NewReader([]byte("garbage"))))
+}
+
+func TestNodeHashesVerifyAfterCompress(t *testing.T) {
+	trie := NewPatriciaTrie()
+	trie.Upsert("ka1", "val1")
+	trie.Upsert("ka3", "val3")
+	trie.compress()
+	// Ht may still hold the orphans of the updates, only the reachable nodes matter
+	trie.walk(trie.Root, nil, func(path []byte, n *pb.Node) error {
+		if n != trie.Root {
+			hash, err := utils.GetHash(n)
+			assert.Nil(t, err)
+			assert.Equal(t, n.Hash, hash)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,159 @@
+package merkle
+
+// Typed values on top of a state tree
+// A TypedTrie converts the values to the strings stored in the tree with a ValueCodec, so callers deal with
+// e.g. *pb.Transaction or float64 balances, and get the decoding errors instead of a missing value.
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// ValueCodec converts the values of a TypedTrie to and from their stored form
+type ValueCodec[V any] interface {
+	Marshal(v V) (string, error)
+	Unmarshal(s string) (V, error)
+}
+
+type TypedTrie[V any] struct {
+	Tree  StateTree
+	Codec ValueCodec[V]
+}
+
+// TypedBatch is a Batch of typed values
+type TypedBatch[V any] struct {
+	b     *Batch
+	codec ValueCodec[V]
+}
+
+func NewTypedTrie[V any](tree StateTree, codec ValueCodec[V]) *TypedTrie[V] {
+	return &TypedTrie[V]{Tree: tree, Codec: codec}
+}
+
+// Get returns the value to the key, ErrNotFound if the key is missing or the error decoding the value
+func (t *TypedTrie[V]) Get(key string) (V, error) {
+	s, err := t.Tree.Lookup(key)
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+
+	return t.Codec.Unmarshal(s)
+}
+
+// Upsert will update or add a kv pair to the trie
+func (t *TypedTrie[V]) Upsert(key string, v V) error {
+	s, err := t.Codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if len(s) == 0 {
+		return fmt.Errorf("Value of %s is stored empty, which is a delete", key)
+	}
+
+	return t.Tree.Upsert(key, s)
+}
+
+// Delete will delete the key
+func (t *TypedTrie[V]) Delete(key string) error {
+	return t.Tree.Delete(key)
+}
+
+// ForEach calls fn with every kv pair of the trie. It stops at the first error of fn or of the codec.
+func (t *TypedTrie[V]) ForEach(fn func(key string, v V) error) error {
+	return t.Tree.ForEach(func(key, s string) error {
+		v, err := t.Codec.Unmarshal(s)
+		if err != nil {
+			return err
+		}
+
+		return fn(key, v)
+	})
+}
+
+// NewBatch creates an empty write batch for the trie
+func (t *TypedTrie[V]) NewBatch() *TypedBatch[V] {
+	return &TypedBatch[V]{b: t.Tree.NewBatch(), codec: t.Codec}
+}
+
+// Put queues an update or add of a kv pair
+func (b *TypedBatch[V]) Put(key string, v V) error {
+	s, err := b.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if len(s) == 0 {
+		return fmt.Errorf("Value of %s is stored empty, which is a delete", key)
+	}
+
+	b.b.Put(key, s)
+	return nil
+}
+
+// Delete queues the deletion of a key
+func (b *TypedBatch[V]) Delete(key string) {
+	b.b.Delete(key)
+}
+
+// Len returns the number of queued ops
+func (b *TypedBatch[V]) Len() int {
+	return b.b.Len()
+}
+
+// Commit applies all the queued ops to the trie atomically
+func (b *TypedBatch[V]) Commit() error {
+	return b.b.Commit()
+}
+
+// StringCodec stores strings as is
+type StringCodec struct{}
+
+func (StringCodec) Marshal(v string) (string, error)   { return v, nil }
+func (StringCodec) Unmarshal(s string) (string, error) { return s, nil }
+
+// FloatCodec stores floats the same way as UpsertFloat
+type FloatCodec struct{}
+
+func (FloatCodec) Marshal(v float64) (string, error) {
+	return fmt.Sprintf("%f", v), nil
+}
+
+func (FloatCodec) Unmarshal(s string) (float64, error) {
+	return strconv.ParseFloat(s, 64)
+}
+
+// ProtoCodec stores protobuf messages, New returns the empty message to unmarshal into
+type ProtoCodec[M proto.Message] struct {
+	New func() M
+}
+
+func NewProtoCodec[M proto.Message](newMsg func() M) ProtoCodec[M] {
+	return ProtoCodec[M]{New: newMsg}
+}
+
+func (c ProtoCodec[M]) Marshal(v M) (string, error) {
+	data, err := proto.Marshal(v)
+	return string(data), err
+}
+
+func (c ProtoCodec[M]) Unmarshal(s string) (M, error) {
+	m := c.New()
+	err := proto.Unmarshal([]byte(s), m)
+	return m, err
+}
+
+// JSONCodec stores any JSON serializable value, e.g. an account record
+type JSONCodec[V any] struct{}
+
+func (JSONCodec[V]) Marshal(v V) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+func (JSONCodec[V]) Unmarshal(s string) (V, error) {
+	var v V
+	err := json.Unmarshal([]byte(s), &v)
+	return v, err
+}
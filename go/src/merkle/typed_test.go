@@ -0,0 +1,80 @@
+package merkle
+
+import (
+	"proto"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type account struct {
+	Balance float64
+	Nonce   int
+}
+
+func TestTypedTrieProto(t *testing.T) {
+	txs := NewTypedTrie[*pb.Transaction](NewPatriciaTrieWithCodec(Identity, Snappy),
+		NewProtoCodec(func() *pb.Transaction { return &pb.Transaction{} }))
+	assert.Nil(t, txs.Upsert("tx1", &pb.Transaction{Id: "tx1", Val: 10}))
+	tx, err := txs.Get("tx1")
+	assert.Nil(t, err)
+	assert.Equal(t, "tx1", tx.Id)
+	assert.Equal(t, 10.0, tx.Val)
+
+	_, err = txs.Get("tx2")
+	assert.Equal(t, ErrNotFound, err)
+
+	// a value that isn't a transaction is an error, not a missing key
+	txs.Tree.Upsert("bad", "\xff\xff")
+	_, err = txs.Get("bad")
+	assert.NotNil(t, err)
+	assert.NotEqual(t, ErrNotFound, err)
+
+	assert.NotNil(t, txs.Upsert("empty", &pb.Transaction{}))
+}
+
+func TestTypedTrieJSON(t *testing.T) {
+	for _, kind := range []string{StatePatricia, StateSparse} {
+		tree, err := NewStateTree(kind)
+		assert.Nil(t, err)
+		accs := NewTypedTrie[account](tree, JSONCodec[account]{})
+		batch := accs.NewBatch()
+		assert.Nil(t, batch.Put("alice", account{Balance: 1.5, Nonce: 1}))
+		assert.Nil(t, batch.Put("bob", account{Balance: 2}))
+		assert.Equal(t, 2, batch.Len())
+		assert.Nil(t, batch.Commit())
+
+		acc, err := accs.Get("alice")
+		assert.Nil(t, err)
+		assert.Equal(t, account{Balance: 1.5, Nonce: 1}, acc)
+
+		total := 0.0
+		assert.Nil(t, accs.ForEach(func(key string, acc account) error {
+			total += acc.Balance
+			return nil
+		}))
+		assert.Equal(t, 3.5, total)
+
+		assert.Nil(t, accs.Delete("bob"))
+		_, err = accs.Get("bob")
+		assert.Equal(t, ErrNotFound, err)
+	}
+}
+
+func TestTypedTrieFloat(t *testing.T) {
+	trie := NewPatriciaTrie()
+	trie.UpsertFloat("acc", 12.5)
+	bals := NewTypedTrie[float64](trie, FloatCodec{})
+	v, err := bals.Get("acc")
+	assert.Nil(t, err)
+	assert.Equal(t, 12.5, v)
+
+	trie.Upsert("nan", "not a float")
+	_, err = bals.Get("nan")
+	assert.NotNil(t, err)
+
+	strs := NewTypedTrie[string](trie, StringCodec{})
+	s, err := strs.Get("acc")
+	assert.Nil(t, err)
+	assert.Equal(t, "12.500000", s)
+}
@@ -0,0 +1,116 @@
+package merkle
+
+// Lock-free reads of the patricia trie
+// The writer never modifies a node reachable from a published root: it works on copies and publishes the new root
+// once its update is done. Get loads the last published view and walks it through a concurrent index of Ht.
+// The nodes replaced by the copies are orphans, kept until a sweep deletes everything unreachable from the root.
+// A reader still on an older view may then miss a node, in which case it retries on the latest view.
+import (
+	"proto"
+	"sync"
+)
+
+const maxViewRetries = 3
+
+// trieView is a published version of the trie, with the codecs it was written with
+type trieView struct {
+	root  *pb.Node
+	nodes *sync.Map
+	keys  Codec
+	vals  Codec
+	err   error // of resolving the codecs
+}
+
+func (v *trieView) lookup(hash string) (*pb.Node, bool) {
+	n, ok := v.nodes.Load(hash)
+	if !ok {
+		return nil, false
+	}
+
+	return n.(*pb.Node), true
+}
+
+func (t *PatriciaTrie) lookup(hash string) (*pb.Node, bool) {
+	n, ok := t.Ht[hash]
+	return n, ok
+}
+
+// loadView returns the last published view, indexing the trie on the first call
+func (t *PatriciaTrie) loadView() *trieView {
+	if v, _ := t.view.Load().(*trieView); v != nil {
+		return v
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	if t.nodes == nil {
+		t.nodes = &sync.Map{}
+		for hash, n := range t.Ht {
+			t.nodes.Store(hash, n)
+		}
+		t.setView()
+	}
+
+	return t.view.Load().(*trieView)
+}
+
+// setView publishes the root to the readers. The caller must hold the write lock.
+func (t *PatriciaTrie) setView() {
+	if t.nodes == nil {
+		return
+	}
+
+	v := &trieView{root: t.Root, nodes: t.nodes}
+	if v.keys, v.err = t.keyCodec(); v.err == nil {
+		v.vals, v.err = t.valCodec()
+	}
+	t.view.Store(v)
+}
+
+// resetView drops the index after the whole tree was replaced, the next Get indexes it again
+func (t *PatriciaTrie) resetView() {
+	t.nodes = nil
+	t.view.Store((*trieView)(nil))
+}
+
+// publish ends an update: the new root is published, then the trie is compressed or swept when due
+func (t *PatriciaTrie) publish() {
+	t.setView()
+	if int64(len(t.Ht)-t.orphans)-t.LastRadixCompression > t.BatchSize {
+		t.compress()
+	} else if t.orphans > len(t.Ht)/2 {
+		t.sweep()
+	}
+}
+
+// sweep deletes the nodes unreachable from the root
+func (t *PatriciaTrie) sweep() {
+	if _, ok := t.Ht[t.Root.Hash]; !ok {
+		return
+	}
+
+	marked := make(map[string]bool, len(t.Ht)-t.orphans)
+	mark(t.Root.Hash, t.Ht, marked)
+	for hash := range t.Ht {
+		if !marked[hash] {
+			t.deleteNode(hash)
+		}
+	}
+
+	t.orphans = 0
+}
+
+// setNode adds the node to the hash table and to the index of the readers
+func (t *PatriciaTrie) setNode(hash string, n *pb.Node) {
+	t.Ht[hash] = n
+	if t.nodes != nil {
+		t.nodes.Store(hash, n)
+	}
+}
+
+func (t *PatriciaTrie) deleteNode(hash string) {
+	delete(t.Ht, hash)
+	if t.nodes != nil {
+		t.nodes.Delete(hash)
+	}
+}
@@ -0,0 +1,211 @@
+package merkle
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDoesNotBlockOnWriter(t *testing.T) {
+	trie := NewPatriciaTrieWithCodec(Identity, Identity)
+	trie.Upsert("key1", "val1")
+	trie.Get("key1")
+
+	trie.Lock()
+	done := make(chan string)
+	go func() {
+		v, _ := trie.Get("key1")
+		done <- v
+	}()
+
+	select {
+	case v := <-done:
+		assert.Equal(t, "val1", v)
+	case <-time.After(time.Second):
+		t.Fatal("Get is blocked by the write lock")
+	}
+	trie.Unlock()
+}
+
+func TestSharedNodes(t *testing.T) {
+	// the leaves of a1 and b1 have the same content, hence the same hash
+	trie := NewPatriciaTrieWithCodec(Identity, Identity)
+	trie.Upsert("a1", "x")
+	trie.Upsert("b1", "x")
+	trie.Upsert("a1", "y")
+	v, ok := trie.Get("b1")
+	assert.True(t, ok)
+	assert.Equal(t, "x", v)
+
+	trie.Delete("a1")
+	trie.sweep()
+	v, ok = trie.Get("b1")
+	assert.True(t, ok)
+	assert.Equal(t, "x", v)
+}
+
+func TestSweep(t *testing.T) {
+	trie := NewPatriciaTrie()
+	for i := 0; i < 100; i++ {
+		trie.Upsert(fmt.Sprintf("key%d", i), "v1")
+	}
+	old := trie.loadView()
+	for i := 0; i < 100; i++ {
+		trie.Upsert(fmt.Sprintf("key%d", i), "v2")
+	}
+
+	trie.sweep()
+	assert.Equal(t, 0, trie.orphans)
+	marked := make(map[string]bool)
+	mark(trie.Root.Hash, trie.Ht, marked)
+	assert.Equal(t, len(marked), len(trie.Ht))
+
+	// the old view lost some of its nodes, a Get retries on the latest one
+	_, _, err := getWithPath(old.root, []byte{}, old.lookup, nil)
+	assert.Nil(t, err)
+	for i := 0; i < 100; i++ {
+		v, ok := trie.Get(fmt.Sprintf("key%d", i))
+		assert.True(t, ok)
+		assert.Equal(t, "v2", v)
+	}
+}
+
+func TestConcurrentGetUpsert(t *testing.T) {
+	trie := NewPatriciaTrieWithCodec(Identity, Identity)
+	trie.BatchSize = 200
+	for i := 0; i < 500; i++ {
+		trie.Upsert(fmt.Sprintf("key%d", i), "0")
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				// the stable keys are only ever updated, so they are always found
+				key := fmt.Sprintf("key%d", (i*7+r)%500)
+				_, ok := trie.Get(key)
+				assert.True(t, ok, key)
+			}
+		}(r)
+	}
+
+	for round := 1; round <= 5; round++ {
+		for i := 0; i < 500; i += 3 {
+			trie.Upsert(fmt.Sprintf("key%d", i), fmt.Sprint(round))
+			trie.Upsert(fmt.Sprintf("tmp%d", i), "tmp")
+		}
+		batch := trie.NewBatch()
+		for i := 0; i < 500; i += 3 {
+			batch.Delete(fmt.Sprintf("tmp%d", i))
+			batch.Put(fmt.Sprintf("key%d", i+1), fmt.Sprint(round))
+		}
+		assert.Nil(t, batch.Commit())
+	}
+	close(stop)
+	wg.Wait()
+
+	for i := 0; i < 500; i++ {
+		v, ok := trie.Get(fmt.Sprintf("key%d", i))
+		assert.True(t, ok)
+		if i%3 != 2 {
+			assert.Equal(t, "5", v)
+		}
+	}
+	_, ok := trie.Get("tmp0")
+	assert.False(t, ok)
+}
+
+func BenchmarkConcurrentGet(b *testing.B) {
+	trie := NewPatriciaTrie()
+	for i := 0; i < 1000; i++ {
+		trie.Upsert(fmt.Sprintf("key%d", i), "val")
+	}
+
+	stop := make(chan struct{})
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+				trie.Upsert(fmt.Sprintf("key%d", i%1000), fmt.Sprint(i))
+			}
+		}
+	}()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			trie.Get(fmt.Sprintf("key%d", i%1000))
+		}
+	})
+	close(stop)
+}
+
+func TestToTreeSweeps(t *testing.T) {
+	trie := NewPatriciaTrie()
+	for i := 0; i < 4; i++ {
+		trie.Upsert(fmt.Sprintf("key%d", i), fmt.Sprintf("v%d", i))
+	}
+	assert.True(t, trie.Stats().Orphans > 0)
+
+	tree, err := trie.ToTree()
+	assert.Nil(t, err)
+	marked := make(map[string]bool)
+	mark(tree.Root.Hash, tree.Ht, marked)
+	assert.Equal(t, len(marked), len(tree.Ht))
+	v, ok := LoadPatriciaTrie(tree).Get("key3")
+	assert.True(t, ok)
+	assert.Equal(t, "v3", v)
+}
+
+func TestGetWhileDeserializing(t *testing.T) {
+	// the codecs of a lookup are the ones of the version it reads, even while the whole trie is swapped
+	hexed := NewPatriciaTrieWithCodec(Hex, Snappy)
+	zipped := NewPatriciaTrie()
+	for i := 0; i < 50; i++ {
+		hexed.Upsert(fmt.Sprintf("key%d", i), "hex")
+		zipped.Upsert(fmt.Sprintf("key%d", i), "zip")
+	}
+	bss := make([][]byte, 2)
+	bss[0], _ = hexed.Serialize()
+	bss[1], _ = zipped.Serialize()
+
+	trie := NewPatriciaTrie()
+	assert.Nil(t, trie.Deserialize(bss[1]))
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			v, err := trie.Lookup(fmt.Sprintf("key%d", i%50))
+			assert.Nil(t, err)
+			assert.Contains(t, []string{"hex", "zip"}, v)
+		}
+	}()
+
+	for i := 0; i < 200; i++ {
+		assert.Nil(t, trie.Deserialize(bss[i%2]))
+	}
+	close(stop)
+	wg.Wait()
+}
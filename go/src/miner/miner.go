@@ -0,0 +1,155 @@
+package miner
+
+// Background miner, making blocks from the open transactions without anyone asking
+// A Miner runs one loop at a time, started with a Policy and stopped on demand: it waits for the policy to allow the
+// next block, mines it on the chain, and starts over. Stopping cancels the block being mined.
+import (
+	"context"
+	"fmt"
+	"proto"
+	"sync"
+	"time"
+)
+
+// idle is the wait before checking again for open transactions, or retrying after an error
+const idle = time.Second
+
+// Policy decides when the miner makes a block, and what goes in it
+type Policy struct {
+	OnlyWithTxs bool  `json:"onlyWithTxs"` // mine only when transactions are open
+	Interval    int64 `json:"interval"`    // min seconds between the starts of two blocks, back to back if 0
+	MaxTxs      int   `json:"maxTxs"`      // max transactions per block, as many as fit if 0
+}
+
+func (p *Policy) Validate() error {
+	if p.Interval < 0 {
+		return fmt.Errorf("Negative interval %d", p.Interval)
+	}
+	if p.MaxTxs < 0 {
+		return fmt.Errorf("Negative max transactions %d", p.MaxTxs)
+	}
+
+	return nil
+}
+
+type Status struct {
+	Running   bool    `json:"running"`
+	Policy    Policy  `json:"policy"`              // of the current or last run
+	Blocks    int     `json:"blocks"`              // mined by the current or last run
+	LastBlock string  `json:"lastBlock,omitempty"` // hash of the last block mined
+	HashRate  float64 `json:"hashRate"`            // of the current or last block, in hashes per second
+	Error     string  `json:"error,omitempty"`     // of the last block if it failed
+}
+
+// Chain is what the miner mines on
+type Chain interface {
+	OpenTxs() int
+	MineBlock(ctx context.Context, maxTxs int) (*pb.Block, error) // seals and adds the next block
+	HashRate() float64
+}
+
+type Miner struct {
+	sync.Mutex
+	chain  Chain
+	idle   time.Duration
+	status Status
+	cancel context.CancelFunc // of the current run, nil if stopped
+	done   chan struct{}      // closed once the current run returns
+}
+
+func NewMiner(chain Chain) *Miner {
+	return &Miner{chain: chain, idle: idle}
+}
+
+// Start runs the miner in the background with the policy
+func (m *Miner) Start(policy Policy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	if m.cancel != nil {
+		return fmt.Errorf("Miner is already running")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel, m.done = cancel, make(chan struct{})
+	m.status = Status{Running: true, Policy: policy}
+	go m.run(ctx, policy, m.done)
+	return nil
+}
+
+// Stop stops the miner, and returns once the block being mined is abandoned
+func (m *Miner) Stop() error {
+	m.Lock()
+	cancel, done := m.cancel, m.done
+	m.cancel = nil
+	m.Unlock()
+	if cancel == nil {
+		return fmt.Errorf("Miner isn't running")
+	}
+
+	cancel()
+	<-done
+	m.Lock()
+	m.status.Running = false
+	m.Unlock()
+	return nil
+}
+
+func (m *Miner) Status() *Status {
+	m.Lock()
+	defer m.Unlock()
+	s := m.status
+	s.HashRate = m.chain.HashRate()
+	return &s
+}
+
+func (m *Miner) run(ctx context.Context, policy Policy, done chan struct{}) {
+	defer close(done)
+	interval := time.Duration(policy.Interval) * time.Second
+	var last time.Time
+	for {
+		wait := time.Until(last.Add(interval))
+		if wait <= 0 && policy.OnlyWithTxs && m.chain.OpenTxs() == 0 {
+			wait = m.idle
+		}
+		if wait > 0 {
+			if !sleep(ctx, wait) {
+				return
+			}
+			continue
+		}
+
+		last = time.Now()
+		block, err := m.chain.MineBlock(ctx, policy.MaxTxs)
+		m.Lock()
+		switch {
+		case err == nil:
+			// even if stopped meanwhile, the block is added
+			m.status.Blocks++
+			m.status.LastBlock, m.status.Error = block.Hash, ""
+		case ctx.Err() == nil:
+			m.status.Error = err.Error()
+		}
+		m.Unlock()
+		if ctx.Err() != nil {
+			return
+		}
+		// e.g. a competing block, or not the turn of the validator yet
+		if err != nil && !sleep(ctx, m.idle) {
+			return
+		}
+	}
+}
+
+// sleep waits for the duration, and tells if the context isn't done meanwhile
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
@@ -0,0 +1,140 @@
+package miner
+
+import (
+	"context"
+	"fmt"
+	"proto"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testChain mines blocks instantly, taking the open transactions
+type testChain struct {
+	sync.Mutex
+	open   int
+	blocks []int // transactions of the blocks
+	err    error
+	hang   bool // mines until canceled
+}
+
+func (c *testChain) OpenTxs() int {
+	c.Lock()
+	defer c.Unlock()
+	return c.open
+}
+
+func (c *testChain) MineBlock(ctx context.Context, maxTxs int) (*pb.Block, error) {
+	c.Lock()
+	hang, err := c.hang, c.err
+	c.Unlock()
+	if hang {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	txs := c.open
+	if maxTxs > 0 && txs > maxTxs {
+		txs = maxTxs
+	}
+	c.open -= txs
+	c.blocks = append(c.blocks, txs)
+	return &pb.Block{Hash: fmt.Sprint("block", len(c.blocks))}, nil
+}
+
+func (c *testChain) HashRate() float64 {
+	return 100
+}
+
+func (c *testChain) mined() []int {
+	c.Lock()
+	defer c.Unlock()
+	return append([]int(nil), c.blocks...)
+}
+
+func newTestMiner() (*Miner, *testChain) {
+	c := &testChain{}
+	m := NewMiner(c)
+	m.idle = time.Millisecond
+	return m, c
+}
+
+func TestStartStop(t *testing.T) {
+	m, c := newTestMiner()
+	assert.False(t, m.Status().Running)
+	assert.NotNil(t, m.Stop())
+
+	assert.Nil(t, m.Start(Policy{}))
+	assert.NotNil(t, m.Start(Policy{}))
+	assert.True(t, m.Status().Running)
+	assert.Eventually(t, func() bool { return m.Status().Blocks >= 3 }, time.Second, time.Millisecond)
+
+	assert.Nil(t, m.Stop())
+	s := m.Status()
+	assert.False(t, s.Running)
+	assert.Equal(t, len(c.mined()), s.Blocks)
+	assert.Equal(t, fmt.Sprint("block", s.Blocks), s.LastBlock)
+	assert.Equal(t, 100.0, s.HashRate)
+	assert.NotNil(t, m.Stop())
+
+	// a new run counts its own blocks
+	assert.Nil(t, m.Start(Policy{}))
+	assert.Nil(t, m.Stop())
+	assert.True(t, m.Status().Blocks < len(c.mined()))
+}
+
+func TestOnlyWithTxs(t *testing.T) {
+	m, c := newTestMiner()
+	assert.Nil(t, m.Start(Policy{OnlyWithTxs: true, MaxTxs: 2}))
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, 0, len(c.mined()))
+
+	c.Lock()
+	c.open = 3
+	c.Unlock()
+	assert.Eventually(t, func() bool { return c.OpenTxs() == 0 }, time.Second, time.Millisecond)
+	assert.Nil(t, m.Stop())
+	assert.Equal(t, []int{2, 1}, c.mined())
+}
+
+func TestInterval(t *testing.T) {
+	m, c := newTestMiner()
+	assert.Nil(t, m.Start(Policy{Interval: 60}))
+	assert.Eventually(t, func() bool { return len(c.mined()) == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	assert.Nil(t, m.Stop())
+	assert.Equal(t, 1, len(c.mined()))
+}
+
+func TestStopCancels(t *testing.T) {
+	m, c := newTestMiner()
+	c.hang = true
+	assert.Nil(t, m.Start(Policy{}))
+	assert.Nil(t, m.Stop())
+	assert.Equal(t, 0, m.Status().Blocks)
+	assert.Empty(t, m.Status().Error)
+}
+
+func TestErrors(t *testing.T) {
+	m, c := newTestMiner()
+	assert.NotNil(t, m.Start(Policy{Interval: -1}))
+	assert.NotNil(t, m.Start(Policy{MaxTxs: -1}))
+
+	// the miner keeps trying
+	c.err = fmt.Errorf("Not in turn")
+	assert.Nil(t, m.Start(Policy{}))
+	assert.Eventually(t, func() bool { return m.Status().Error == "Not in turn" }, time.Second, time.Millisecond)
+	c.Lock()
+	c.err = nil
+	c.Unlock()
+	assert.Eventually(t, func() bool { return m.Status().Blocks > 0 }, time.Second, time.Millisecond)
+	assert.Empty(t, m.Status().Error)
+	assert.Nil(t, m.Stop())
+}
@@ -0,0 +1,158 @@
+package main
+
+// genesis command, to write and check the genesis spec of a network
+import (
+	"api"
+	"config"
+	"flag"
+	"fmt"
+	"genesis"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type genesisInfo struct {
+	ChainID string `json:"chainId"`
+	Hash    string `json:"hash"`
+	File    string `json:"file,omitempty"`
+	Chain   string `json:"chain,omitempty"` // genesis hash of the chain checked against, if any
+}
+
+func (c *cli) genesis(args []string) error {
+	if len(args) > 0 && args[0] == "new" {
+		return c.genesisNew(args[1:])
+	}
+	if len(args) == 2 && args[0] == "verify" {
+		return c.genesisVerify(args[1])
+	}
+
+	return fmt.Errorf("Usage: genesis new [flags] | genesis verify <file>")
+}
+
+// genesisNew writes a spec with the initial accounts of the config, if any, to the file or else to stdout
+func (c *cli) genesisNew(args []string) error {
+	fs := flag.NewFlagSet("genesis new", flag.ExitOnError)
+	def := genesis.Default(nil)
+	spec := *def
+	fs.StringVar(&spec.ChainID, "chain-id", def.ChainID, "chain ID of the network")
+	fs.Int64Var(&spec.Timestamp, "timestamp", time.Now().UnixNano(), "timestamp of the genesis block, unix nanoseconds")
+	var difficulty int
+	fs.IntVar(&difficulty, "difficulty", int(def.Difficulty), "initial difficulty")
+	fs.Int64Var(&spec.BlockTime, "block-time", def.BlockTime, "target time between blocks, in seconds")
+	reward := fs.Float64("reward", 0, "reward of every block, none if 0")
+	fs.IntVar(&spec.MaxBlockSize, "max-block-size", 0, "max encoded size of the txs of a block, no limit if 0")
+	fs.StringVar(&spec.StateTree, "state", def.StateTree, "kind of the balances tree")
+	fs.StringVar(&spec.Consensus.Engine, "engine", genesis.EnginePoW, "consensus engine, "+genesis.EnginePoW+", "+genesis.EnginePoA+", "+genesis.EnginePoS+", "+genesis.EngineBFT+" or "+genesis.EngineDev)
+	signers := fs.String("signers", "", "comma separated public keys of the validators of the poa and bft engines, see key new")
+	stakes := fs.String("stakes", "", "comma separated key:val initial stakes of the validators of the pos engine")
+	out := fs.String("o", "", "file to write, stdout by default")
+	fs.Parse(args)
+
+	spec.Difficulty = int32(difficulty)
+	if len(*signers) > 0 {
+		spec.Consensus.Signers = strings.Split(*signers, ",")
+	}
+	if len(*stakes) > 0 {
+		for _, s := range strings.Split(*stakes, ",") {
+			i := strings.LastIndex(s, ":")
+			if i < 0 {
+				return fmt.Errorf("Invalid stake %q, expected key:val", s)
+			}
+			val, err := strconv.ParseFloat(s[i+1:], 64)
+			if err != nil {
+				return fmt.Errorf("Invalid stake %q, expected key:val", s)
+			}
+			spec.Consensus.Stakes = append(spec.Consensus.Stakes, config.Account{Address: s[:i], Val: val})
+		}
+	}
+	if *reward > 0 {
+		spec.Rewards = []genesis.Reward{{Height: 1, Val: *reward}}
+	}
+
+	cfg, err := c.config(false)
+	if err != nil {
+		return err
+	}
+	spec.Alloc = cfg.Init
+	if err := spec.Validate(); err != nil {
+		return err
+	}
+
+	if len(*out) == 0 {
+		return spec.Write(os.Stdout)
+	}
+
+	f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if err := spec.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	hash, err := spec.Hash()
+	if err != nil {
+		return err
+	}
+
+	info := &genesisInfo{ChainID: spec.ChainID, Hash: hash, File: *out}
+	return c.print(info, func() { fmt.Println(hash) })
+}
+
+// genesisVerify validates the spec, and checks that it's the genesis of the chain of the node or data dir if any
+func (c *cli) genesisVerify(path string) error {
+	spec, err := genesis.Load(path)
+	if err != nil {
+		return err
+	}
+
+	hash, err := spec.Hash()
+	if err != nil {
+		return err
+	}
+
+	info := &genesisInfo{ChainID: spec.ChainID, Hash: hash, File: path}
+	if len(c.node) > 0 || c.hasChain() {
+		b, err := c.backend()
+		if err != nil {
+			return err
+		}
+
+		chain, err := b.ChainInfo()
+		if err != nil {
+			return err
+		}
+		info.Chain = chain.Genesis
+	}
+
+	if err := c.print(info, func() {
+		fmt.Printf("chain id: %s\nhash:     %s\n", info.ChainID, info.Hash)
+		if len(info.Chain) > 0 && info.Chain == info.Hash {
+			fmt.Println("matches the genesis of the chain")
+		}
+	}); err != nil {
+		return err
+	}
+	if len(info.Chain) > 0 && info.Chain != info.Hash {
+		return fmt.Errorf("Spec doesn't match the genesis %s of the chain", info.Chain)
+	}
+
+	return nil
+}
+
+// hasChain tells if there is a chain in the data dir
+func (c *cli) hasChain() bool {
+	cfg, err := c.config(false)
+	if err != nil {
+		return false
+	}
+
+	_, err = os.Stat(api.ChainPath(cfg.DataDir))
+	return err == nil
+}
@@ -0,0 +1,63 @@
+package main
+
+// Validator keys, see keys.Load
+import (
+	"flag"
+	"fmt"
+	"keys"
+	"os"
+)
+
+func (c *cli) key(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("Usage: key new [-o file] | key show [file]")
+	}
+
+	switch args[0] {
+	case "new":
+		fs := flag.NewFlagSet("key new", flag.ExitOnError)
+		out := fs.String("o", "validator.key", "key file to write, never overwritten")
+		fs.Parse(args[1:])
+
+		if _, err := os.Stat(*out); err == nil {
+			return fmt.Errorf("Key file %s already exists", *out)
+		}
+		priv, err := keys.Generate()
+		if err != nil {
+			return err
+		}
+		if err := keys.Save(*out, priv); err != nil {
+			return err
+		}
+
+		pub := keys.Public(priv)
+		return c.print(&KeyResponse{Public: pub}, func() { fmt.Println(pub) })
+	case "show":
+		path := ""
+		if len(args) == 2 {
+			path = args[1]
+		} else {
+			cfg, err := c.config(false)
+			if err != nil {
+				return err
+			}
+			path = cfg.Key
+		}
+		if len(path) == 0 {
+			return fmt.Errorf("No key file given, nor in the config")
+		}
+
+		priv, err := keys.Load(path)
+		if err != nil {
+			return err
+		}
+
+		pub := keys.Public(priv)
+		return c.print(&KeyResponse{Public: pub}, func() { fmt.Println(pub) })
+	}
+	return fmt.Errorf("Usage: key new [-o file] | key show [file]")
+}
+
+type KeyResponse struct {
+	Public string `json:"public"`
+}
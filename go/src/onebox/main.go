@@ -1,5 +1,469 @@
 package main
 
+// onebox runs a single node chain and operates it from the command line
+// Every command works either on the chain in the local data dir, or on a running node with -node.
+//   onebox [-config file] [-datadir dir] [-user addr] [-node addr] [-chain-id id] [-json] <command> [args]
+import (
+	"api"
+	"blockchain"
+	"config"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"merkle"
+	"net/http"
+	"os"
+	"proto"
+	"strconv"
+	"time"
+)
+
+const usage = `Usage: onebox [flags] <command> [args]
+
+Commands:
+  init [-genesis file] [-state kind]
+                              create a new chain in the data dir, with the user of the config and the genesis spec
+                              of the file or the config, else the initial accounts of the config, and the balances
+                              in a state tree of the kind, "" (patricia) or "smt"
+  genesis new [flags]         write a genesis spec with the initial accounts of the config, see genesis new -h
+  genesis verify <file>       validate a genesis spec, show its hash and check it against the chain if any
+  key new [-o file]           write a new validator key to the file, validator.key by default, show its public key
+  key show [file]             show the public key of the key file, the one of the config by default
+  node [-addr addr] [-mine] [-only-txs] [-interval s] [-max-txs n]
+                              serve the chain of the data dir over HTTP, mining in the background with -mine, with
+                              the policy of the flags as miner start
+  tx send <recipient> <val>   send val from the user to recipient
+  tx get <id>                 show a transaction
+  tx submit <file>            submit a transaction made elsewhere, as the JSON of tx get -json, "-" for stdin
+  balance <addr>              show the balance of an account
+  block get <index|hash>      show a block
+  mine                        mine the open transactions into a new block
+  chain info                  show the state of the chain
+  validate                    check the linkage and integrity of the chain
+  validators                  show the validators of the next block, their stakes and the votes of the node
+  propose <key> add|remove|discard
+                              vote in the blocks signed by the node for adding or removing a validator, or stop voting
+  stake bond|unbond <val>     bond val of the balance of the user as stake, or unbond it, with the pos engine
+  miner start [-only-txs] [-interval s] [-max-txs n]
+                              start the background miner of the node, with the policy of the flags
+  miner stop|status           stop the background miner of the node, or show its status
+
+Flags:
+`
+
+type cli struct {
+	cfgFile string
+	dataDir string
+	user    string
+	node    string
+	chainID string
+	json    bool
+}
+
 func main() {
-	println("testing hawaii-chain")
+	c := &cli{}
+	flag.StringVar(&c.cfgFile, "config", "", "config file, $"+config.EnvPath+" by default, required by init")
+	flag.StringVar(&c.dataDir, "datadir", "", "data dir of the chain, overriding the config, \"data\" by default")
+	flag.StringVar(&c.user, "user", "", "address of the user, overriding the config")
+	flag.StringVar(&c.node, "node", "", "address of a running node to use instead of the data dir")
+	flag.StringVar(&c.chainID, "chain-id", "", "refuse to operate on a chain with another chain ID")
+	flag.BoolVar(&c.json, "json", false, "print the output as JSON")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := c.run(flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "onebox:", err)
+		os.Exit(1)
+	}
+}
+
+func (c *cli) run(args []string) error {
+	if len(args) == 0 {
+		flag.Usage()
+		return fmt.Errorf("No command")
+	}
+
+	cmd, args := args[0], args[1:]
+	switch cmd {
+	case "init":
+		return c.init(args)
+	case "node":
+		return c.serve(args)
+	case "genesis":
+		return c.genesis(args)
+	case "key":
+		return c.key(args)
+	case "miner":
+		return c.miner(args)
+	}
+
+	switch cmd {
+	case "tx", "balance", "block", "mine", "chain", "validate", "validators", "propose", "stake":
+	default:
+		flag.Usage()
+		return fmt.Errorf("Unknown command %s", cmd)
+	}
+
+	b, err := c.backend()
+	if err != nil {
+		return err
+	}
+
+	switch cmd {
+	case "tx":
+		if len(args) == 3 && args[0] == "send" {
+			val, err := strconv.ParseFloat(args[2], 64)
+			if err != nil {
+				return fmt.Errorf("Invalid amount %s", args[2])
+			}
+
+			id, err := b.SendTx(args[1], val)
+			if err != nil {
+				return err
+			}
+			return c.print(&api.TxResponse{Id: id}, func() { fmt.Println(id) })
+		}
+		if len(args) == 2 && args[0] == "get" {
+			tx, err := b.Tx(args[1])
+			if err != nil {
+				return err
+			}
+			return c.print(tx, func() { printTx(tx, "") })
+		}
+		if len(args) == 2 && args[0] == "submit" {
+			tx, err := readTx(args[1])
+			if err != nil {
+				return err
+			}
+
+			id, err := b.SubmitTx(tx)
+			if err != nil {
+				return err
+			}
+			return c.print(&api.TxResponse{Id: id}, func() { fmt.Println(id) })
+		}
+		return fmt.Errorf("Usage: tx send <recipient> <val> | tx get <id> | tx submit <file>")
+	case "balance":
+		if len(args) != 1 {
+			return fmt.Errorf("Usage: balance <addr>")
+		}
+
+		bal, err := b.Balance(args[0])
+		if err != nil {
+			return err
+		}
+		return c.print(&api.BalanceResponse{Addr: args[0], Balance: bal}, func() { fmt.Printf("%f\n", bal) })
+	case "block":
+		if len(args) != 2 || args[0] != "get" {
+			return fmt.Errorf("Usage: block get <index|hash>")
+		}
+
+		block, err := b.Block(args[1])
+		if err != nil {
+			return err
+		}
+		return c.print(block, func() { printBlock(block) })
+	case "mine":
+		block, err := b.Mine()
+		if err != nil {
+			return err
+		}
+		return c.print(block, func() { printBlock(block) })
+	case "chain":
+		if len(args) != 1 || args[0] != "info" {
+			return fmt.Errorf("Usage: chain info")
+		}
+
+		info, err := b.ChainInfo()
+		if err != nil {
+			return err
+		}
+		return c.print(info, func() { printChainInfo(info) })
+	case "validate":
+		err := b.Validate()
+		if err == nil {
+			return c.print(&api.ValidateResponse{Valid: true}, func() { fmt.Println("valid") })
+		}
+		if c.json {
+			c.print(&api.ValidateResponse{Error: err.Error()}, nil)
+		}
+		return err
+	case "validators":
+		info, err := b.Validators()
+		if err != nil {
+			return err
+		}
+		return c.print(info, func() { printValidators(info) })
+	case "propose":
+		if len(args) != 2 {
+			return fmt.Errorf("Usage: propose <key> add|remove|discard")
+		}
+		if err := b.Propose(args[0], args[1]); err != nil {
+			return err
+		}
+
+		info, err := b.Validators()
+		if err != nil {
+			return err
+		}
+		return c.print(info, func() { printValidators(info) })
+	case "stake":
+		if len(args) != 2 {
+			return fmt.Errorf("Usage: stake bond|unbond <val>")
+		}
+		val, err := strconv.ParseFloat(args[1], 64)
+		if err != nil {
+			return fmt.Errorf("Invalid amount %s", args[1])
+		}
+
+		id, err := b.Stake(args[0], val)
+		if err != nil {
+			return err
+		}
+		return c.print(&api.TxResponse{Id: id}, func() { fmt.Println(id) })
+	}
+
+	return nil
+}
+
+// backend returns the node if set, else the chain of the data dir, after checking its chain ID if set
+func (c *cli) backend() (api.Backend, error) {
+	if len(c.node) > 0 {
+		client := api.NewClient(c.node)
+		if len(c.chainID) > 0 {
+			if err := client.CheckNetwork(c.chainID, ""); err != nil {
+				return nil, err
+			}
+		}
+		return client, nil
+	}
+
+	cfg, err := c.config(false)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := api.OpenService(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if h, _ := s.Handshake(); len(c.chainID) > 0 && h.ChainId != c.chainID {
+		return nil, fmt.Errorf("Chain in %s has the chain ID %q, not %q", cfg.DataDir, h.ChainId, c.chainID)
+	}
+	return s, nil
+}
+
+// config loads the config file, optional unless required, and applies the env vars and the flags
+func (c *cli) config(required bool) (config.Config, error) {
+	cfg := config.Config{}
+	if required || len(c.cfgFile) > 0 || len(os.Getenv(config.EnvPath)) > 0 {
+		loaded, err := config.Load(c.cfgFile)
+		if err != nil {
+			return cfg, err
+		}
+		cfg = *loaded
+	} else {
+		cfg.ApplyEnv()
+	}
+
+	if len(c.dataDir) > 0 {
+		cfg.DataDir = c.dataDir
+	}
+	if len(cfg.DataDir) == 0 {
+		cfg.DataDir = "data"
+	}
+	if len(c.user) > 0 {
+		cfg.User.Address = c.user
+	}
+	return cfg, nil
+}
+
+func (c *cli) init(args []string) error {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	spec := fs.String("genesis", "", "genesis spec file, overriding the config")
+	kind := fs.String("state", "", "kind of the balances tree, overriding the genesis spec")
+	fs.Parse(args)
+
+	cfg, err := c.config(true)
+	if err != nil {
+		return err
+	}
+	if len(*spec) > 0 {
+		cfg.Genesis = *spec
+	}
+
+	var opts []blockchain.Option
+	if isFlagSet(fs, "state") {
+		opts = append(opts, blockchain.WithState(*kind))
+	}
+	s, err := api.InitService(cfg, opts...)
+	if err != nil {
+		return err
+	}
+
+	info, err := s.ChainInfo()
+	if err != nil {
+		return err
+	}
+	return c.print(info, func() {
+		fmt.Println("created chain at", api.ChainPath(cfg.DataDir))
+		printChainInfo(info)
+	})
+}
+
+func (c *cli) serve(args []string) error {
+	fs := flag.NewFlagSet("node", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	mine := fs.Bool("mine", false, "mine in the background, with the policy of the flags")
+	policy := policyFlags(fs)
+	fs.Parse(args)
+
+	cfg, err := c.config(false)
+	if err != nil {
+		return err
+	}
+
+	s, err := api.OpenService(cfg)
+	if err != nil {
+		return err
+	}
+	if *mine {
+		if _, err := s.StartMiner(*policy); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, "serving", cfg.DataDir, "on", *addr)
+	return http.ListenAndServe(*addr, api.NewServer(s))
+}
+
+// print writes v as JSON with -json, else calls human to write it
+func (c *cli) print(v interface{}, human func()) error {
+	if !c.json {
+		human()
+		return nil
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func printChainInfo(info *api.ChainInfo) {
+	state := info.StateTree
+	if state == merkle.StatePatricia {
+		state = "patricia"
+	}
+	fmt.Printf("chain id:   %s\n", info.ChainID)
+	fmt.Printf("genesis:    %s\n", info.Genesis)
+	fmt.Printf("height:     %d\n", info.Height)
+	fmt.Printf("last hash:  %s\n", info.LastHash)
+	fmt.Printf("difficulty: %d\n", info.Difficulty)
+	fmt.Printf("open txs:   %d\n", info.OpenTxs)
+	fmt.Printf("user:       %s\n", info.User)
+	fmt.Printf("state tree: %s\n", state)
+	fmt.Printf("consensus:  %s\n", info.Engine)
+	if info.HashRate > 0 {
+		fmt.Printf("hash rate:  %.0f H/s\n", info.HashRate)
+	}
+}
+
+func printBlock(block *api.BlockInfo) {
+	fmt.Printf("index:      %d\n", block.Index)
+	fmt.Printf("hash:       %s\n", block.Hash)
+	fmt.Printf("prev hash:  %s\n", block.PrevHash)
+	fmt.Printf("proof:      %d\n", block.Proof)
+	fmt.Printf("time:       %s\n", formatTime(block.Timestamp))
+	fmt.Printf("tx root:    %s\n", block.TxRoot)
+	fmt.Printf("state root: %s\n", block.StateRoot)
+	if block.Difficulty > 0 {
+		fmt.Printf("difficulty: %d\n", block.Difficulty)
+	}
+	if len(block.Signer) > 0 {
+		fmt.Printf("signer:     %s\n", block.Signer)
+	}
+	if len(block.Coinbase) > 0 {
+		fmt.Printf("coinbase:   %s\n", block.Coinbase)
+	}
+	for _, ev := range block.Evidence {
+		fmt.Printf("slashed:    %s, double signing at height %d\n", ev.A.Header.Signer, ev.A.Header.Index)
+	}
+	if block.Commit != nil {
+		fmt.Printf("commit:     round %d, %d precommits\n", block.Commit.Round, len(block.Commit.Precommits))
+	}
+	fmt.Printf("txs:        %d\n", len(block.Txs))
+	for _, tx := range block.Txs {
+		printTx(tx, "  ")
+	}
+}
+
+func printValidators(info *api.ValidatorsInfo) {
+	fmt.Println("validators:")
+	for _, v := range info.Validators {
+		if stake, ok := info.Stakes[v]; ok {
+			fmt.Printf("  %s  %f\n", v, stake)
+		} else {
+			fmt.Printf("  %s\n", v)
+		}
+	}
+	if len(info.Proposals) == 0 {
+		return
+	}
+
+	fmt.Println("proposals:")
+	for _, v := range info.Proposals {
+		action := api.ProposeRemove
+		if v.Add {
+			action = api.ProposeAdd
+		}
+		fmt.Printf("  %-6s %s\n", action, v.Validator)
+	}
+}
+
+func printTx(tx *pb.Transaction, indent string) {
+	status := tx.Status
+	if len(tx.Type) > 0 {
+		status = tx.Type + " " + status
+	}
+	fmt.Printf("%s%s  %s -> %s  %f  %s  %s\n", indent, tx.Id, tx.Sender, tx.Recipient, tx.Val, status,
+		formatTime(tx.Timestamp))
+}
+
+// readTx reads a transaction as JSON from the file, or stdin if "-"
+func readTx(path string) (*pb.Transaction, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tx := &pb.Transaction{}
+	return tx, json.Unmarshal(data, tx)
+}
+
+func formatTime(ns int64) string {
+	if ns == 0 {
+		return "-"
+	}
+
+	return time.Unix(0, ns).Format(time.RFC3339)
+}
+
+func isFlagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
 }
@@ -0,0 +1,71 @@
+package main
+
+// miner command, to control the background miner of a running node
+import (
+	"flag"
+	"fmt"
+	"miner"
+)
+
+func (c *cli) miner(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("Usage: miner start [flags] | miner stop | miner status")
+	}
+
+	b, err := c.backend()
+	if err != nil {
+		return err
+	}
+
+	var status *miner.Status
+	switch args[0] {
+	case "start":
+		fs := flag.NewFlagSet("miner start", flag.ExitOnError)
+		policy := policyFlags(fs)
+		fs.Parse(args[1:])
+		if len(c.node) == 0 {
+			// the miner would stop with the command
+			return fmt.Errorf("The miner runs in a node, see -node and node -mine")
+		}
+
+		status, err = b.StartMiner(*policy)
+	case "stop":
+		status, err = b.StopMiner()
+	case "status":
+		status, err = b.MinerStatus()
+	default:
+		return fmt.Errorf("Usage: miner start [flags] | miner stop | miner status")
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.print(status, func() { printMinerStatus(status) })
+}
+
+// policyFlags defines the flags of the miner policy
+func policyFlags(fs *flag.FlagSet) *miner.Policy {
+	p := &miner.Policy{}
+	fs.BoolVar(&p.OnlyWithTxs, "only-txs", false, "mine only when transactions are open")
+	fs.Int64Var(&p.Interval, "interval", 0, "min seconds between two blocks, back to back if 0")
+	fs.IntVar(&p.MaxTxs, "max-txs", 0, "max transactions per block, as many as fit if 0")
+	return p
+}
+
+func printMinerStatus(status *miner.Status) {
+	state := "stopped"
+	if status.Running {
+		state = "running"
+	}
+	fmt.Printf("miner:      %s\n", state)
+	fmt.Printf("policy:     only with txs %t, interval %ds, max txs %d\n", status.Policy.OnlyWithTxs,
+		status.Policy.Interval, status.Policy.MaxTxs)
+	fmt.Printf("blocks:     %d\n", status.Blocks)
+	if len(status.LastBlock) > 0 {
+		fmt.Printf("last block: %s\n", status.LastBlock)
+	}
+	fmt.Printf("hash rate:  %.0f H/s\n", status.HashRate)
+	if len(status.Error) > 0 {
+		fmt.Printf("error:      %s\n", status.Error)
+	}
+}
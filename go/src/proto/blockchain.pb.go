@@ -25,6 +25,8 @@ type Transaction struct {
 	Val                  float64  `protobuf:"fixed64,4,opt,name=Val,proto3" json:"Val,omitempty"`
 	Timestamp            int64    `protobuf:"varint,5,opt,name=Timestamp,proto3" json:"Timestamp,omitempty"`
 	Status               string   `protobuf:"bytes,6,opt,name=Status,proto3" json:"Status,omitempty"`
+	ChainId              string   `protobuf:"bytes,7,opt,name=ChainId,proto3" json:"ChainId,omitempty"`
+	Type                 string   `protobuf:"bytes,8,opt,name=Type,proto3" json:"Type,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -34,7 +36,7 @@ func (m *Transaction) Reset()         { *m = Transaction{} }
 func (m *Transaction) String() string { return proto.CompactTextString(m) }
 func (*Transaction) ProtoMessage()    {}
 func (*Transaction) Descriptor() ([]byte, []int) {
-	return fileDescriptor_blockchain_d4304b0d40850632, []int{0}
+	return fileDescriptor_blockchain_10cfc2182350651d, []int{0}
 }
 func (m *Transaction) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Transaction.Unmarshal(m, b)
@@ -96,24 +98,46 @@ func (m *Transaction) GetStatus() string {
 	return ""
 }
 
+func (m *Transaction) GetChainId() string {
+	if m != nil {
+		return m.ChainId
+	}
+	return ""
+}
+
+func (m *Transaction) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
 type Block struct {
-	Index                int32    `protobuf:"varint,1,opt,name=Index,proto3" json:"Index,omitempty"`
-	Hash                 string   `protobuf:"bytes,2,opt,name=Hash,proto3" json:"Hash,omitempty"`
-	PrevHash             string   `protobuf:"bytes,3,opt,name=PrevHash,proto3" json:"PrevHash,omitempty"`
-	Proof                int64    `protobuf:"varint,4,opt,name=Proof,proto3" json:"Proof,omitempty"`
-	Timestamp            int64    `protobuf:"varint,5,opt,name=Timestamp,proto3" json:"Timestamp,omitempty"`
-	Txs                  *Tree    `protobuf:"bytes,6,opt,name=Txs,proto3" json:"Txs,omitempty"`
-	Balances             *Tree    `protobuf:"bytes,7,opt,name=Balances,proto3" json:"Balances,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Index                int32       `protobuf:"varint,1,opt,name=Index,proto3" json:"Index,omitempty"`
+	Hash                 string      `protobuf:"bytes,2,opt,name=Hash,proto3" json:"Hash,omitempty"`
+	PrevHash             string      `protobuf:"bytes,3,opt,name=PrevHash,proto3" json:"PrevHash,omitempty"`
+	Proof                int64       `protobuf:"varint,4,opt,name=Proof,proto3" json:"Proof,omitempty"`
+	Timestamp            int64       `protobuf:"varint,5,opt,name=Timestamp,proto3" json:"Timestamp,omitempty"`
+	Txs                  *Tree       `protobuf:"bytes,6,opt,name=Txs,proto3" json:"Txs,omitempty"`
+	Balances             *Tree       `protobuf:"bytes,7,opt,name=Balances,proto3" json:"Balances,omitempty"`
+	ChainId              string      `protobuf:"bytes,8,opt,name=ChainId,proto3" json:"ChainId,omitempty"`
+	Signer               string      `protobuf:"bytes,9,opt,name=Signer,proto3" json:"Signer,omitempty"`
+	Signature            []byte      `protobuf:"bytes,10,opt,name=Signature,proto3" json:"Signature,omitempty"`
+	Votes                []*Vote     `protobuf:"bytes,11,rep,name=Votes,proto3" json:"Votes,omitempty"`
+	Evidence             []*Evidence `protobuf:"bytes,12,rep,name=Evidence,proto3" json:"Evidence,omitempty"`
+	Commit               *Commit     `protobuf:"bytes,13,opt,name=Commit,proto3" json:"Commit,omitempty"`
+	Difficulty           int32       `protobuf:"varint,14,opt,name=Difficulty,proto3" json:"Difficulty,omitempty"`
+	Coinbase             string      `protobuf:"bytes,15,opt,name=Coinbase,proto3" json:"Coinbase,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
 }
 
 func (m *Block) Reset()         { *m = Block{} }
 func (m *Block) String() string { return proto.CompactTextString(m) }
 func (*Block) ProtoMessage()    {}
 func (*Block) Descriptor() ([]byte, []int) {
-	return fileDescriptor_blockchain_d4304b0d40850632, []int{1}
+	return fileDescriptor_blockchain_10cfc2182350651d, []int{1}
 }
 func (m *Block) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Block.Unmarshal(m, b)
@@ -182,6 +206,600 @@ func (m *Block) GetBalances() *Tree {
 	return nil
 }
 
+func (m *Block) GetChainId() string {
+	if m != nil {
+		return m.ChainId
+	}
+	return ""
+}
+
+func (m *Block) GetSigner() string {
+	if m != nil {
+		return m.Signer
+	}
+	return ""
+}
+
+func (m *Block) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func (m *Block) GetVotes() []*Vote {
+	if m != nil {
+		return m.Votes
+	}
+	return nil
+}
+
+func (m *Block) GetEvidence() []*Evidence {
+	if m != nil {
+		return m.Evidence
+	}
+	return nil
+}
+
+func (m *Block) GetCommit() *Commit {
+	if m != nil {
+		return m.Commit
+	}
+	return nil
+}
+
+func (m *Block) GetDifficulty() int32 {
+	if m != nil {
+		return m.Difficulty
+	}
+	return 0
+}
+
+func (m *Block) GetCoinbase() string {
+	if m != nil {
+		return m.Coinbase
+	}
+	return ""
+}
+
+// BlockHeader is the compact header of a block, whose hash is the ID of the block, see utils.HashBlock. It holds every
+// field of the block but the signature, which signs the hash, and the commit certificate, made after. It commits to
+// the body of the block by the root hashes of its tries, and to the votes and evidence by their hash.
+type BlockHeader struct {
+	Index                int32    `protobuf:"varint,1,opt,name=Index,proto3" json:"Index,omitempty"`
+	PrevHash             string   `protobuf:"bytes,2,opt,name=PrevHash,proto3" json:"PrevHash,omitempty"`
+	TxRoot               string   `protobuf:"bytes,3,opt,name=TxRoot,proto3" json:"TxRoot,omitempty"`
+	StateRoot            string   `protobuf:"bytes,4,opt,name=StateRoot,proto3" json:"StateRoot,omitempty"`
+	Timestamp            int64    `protobuf:"varint,5,opt,name=Timestamp,proto3" json:"Timestamp,omitempty"`
+	Difficulty           int32    `protobuf:"varint,6,opt,name=Difficulty,proto3" json:"Difficulty,omitempty"`
+	Nonce                int64    `protobuf:"varint,7,opt,name=Nonce,proto3" json:"Nonce,omitempty"`
+	ChainId              string   `protobuf:"bytes,8,opt,name=ChainId,proto3" json:"ChainId,omitempty"`
+	Signer               string   `protobuf:"bytes,9,opt,name=Signer,proto3" json:"Signer,omitempty"`
+	ExtraHash            string   `protobuf:"bytes,10,opt,name=ExtraHash,proto3" json:"ExtraHash,omitempty"`
+	Coinbase             string   `protobuf:"bytes,11,opt,name=Coinbase,proto3" json:"Coinbase,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BlockHeader) Reset()         { *m = BlockHeader{} }
+func (m *BlockHeader) String() string { return proto.CompactTextString(m) }
+func (*BlockHeader) ProtoMessage()    {}
+func (*BlockHeader) Descriptor() ([]byte, []int) {
+	return fileDescriptor_blockchain_10cfc2182350651d, []int{2}
+}
+func (m *BlockHeader) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BlockHeader.Unmarshal(m, b)
+}
+func (m *BlockHeader) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BlockHeader.Marshal(b, m, deterministic)
+}
+func (dst *BlockHeader) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BlockHeader.Merge(dst, src)
+}
+func (m *BlockHeader) XXX_Size() int {
+	return xxx_messageInfo_BlockHeader.Size(m)
+}
+func (m *BlockHeader) XXX_DiscardUnknown() {
+	xxx_messageInfo_BlockHeader.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BlockHeader proto.InternalMessageInfo
+
+func (m *BlockHeader) GetIndex() int32 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *BlockHeader) GetPrevHash() string {
+	if m != nil {
+		return m.PrevHash
+	}
+	return ""
+}
+
+func (m *BlockHeader) GetTxRoot() string {
+	if m != nil {
+		return m.TxRoot
+	}
+	return ""
+}
+
+func (m *BlockHeader) GetStateRoot() string {
+	if m != nil {
+		return m.StateRoot
+	}
+	return ""
+}
+
+func (m *BlockHeader) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *BlockHeader) GetDifficulty() int32 {
+	if m != nil {
+		return m.Difficulty
+	}
+	return 0
+}
+
+func (m *BlockHeader) GetNonce() int64 {
+	if m != nil {
+		return m.Nonce
+	}
+	return 0
+}
+
+func (m *BlockHeader) GetChainId() string {
+	if m != nil {
+		return m.ChainId
+	}
+	return ""
+}
+
+func (m *BlockHeader) GetSigner() string {
+	if m != nil {
+		return m.Signer
+	}
+	return ""
+}
+
+func (m *BlockHeader) GetExtraHash() string {
+	if m != nil {
+		return m.ExtraHash
+	}
+	return ""
+}
+
+func (m *BlockHeader) GetCoinbase() string {
+	if m != nil {
+		return m.Coinbase
+	}
+	return ""
+}
+
+// BlockBody holds the tries of a block, stored and sent apart from its header
+type BlockBody struct {
+	Txs                  *Tree    `protobuf:"bytes,1,opt,name=Txs,proto3" json:"Txs,omitempty"`
+	Balances             *Tree    `protobuf:"bytes,2,opt,name=Balances,proto3" json:"Balances,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BlockBody) Reset()         { *m = BlockBody{} }
+func (m *BlockBody) String() string { return proto.CompactTextString(m) }
+func (*BlockBody) ProtoMessage()    {}
+func (*BlockBody) Descriptor() ([]byte, []int) {
+	return fileDescriptor_blockchain_10cfc2182350651d, []int{3}
+}
+func (m *BlockBody) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BlockBody.Unmarshal(m, b)
+}
+func (m *BlockBody) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BlockBody.Marshal(b, m, deterministic)
+}
+func (dst *BlockBody) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BlockBody.Merge(dst, src)
+}
+func (m *BlockBody) XXX_Size() int {
+	return xxx_messageInfo_BlockBody.Size(m)
+}
+func (m *BlockBody) XXX_DiscardUnknown() {
+	xxx_messageInfo_BlockBody.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BlockBody proto.InternalMessageInfo
+
+func (m *BlockBody) GetTxs() *Tree {
+	if m != nil {
+		return m.Txs
+	}
+	return nil
+}
+
+func (m *BlockBody) GetBalances() *Tree {
+	if m != nil {
+		return m.Balances
+	}
+	return nil
+}
+
+// Vote of a validator to add or remove a validator
+type Vote struct {
+	Validator            string   `protobuf:"bytes,1,opt,name=Validator,proto3" json:"Validator,omitempty"`
+	Add                  bool     `protobuf:"varint,2,opt,name=Add,proto3" json:"Add,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Vote) Reset()         { *m = Vote{} }
+func (m *Vote) String() string { return proto.CompactTextString(m) }
+func (*Vote) ProtoMessage()    {}
+func (*Vote) Descriptor() ([]byte, []int) {
+	return fileDescriptor_blockchain_10cfc2182350651d, []int{4}
+}
+func (m *Vote) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Vote.Unmarshal(m, b)
+}
+func (m *Vote) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Vote.Marshal(b, m, deterministic)
+}
+func (dst *Vote) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Vote.Merge(dst, src)
+}
+func (m *Vote) XXX_Size() int {
+	return xxx_messageInfo_Vote.Size(m)
+}
+func (m *Vote) XXX_DiscardUnknown() {
+	xxx_messageInfo_Vote.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Vote proto.InternalMessageInfo
+
+func (m *Vote) GetValidator() string {
+	if m != nil {
+		return m.Validator
+	}
+	return ""
+}
+
+func (m *Vote) GetAdd() bool {
+	if m != nil {
+		return m.Add
+	}
+	return false
+}
+
+// SignedHeader is the header of a block with the signature of its signer, which signs the header
+type SignedHeader struct {
+	Header               *BlockHeader `protobuf:"bytes,1,opt,name=Header,proto3" json:"Header,omitempty"`
+	Signature            []byte       `protobuf:"bytes,2,opt,name=Signature,proto3" json:"Signature,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *SignedHeader) Reset()         { *m = SignedHeader{} }
+func (m *SignedHeader) String() string { return proto.CompactTextString(m) }
+func (*SignedHeader) ProtoMessage()    {}
+func (*SignedHeader) Descriptor() ([]byte, []int) {
+	return fileDescriptor_blockchain_10cfc2182350651d, []int{5}
+}
+func (m *SignedHeader) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SignedHeader.Unmarshal(m, b)
+}
+func (m *SignedHeader) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SignedHeader.Marshal(b, m, deterministic)
+}
+func (dst *SignedHeader) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SignedHeader.Merge(dst, src)
+}
+func (m *SignedHeader) XXX_Size() int {
+	return xxx_messageInfo_SignedHeader.Size(m)
+}
+func (m *SignedHeader) XXX_DiscardUnknown() {
+	xxx_messageInfo_SignedHeader.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SignedHeader proto.InternalMessageInfo
+
+func (m *SignedHeader) GetHeader() *BlockHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *SignedHeader) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+// Evidence that a validator signed two blocks at the same height, as the signed headers of the blocks
+type Evidence struct {
+	A                    *SignedHeader `protobuf:"bytes,1,opt,name=A,proto3" json:"A,omitempty"`
+	B                    *SignedHeader `protobuf:"bytes,2,opt,name=B,proto3" json:"B,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *Evidence) Reset()         { *m = Evidence{} }
+func (m *Evidence) String() string { return proto.CompactTextString(m) }
+func (*Evidence) ProtoMessage()    {}
+func (*Evidence) Descriptor() ([]byte, []int) {
+	return fileDescriptor_blockchain_10cfc2182350651d, []int{6}
+}
+func (m *Evidence) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Evidence.Unmarshal(m, b)
+}
+func (m *Evidence) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Evidence.Marshal(b, m, deterministic)
+}
+func (dst *Evidence) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Evidence.Merge(dst, src)
+}
+func (m *Evidence) XXX_Size() int {
+	return xxx_messageInfo_Evidence.Size(m)
+}
+func (m *Evidence) XXX_DiscardUnknown() {
+	xxx_messageInfo_Evidence.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Evidence proto.InternalMessageInfo
+
+func (m *Evidence) GetA() *SignedHeader {
+	if m != nil {
+		return m.A
+	}
+	return nil
+}
+
+func (m *Evidence) GetB() *SignedHeader {
+	if m != nil {
+		return m.B
+	}
+	return nil
+}
+
+// Commit certificate of a block: the precommits of more than 2/3 of the validators for the block in a round
+type Commit struct {
+	Height               int32            `protobuf:"varint,1,opt,name=Height,proto3" json:"Height,omitempty"`
+	Round                int32            `protobuf:"varint,2,opt,name=Round,proto3" json:"Round,omitempty"`
+	BlockId              string           `protobuf:"bytes,3,opt,name=BlockId,proto3" json:"BlockId,omitempty"`
+	Precommits           []*ConsensusVote `protobuf:"bytes,4,rep,name=Precommits,proto3" json:"Precommits,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *Commit) Reset()         { *m = Commit{} }
+func (m *Commit) String() string { return proto.CompactTextString(m) }
+func (*Commit) ProtoMessage()    {}
+func (*Commit) Descriptor() ([]byte, []int) {
+	return fileDescriptor_blockchain_10cfc2182350651d, []int{7}
+}
+func (m *Commit) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Commit.Unmarshal(m, b)
+}
+func (m *Commit) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Commit.Marshal(b, m, deterministic)
+}
+func (dst *Commit) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Commit.Merge(dst, src)
+}
+func (m *Commit) XXX_Size() int {
+	return xxx_messageInfo_Commit.Size(m)
+}
+func (m *Commit) XXX_DiscardUnknown() {
+	xxx_messageInfo_Commit.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Commit proto.InternalMessageInfo
+
+func (m *Commit) GetHeight() int32 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *Commit) GetRound() int32 {
+	if m != nil {
+		return m.Round
+	}
+	return 0
+}
+
+func (m *Commit) GetBlockId() string {
+	if m != nil {
+		return m.BlockId
+	}
+	return ""
+}
+
+func (m *Commit) GetPrecommits() []*ConsensusVote {
+	if m != nil {
+		return m.Precommits
+	}
+	return nil
+}
+
+// Vote of a bft validator in a round, for a block or for none
+type ConsensusVote struct {
+	Type                 string   `protobuf:"bytes,1,opt,name=Type,proto3" json:"Type,omitempty"`
+	Height               int32    `protobuf:"varint,2,opt,name=Height,proto3" json:"Height,omitempty"`
+	Round                int32    `protobuf:"varint,3,opt,name=Round,proto3" json:"Round,omitempty"`
+	BlockId              string   `protobuf:"bytes,4,opt,name=BlockId,proto3" json:"BlockId,omitempty"`
+	Validator            string   `protobuf:"bytes,5,opt,name=Validator,proto3" json:"Validator,omitempty"`
+	Signature            []byte   `protobuf:"bytes,6,opt,name=Signature,proto3" json:"Signature,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ConsensusVote) Reset()         { *m = ConsensusVote{} }
+func (m *ConsensusVote) String() string { return proto.CompactTextString(m) }
+func (*ConsensusVote) ProtoMessage()    {}
+func (*ConsensusVote) Descriptor() ([]byte, []int) {
+	return fileDescriptor_blockchain_10cfc2182350651d, []int{8}
+}
+func (m *ConsensusVote) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ConsensusVote.Unmarshal(m, b)
+}
+func (m *ConsensusVote) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ConsensusVote.Marshal(b, m, deterministic)
+}
+func (dst *ConsensusVote) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ConsensusVote.Merge(dst, src)
+}
+func (m *ConsensusVote) XXX_Size() int {
+	return xxx_messageInfo_ConsensusVote.Size(m)
+}
+func (m *ConsensusVote) XXX_DiscardUnknown() {
+	xxx_messageInfo_ConsensusVote.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ConsensusVote proto.InternalMessageInfo
+
+func (m *ConsensusVote) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *ConsensusVote) GetHeight() int32 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *ConsensusVote) GetRound() int32 {
+	if m != nil {
+		return m.Round
+	}
+	return 0
+}
+
+func (m *ConsensusVote) GetBlockId() string {
+	if m != nil {
+		return m.BlockId
+	}
+	return ""
+}
+
+func (m *ConsensusVote) GetValidator() string {
+	if m != nil {
+		return m.Validator
+	}
+	return ""
+}
+
+func (m *ConsensusVote) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+// Proposal of a block by the proposer of a bft round, with the round it was last seen valid in if any, else -1
+type Proposal struct {
+	Block                *Block   `protobuf:"bytes,1,opt,name=Block,proto3" json:"Block,omitempty"`
+	Height               int32    `protobuf:"varint,2,opt,name=Height,proto3" json:"Height,omitempty"`
+	Round                int32    `protobuf:"varint,3,opt,name=Round,proto3" json:"Round,omitempty"`
+	ValidRound           int32    `protobuf:"varint,4,opt,name=ValidRound,proto3" json:"ValidRound,omitempty"`
+	Proposer             string   `protobuf:"bytes,5,opt,name=Proposer,proto3" json:"Proposer,omitempty"`
+	Signature            []byte   `protobuf:"bytes,6,opt,name=Signature,proto3" json:"Signature,omitempty"`
+	BlockId              string   `protobuf:"bytes,7,opt,name=BlockId,proto3" json:"BlockId,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Proposal) Reset()         { *m = Proposal{} }
+func (m *Proposal) String() string { return proto.CompactTextString(m) }
+func (*Proposal) ProtoMessage()    {}
+func (*Proposal) Descriptor() ([]byte, []int) {
+	return fileDescriptor_blockchain_10cfc2182350651d, []int{9}
+}
+func (m *Proposal) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Proposal.Unmarshal(m, b)
+}
+func (m *Proposal) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Proposal.Marshal(b, m, deterministic)
+}
+func (dst *Proposal) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Proposal.Merge(dst, src)
+}
+func (m *Proposal) XXX_Size() int {
+	return xxx_messageInfo_Proposal.Size(m)
+}
+func (m *Proposal) XXX_DiscardUnknown() {
+	xxx_messageInfo_Proposal.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Proposal proto.InternalMessageInfo
+
+func (m *Proposal) GetBlock() *Block {
+	if m != nil {
+		return m.Block
+	}
+	return nil
+}
+
+func (m *Proposal) GetHeight() int32 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *Proposal) GetRound() int32 {
+	if m != nil {
+		return m.Round
+	}
+	return 0
+}
+
+func (m *Proposal) GetValidRound() int32 {
+	if m != nil {
+		return m.ValidRound
+	}
+	return 0
+}
+
+func (m *Proposal) GetProposer() string {
+	if m != nil {
+		return m.Proposer
+	}
+	return ""
+}
+
+func (m *Proposal) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func (m *Proposal) GetBlockId() string {
+	if m != nil {
+		return m.BlockId
+	}
+	return ""
+}
+
 type User struct {
 	Addr                 string   `protobuf:"bytes,1,opt,name=Addr,proto3" json:"Addr,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -193,7 +811,7 @@ func (m *User) Reset()         { *m = User{} }
 func (m *User) String() string { return proto.CompactTextString(m) }
 func (*User) ProtoMessage()    {}
 func (*User) Descriptor() ([]byte, []int) {
-	return fileDescriptor_blockchain_d4304b0d40850632, []int{2}
+	return fileDescriptor_blockchain_10cfc2182350651d, []int{10}
 }
 func (m *User) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_User.Unmarshal(m, b)
@@ -225,6 +843,9 @@ type Chain struct {
 	OpenTxs              []*Transaction `protobuf:"bytes,2,rep,name=OpenTxs,proto3" json:"OpenTxs,omitempty"`
 	Difficulty           int32          `protobuf:"varint,3,opt,name=Difficulty,proto3" json:"Difficulty,omitempty"`
 	Usr                  *User          `protobuf:"bytes,4,opt,name=Usr,proto3" json:"Usr,omitempty"`
+	GenesisSpec          []byte         `protobuf:"bytes,5,opt,name=GenesisSpec,proto3" json:"GenesisSpec,omitempty"`
+	Proposals            []*Vote        `protobuf:"bytes,6,rep,name=Proposals,proto3" json:"Proposals,omitempty"`
+	Evidence             []*Evidence    `protobuf:"bytes,7,rep,name=Evidence,proto3" json:"Evidence,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
 	XXX_unrecognized     []byte         `json:"-"`
 	XXX_sizecache        int32          `json:"-"`
@@ -234,7 +855,7 @@ func (m *Chain) Reset()         { *m = Chain{} }
 func (m *Chain) String() string { return proto.CompactTextString(m) }
 func (*Chain) ProtoMessage()    {}
 func (*Chain) Descriptor() ([]byte, []int) {
-	return fileDescriptor_blockchain_d4304b0d40850632, []int{3}
+	return fileDescriptor_blockchain_10cfc2182350651d, []int{11}
 }
 func (m *Chain) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Chain.Unmarshal(m, b)
@@ -282,38 +903,154 @@ func (m *Chain) GetUsr() *User {
 	return nil
 }
 
+func (m *Chain) GetGenesisSpec() []byte {
+	if m != nil {
+		return m.GenesisSpec
+	}
+	return nil
+}
+
+func (m *Chain) GetProposals() []*Vote {
+	if m != nil {
+		return m.Proposals
+	}
+	return nil
+}
+
+func (m *Chain) GetEvidence() []*Evidence {
+	if m != nil {
+		return m.Evidence
+	}
+	return nil
+}
+
+// Handshake is the first message exchanged by nodes, which only talk if they are on the same network
+type Handshake struct {
+	ChainId              string   `protobuf:"bytes,1,opt,name=ChainId,proto3" json:"ChainId,omitempty"`
+	Genesis              string   `protobuf:"bytes,2,opt,name=Genesis,proto3" json:"Genesis,omitempty"`
+	Height               int32    `protobuf:"varint,3,opt,name=Height,proto3" json:"Height,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Handshake) Reset()         { *m = Handshake{} }
+func (m *Handshake) String() string { return proto.CompactTextString(m) }
+func (*Handshake) ProtoMessage()    {}
+func (*Handshake) Descriptor() ([]byte, []int) {
+	return fileDescriptor_blockchain_10cfc2182350651d, []int{12}
+}
+func (m *Handshake) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Handshake.Unmarshal(m, b)
+}
+func (m *Handshake) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Handshake.Marshal(b, m, deterministic)
+}
+func (dst *Handshake) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Handshake.Merge(dst, src)
+}
+func (m *Handshake) XXX_Size() int {
+	return xxx_messageInfo_Handshake.Size(m)
+}
+func (m *Handshake) XXX_DiscardUnknown() {
+	xxx_messageInfo_Handshake.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Handshake proto.InternalMessageInfo
+
+func (m *Handshake) GetChainId() string {
+	if m != nil {
+		return m.ChainId
+	}
+	return ""
+}
+
+func (m *Handshake) GetGenesis() string {
+	if m != nil {
+		return m.Genesis
+	}
+	return ""
+}
+
+func (m *Handshake) GetHeight() int32 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*Transaction)(nil), "pb.Transaction")
 	proto.RegisterType((*Block)(nil), "pb.Block")
+	proto.RegisterType((*BlockHeader)(nil), "pb.BlockHeader")
+	proto.RegisterType((*BlockBody)(nil), "pb.BlockBody")
+	proto.RegisterType((*Vote)(nil), "pb.Vote")
+	proto.RegisterType((*SignedHeader)(nil), "pb.SignedHeader")
+	proto.RegisterType((*Evidence)(nil), "pb.Evidence")
+	proto.RegisterType((*Commit)(nil), "pb.Commit")
+	proto.RegisterType((*ConsensusVote)(nil), "pb.ConsensusVote")
+	proto.RegisterType((*Proposal)(nil), "pb.Proposal")
 	proto.RegisterType((*User)(nil), "pb.User")
 	proto.RegisterType((*Chain)(nil), "pb.Chain")
+	proto.RegisterType((*Handshake)(nil), "pb.Handshake")
 }
 
-func init() { proto.RegisterFile("blockchain.proto", fileDescriptor_blockchain_d4304b0d40850632) }
-
-var fileDescriptor_blockchain_d4304b0d40850632 = []byte{
-	// 361 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x92, 0xb1, 0x6e, 0xdb, 0x30,
-	0x10, 0x86, 0x41, 0xc9, 0x92, 0xed, 0x33, 0xe0, 0x1a, 0x44, 0x51, 0x10, 0x42, 0x51, 0xa8, 0x42,
-	0x07, 0x75, 0xf1, 0xe0, 0x3e, 0x41, 0xdd, 0x0e, 0xf5, 0x54, 0x83, 0x91, 0xb3, 0x53, 0x12, 0x0d,
-	0x13, 0x91, 0x29, 0x81, 0xa4, 0x03, 0xe7, 0x31, 0x32, 0xe7, 0x71, 0xf2, 0x62, 0x01, 0x4f, 0x8a,
-	0x1d, 0x64, 0xc9, 0x76, 0xff, 0x7f, 0x07, 0xde, 0x77, 0x77, 0x84, 0x45, 0xd9, 0xb4, 0xd5, 0x5d,
-	0x75, 0x10, 0x4a, 0x2f, 0x3b, 0xd3, 0xba, 0x96, 0x06, 0x5d, 0x99, 0xcc, 0x3b, 0xe1, 0x8c, 0xaa,
-	0x94, 0xe8, 0xbd, 0xec, 0x89, 0xc0, 0xac, 0x30, 0x42, 0x5b, 0x51, 0x39, 0xd5, 0x6a, 0x3a, 0x87,
-	0x60, 0x53, 0x33, 0x92, 0x92, 0x7c, 0xca, 0x83, 0x4d, 0x4d, 0xbf, 0x40, 0x7c, 0x23, 0x75, 0x2d,
-	0x0d, 0x0b, 0xd0, 0x1b, 0x14, 0xfd, 0x0a, 0x53, 0x2e, 0x2b, 0xd5, 0x29, 0xa9, 0x1d, 0x0b, 0x31,
-	0x75, 0x35, 0xe8, 0x02, 0xc2, 0x5b, 0xd1, 0xb0, 0x51, 0x4a, 0x72, 0xc2, 0x7d, 0xe8, 0xeb, 0x0b,
-	0x75, 0x94, 0xd6, 0x89, 0x63, 0xc7, 0xa2, 0x94, 0xe4, 0x21, 0xbf, 0x1a, 0xd8, 0xc5, 0x09, 0x77,
-	0xb2, 0x2c, 0x1e, 0xba, 0xa0, 0xca, 0x9e, 0x09, 0x44, 0x6b, 0x3f, 0x06, 0xfd, 0x0c, 0xd1, 0x46,
-	0xd7, 0xf2, 0x8c, 0x68, 0x11, 0xef, 0x05, 0xa5, 0x30, 0xfa, 0x27, 0xec, 0x61, 0x60, 0xc3, 0x98,
-	0x26, 0x30, 0xd9, 0x1a, 0x79, 0x8f, 0x7e, 0x0f, 0x76, 0xd1, 0xfe, 0x95, 0xad, 0x69, 0xdb, 0x3d,
-	0x92, 0x85, 0xbc, 0x17, 0x1f, 0xb0, 0x25, 0x10, 0x16, 0xe7, 0x1e, 0x6c, 0xb6, 0x9a, 0x2c, 0xbb,
-	0x72, 0x59, 0x18, 0x29, 0xb9, 0x37, 0xe9, 0x0f, 0x98, 0xac, 0x45, 0x23, 0x74, 0x25, 0x2d, 0x1b,
-	0xbf, 0x2b, 0xb8, 0x64, 0xb2, 0x04, 0x46, 0x3b, 0x2b, 0x8d, 0xa7, 0xfd, 0x5d, 0xd7, 0x66, 0xd8,
-	0x2e, 0xc6, 0xd9, 0x23, 0x81, 0xe8, 0x8f, 0xbf, 0x11, 0xfd, 0x0e, 0x31, 0x8e, 0x6a, 0x19, 0x49,
-	0xc3, 0x7c, 0xb6, 0x9a, 0xfa, 0x97, 0xd0, 0xe1, 0x43, 0x82, 0xfe, 0x84, 0xf1, 0xff, 0x4e, 0x6a,
-	0x8f, 0x13, 0x60, 0xcd, 0xa7, 0xbe, 0xdb, 0xe5, 0x7c, 0xfc, 0x35, 0x4f, 0xbf, 0x01, 0xfc, 0x55,
-	0xfb, 0xbd, 0xaa, 0x4e, 0x8d, 0x7b, 0xc0, 0x3d, 0x44, 0xfc, 0x8d, 0xe3, 0xa7, 0xda, 0x59, 0x83,
-	0x7b, 0x18, 0xa0, 0x3d, 0x22, 0xf7, 0x66, 0x19, 0xe3, 0xd7, 0xf8, 0xf5, 0x12, 0x00, 0x00, 0xff,
-	0xff, 0xbc, 0xd0, 0x2f, 0x1a, 0x42, 0x02, 0x00, 0x00,
+func init() { proto.RegisterFile("blockchain.proto", fileDescriptor_blockchain_10cfc2182350651d) }
+
+var fileDescriptor_blockchain_10cfc2182350651d = []byte{
+	// 859 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x56, 0xcd, 0x6e, 0x23, 0x45,
+	0x10, 0xd6, 0xfc, 0xda, 0x2e, 0x7b, 0xb3, 0xa1, 0xb5, 0x5a, 0xb5, 0x2c, 0x14, 0xcc, 0x08, 0x81,
+	0xb9, 0x44, 0x02, 0x24, 0xee, 0x71, 0x58, 0x11, 0x23, 0x01, 0x51, 0xaf, 0x13, 0xce, 0xed, 0x99,
+	0xce, 0x66, 0xb4, 0xce, 0xf4, 0x68, 0xa6, 0xbd, 0x72, 0xce, 0x88, 0x67, 0xe1, 0x09, 0x38, 0xf2,
+	0x00, 0x1c, 0x78, 0x27, 0x54, 0xd5, 0x3d, 0x9e, 0x19, 0x93, 0x1f, 0xc1, 0xad, 0xeb, 0xab, 0x9e,
+	0xee, 0xfa, 0xea, 0xfb, 0xba, 0x6c, 0x38, 0x5e, 0x6f, 0x74, 0xfa, 0x3e, 0xbd, 0x95, 0x79, 0x71,
+	0x5a, 0x56, 0xda, 0x68, 0xe6, 0x97, 0xeb, 0xe9, 0x51, 0x29, 0x4d, 0x95, 0xa7, 0xb9, 0xb4, 0x58,
+	0xf2, 0x97, 0x07, 0xe3, 0x55, 0x25, 0x8b, 0x5a, 0xa6, 0x26, 0xd7, 0x05, 0x3b, 0x02, 0x7f, 0x99,
+	0x71, 0x6f, 0xe6, 0xcd, 0x47, 0xc2, 0x5f, 0x66, 0xec, 0x35, 0xc4, 0x6f, 0x55, 0x91, 0xa9, 0x8a,
+	0xfb, 0x84, 0xb9, 0x88, 0x7d, 0x0c, 0x23, 0xa1, 0xd2, 0xbc, 0xcc, 0x55, 0x61, 0x78, 0x40, 0xa9,
+	0x16, 0x60, 0xc7, 0x10, 0x5c, 0xcb, 0x0d, 0x0f, 0x67, 0xde, 0xdc, 0x13, 0xb8, 0xc4, 0xfd, 0xab,
+	0xfc, 0x4e, 0xd5, 0x46, 0xde, 0x95, 0x3c, 0x9a, 0x79, 0xf3, 0x40, 0xb4, 0x00, 0xdd, 0x62, 0xa4,
+	0xd9, 0xd6, 0x3c, 0x76, 0xb7, 0x50, 0xc4, 0x38, 0x0c, 0xce, 0x91, 0xc0, 0x32, 0xe3, 0x03, 0x4a,
+	0x34, 0x21, 0x63, 0x10, 0xae, 0xee, 0x4b, 0xc5, 0x87, 0x04, 0xd3, 0x3a, 0xf9, 0x33, 0x80, 0x68,
+	0x81, 0xa4, 0xd9, 0x2b, 0x88, 0x96, 0x45, 0xa6, 0x76, 0x44, 0x24, 0x12, 0x36, 0xc0, 0x6f, 0x2e,
+	0x64, 0x7d, 0xeb, 0x98, 0xd0, 0x9a, 0x4d, 0x61, 0x78, 0x59, 0xa9, 0x0f, 0x84, 0x5b, 0x1a, 0xfb,
+	0x18, 0x4f, 0xb9, 0xac, 0xb4, 0xbe, 0x21, 0x1e, 0x81, 0xb0, 0xc1, 0x33, 0x4c, 0xa6, 0x10, 0xac,
+	0x76, 0x96, 0xc6, 0xf8, 0xeb, 0xe1, 0x69, 0xb9, 0x3e, 0x5d, 0x55, 0x4a, 0x09, 0x04, 0xd9, 0x67,
+	0x30, 0x5c, 0xc8, 0x8d, 0x2c, 0x52, 0x55, 0xf3, 0xc1, 0xc1, 0x86, 0x7d, 0xa6, 0xcb, 0x79, 0xd8,
+	0xe7, 0x8c, 0x5d, 0xca, 0xdf, 0x15, 0xaa, 0xe2, 0x23, 0xd7, 0x25, 0x8a, 0xb0, 0x22, 0x5c, 0x49,
+	0xb3, 0xad, 0x14, 0x87, 0x99, 0x37, 0x9f, 0x88, 0x16, 0x60, 0x27, 0x10, 0x5d, 0x6b, 0xa3, 0x6a,
+	0x3e, 0x9e, 0x05, 0xcd, 0x95, 0x08, 0x08, 0x0b, 0xb3, 0x39, 0x0c, 0xdf, 0x7c, 0xc8, 0x33, 0x55,
+	0xa4, 0x8a, 0x4f, 0x68, 0xcb, 0x04, 0xb7, 0x34, 0x98, 0xd8, 0x67, 0x59, 0x02, 0xf1, 0xb9, 0xbe,
+	0xbb, 0xcb, 0x0d, 0x7f, 0x41, 0xd5, 0x03, 0xee, 0xb3, 0x88, 0x70, 0x19, 0x76, 0x02, 0xf0, 0x5d,
+	0x7e, 0x73, 0x93, 0xa7, 0xdb, 0x8d, 0xb9, 0xe7, 0x47, 0xd4, 0xfe, 0x0e, 0x82, 0xfd, 0x3e, 0xd7,
+	0x79, 0xb1, 0x96, 0xb5, 0xe2, 0x2f, 0x6d, 0xbf, 0x9b, 0x38, 0xf9, 0xc3, 0x87, 0x31, 0xe9, 0x77,
+	0xa1, 0x24, 0x7a, 0xec, 0x61, 0x15, 0xbb, 0x8a, 0xf9, 0x07, 0x8a, 0xbd, 0x86, 0x78, 0xb5, 0x13,
+	0x5a, 0x37, 0x96, 0x74, 0x11, 0x75, 0xc8, 0x48, 0xa3, 0x28, 0x15, 0x52, 0xaa, 0x05, 0x9e, 0x51,
+	0xb4, 0xcf, 0x28, 0xfe, 0x17, 0xa3, 0x57, 0x10, 0xfd, 0xa4, 0xb1, 0x79, 0x03, 0xeb, 0x12, 0x0a,
+	0xfe, 0x9f, 0x8a, 0x6f, 0x76, 0xa6, 0x92, 0x44, 0x0c, 0x6c, 0x8d, 0x7b, 0xa0, 0xd7, 0xb7, 0xf1,
+	0x41, 0xdf, 0x7e, 0x84, 0x11, 0xb5, 0x6d, 0xa1, 0xb3, 0xfb, 0xc6, 0x80, 0xde, 0x73, 0x06, 0xf4,
+	0x1f, 0x33, 0x60, 0xf2, 0x2d, 0x84, 0xe8, 0x0c, 0x2c, 0xe8, 0x5a, 0x6e, 0xf2, 0x4c, 0x1a, 0x5d,
+	0xb9, 0x89, 0xd0, 0x02, 0xf8, 0xc4, 0xcf, 0xb2, 0x8c, 0x8e, 0x19, 0x0a, 0x5c, 0x26, 0x57, 0x30,
+	0x21, 0x2a, 0x99, 0x93, 0xef, 0x0b, 0x88, 0xed, 0xca, 0x15, 0xf3, 0x12, 0xef, 0xea, 0xe8, 0x2b,
+	0x5c, 0xba, 0xef, 0x5f, 0xff, 0xc0, 0xbf, 0xc9, 0x0f, 0xad, 0x3f, 0xd9, 0x09, 0x78, 0x67, 0xee,
+	0xb4, 0x63, 0x3c, 0xad, 0x7b, 0x9f, 0xf0, 0xce, 0x30, 0xbf, 0xe0, 0xfe, 0x63, 0xf9, 0x45, 0xf2,
+	0xab, 0xd7, 0x58, 0x18, 0x65, 0xb8, 0x50, 0xf9, 0xbb, 0x5b, 0xe3, 0xdc, 0xe5, 0x22, 0x94, 0x53,
+	0xe8, 0x6d, 0x61, 0x99, 0x45, 0xc2, 0x06, 0x28, 0x27, 0x55, 0xbe, 0xcc, 0x9c, 0xb3, 0x9a, 0x90,
+	0x7d, 0x05, 0x70, 0x59, 0xa9, 0x94, 0x0e, 0xad, 0x79, 0x48, 0x0f, 0xe8, 0x23, 0xfb, 0x30, 0x8a,
+	0x5a, 0x15, 0xf5, 0xb6, 0xa6, 0xc7, 0xd6, 0xd9, 0x94, 0xfc, 0xee, 0xc1, 0x8b, 0x5e, 0x76, 0x3f,
+	0xcd, 0xbc, 0x76, 0x9a, 0x75, 0x0a, 0xf4, 0x1f, 0x2e, 0x30, 0x78, 0xa4, 0xc0, 0xb0, 0x5f, 0x60,
+	0x4f, 0xc6, 0xe8, 0x50, 0xc6, 0x5e, 0xef, 0xe3, 0xc3, 0xde, 0xff, 0xed, 0xe1, 0x63, 0xd3, 0xa5,
+	0xae, 0xe5, 0x86, 0x7d, 0xe2, 0xa6, 0xab, 0x13, 0x60, 0xb4, 0x97, 0x53, 0x58, 0xfc, 0x3f, 0x56,
+	0x7c, 0x02, 0x40, 0x65, 0xd8, 0x54, 0x48, 0xa9, 0x0e, 0xc2, 0xa6, 0xcd, 0xd5, 0xaa, 0x29, 0x7b,
+	0x1f, 0x3f, 0x5d, 0x75, 0xb7, 0x17, 0x83, 0x5e, 0x2f, 0x92, 0x29, 0x84, 0x57, 0xf8, 0x3d, 0x83,
+	0xf0, 0x2c, 0xcb, 0x1a, 0x57, 0xd3, 0x3a, 0xf9, 0xcd, 0x87, 0x88, 0xde, 0x28, 0xfb, 0x14, 0x62,
+	0xfa, 0x00, 0x5f, 0x51, 0xd0, 0x67, 0xea, 0x12, 0xec, 0x4b, 0x18, 0xfc, 0x5c, 0xaa, 0x02, 0x5f,
+	0x9a, 0x3f, 0x0b, 0x1a, 0x73, 0x77, 0x7e, 0x48, 0x45, 0x93, 0x3f, 0x98, 0x1f, 0xc1, 0x03, 0x13,
+	0x31, 0xb8, 0xaa, 0x2b, 0x1e, 0xb6, 0xef, 0x11, 0x4b, 0x14, 0x08, 0xb2, 0x19, 0x8c, 0xbf, 0x57,
+	0x85, 0xaa, 0xf3, 0xfa, 0x6d, 0xa9, 0x52, 0x6a, 0xc3, 0x44, 0x74, 0x21, 0xf6, 0x39, 0x8c, 0x1a,
+	0x81, 0xf0, 0x57, 0xa7, 0x3f, 0xe1, 0xdb, 0x54, 0x6f, 0xca, 0x0f, 0x9e, 0x9a, 0xf2, 0xc9, 0x2f,
+	0x30, 0xba, 0x90, 0x45, 0x56, 0xdf, 0xca, 0xf7, 0xbd, 0x31, 0xe6, 0xf5, 0xc7, 0x18, 0x87, 0x81,
+	0xab, 0xc3, 0x4d, 0xe1, 0x26, 0xec, 0xd8, 0x20, 0xe8, 0xda, 0x60, 0x1d, 0xd3, 0x3f, 0x8e, 0x6f,
+	0xfe, 0x19, 0x00, 0x76, 0x05, 0x0f, 0x57, 0x99, 0x08, 0x00, 0x00,
 }
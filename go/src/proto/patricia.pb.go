@@ -24,6 +24,7 @@ type Node struct {
 	Val                  string            `protobuf:"bytes,3,opt,name=Val,proto3" json:"Val,omitempty"`
 	Count                int32             `protobuf:"varint,4,opt,name=Count,proto3" json:"Count,omitempty"`
 	EncodedPaths         map[string]string `protobuf:"bytes,5,rep,name=EncodedPaths,proto3" json:"EncodedPaths,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Key                  string            `protobuf:"bytes,6,opt,name=Key,proto3" json:"Key,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
 	XXX_unrecognized     []byte            `json:"-"`
 	XXX_sizecache        int32             `json:"-"`
@@ -33,7 +34,7 @@ func (m *Node) Reset()         { *m = Node{} }
 func (m *Node) String() string { return proto.CompactTextString(m) }
 func (*Node) ProtoMessage()    {}
 func (*Node) Descriptor() ([]byte, []int) {
-	return fileDescriptor_patricia_76c2e0332967e273, []int{0}
+	return fileDescriptor_patricia_efa377143fb21cae, []int{0}
 }
 func (m *Node) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Node.Unmarshal(m, b)
@@ -88,12 +89,24 @@ func (m *Node) GetEncodedPaths() map[string]string {
 	return nil
 }
 
+func (m *Node) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
 type Tree struct {
 	Root                 *Node            `protobuf:"bytes,1,opt,name=Root,proto3" json:"Root,omitempty"`
 	Ht                   map[string]*Node `protobuf:"bytes,2,rep,name=Ht,proto3" json:"Ht,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
 	LastRadixCompression int64            `protobuf:"varint,3,opt,name=LastRadixCompression,proto3" json:"LastRadixCompression,omitempty"`
 	BatchSize            int64            `protobuf:"varint,4,opt,name=BatchSize,proto3" json:"BatchSize,omitempty"`
 	Zipped               bool             `protobuf:"varint,5,opt,name=Zipped,proto3" json:"Zipped,omitempty"`
+	KeyCodec             string           `protobuf:"bytes,6,opt,name=KeyCodec,proto3" json:"KeyCodec,omitempty"`
+	ValCodec             string           `protobuf:"bytes,7,opt,name=ValCodec,proto3" json:"ValCodec,omitempty"`
+	Dict                 []byte           `protobuf:"bytes,8,opt,name=Dict,proto3" json:"Dict,omitempty"`
+	Kind                 string           `protobuf:"bytes,10,opt,name=Kind,proto3" json:"Kind,omitempty"`
+	ValDict              []byte           `protobuf:"bytes,11,opt,name=ValDict,proto3" json:"ValDict,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
 	XXX_unrecognized     []byte           `json:"-"`
 	XXX_sizecache        int32            `json:"-"`
@@ -103,7 +116,7 @@ func (m *Tree) Reset()         { *m = Tree{} }
 func (m *Tree) String() string { return proto.CompactTextString(m) }
 func (*Tree) ProtoMessage()    {}
 func (*Tree) Descriptor() ([]byte, []int) {
-	return fileDescriptor_patricia_76c2e0332967e273, []int{1}
+	return fileDescriptor_patricia_efa377143fb21cae, []int{1}
 }
 func (m *Tree) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Tree.Unmarshal(m, b)
@@ -158,35 +171,273 @@ func (m *Tree) GetZipped() bool {
 	return false
 }
 
+func (m *Tree) GetKeyCodec() string {
+	if m != nil {
+		return m.KeyCodec
+	}
+	return ""
+}
+
+func (m *Tree) GetValCodec() string {
+	if m != nil {
+		return m.ValCodec
+	}
+	return ""
+}
+
+func (m *Tree) GetDict() []byte {
+	if m != nil {
+		return m.Dict
+	}
+	return nil
+}
+
+func (m *Tree) GetKind() string {
+	if m != nil {
+		return m.Kind
+	}
+	return ""
+}
+
+func (m *Tree) GetValDict() []byte {
+	if m != nil {
+		return m.ValDict
+	}
+	return nil
+}
+
+// TreeHeader starts a streamed export of a Tree, it is followed by the node records
+type TreeHeader struct {
+	RootHash             string   `protobuf:"bytes,1,opt,name=RootHash,proto3" json:"RootHash,omitempty"`
+	LastRadixCompression int64    `protobuf:"varint,2,opt,name=LastRadixCompression,proto3" json:"LastRadixCompression,omitempty"`
+	BatchSize            int64    `protobuf:"varint,3,opt,name=BatchSize,proto3" json:"BatchSize,omitempty"`
+	Zipped               bool     `protobuf:"varint,4,opt,name=Zipped,proto3" json:"Zipped,omitempty"`
+	KeyCodec             string   `protobuf:"bytes,5,opt,name=KeyCodec,proto3" json:"KeyCodec,omitempty"`
+	ValCodec             string   `protobuf:"bytes,6,opt,name=ValCodec,proto3" json:"ValCodec,omitempty"`
+	Dict                 []byte   `protobuf:"bytes,7,opt,name=Dict,proto3" json:"Dict,omitempty"`
+	NodeCount            int64    `protobuf:"varint,8,opt,name=NodeCount,proto3" json:"NodeCount,omitempty"`
+	ValDict              []byte   `protobuf:"bytes,10,opt,name=ValDict,proto3" json:"ValDict,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TreeHeader) Reset()         { *m = TreeHeader{} }
+func (m *TreeHeader) String() string { return proto.CompactTextString(m) }
+func (*TreeHeader) ProtoMessage()    {}
+func (*TreeHeader) Descriptor() ([]byte, []int) {
+	return fileDescriptor_patricia_efa377143fb21cae, []int{2}
+}
+func (m *TreeHeader) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TreeHeader.Unmarshal(m, b)
+}
+func (m *TreeHeader) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TreeHeader.Marshal(b, m, deterministic)
+}
+func (dst *TreeHeader) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TreeHeader.Merge(dst, src)
+}
+func (m *TreeHeader) XXX_Size() int {
+	return xxx_messageInfo_TreeHeader.Size(m)
+}
+func (m *TreeHeader) XXX_DiscardUnknown() {
+	xxx_messageInfo_TreeHeader.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TreeHeader proto.InternalMessageInfo
+
+func (m *TreeHeader) GetRootHash() string {
+	if m != nil {
+		return m.RootHash
+	}
+	return ""
+}
+
+func (m *TreeHeader) GetLastRadixCompression() int64 {
+	if m != nil {
+		return m.LastRadixCompression
+	}
+	return 0
+}
+
+func (m *TreeHeader) GetBatchSize() int64 {
+	if m != nil {
+		return m.BatchSize
+	}
+	return 0
+}
+
+func (m *TreeHeader) GetZipped() bool {
+	if m != nil {
+		return m.Zipped
+	}
+	return false
+}
+
+func (m *TreeHeader) GetKeyCodec() string {
+	if m != nil {
+		return m.KeyCodec
+	}
+	return ""
+}
+
+func (m *TreeHeader) GetValCodec() string {
+	if m != nil {
+		return m.ValCodec
+	}
+	return ""
+}
+
+func (m *TreeHeader) GetDict() []byte {
+	if m != nil {
+		return m.Dict
+	}
+	return nil
+}
+
+func (m *TreeHeader) GetNodeCount() int64 {
+	if m != nil {
+		return m.NodeCount
+	}
+	return 0
+}
+
+func (m *TreeHeader) GetValDict() []byte {
+	if m != nil {
+		return m.ValDict
+	}
+	return nil
+}
+
+// MultiProof holds the nodes on the paths of a set of keys, each node once, with the codecs of the trie
+type MultiProof struct {
+	Root                 string   `protobuf:"bytes,1,opt,name=Root,proto3" json:"Root,omitempty"`
+	Nodes                []*Node  `protobuf:"bytes,2,rep,name=Nodes,proto3" json:"Nodes,omitempty"`
+	Zipped               bool     `protobuf:"varint,3,opt,name=Zipped,proto3" json:"Zipped,omitempty"`
+	KeyCodec             string   `protobuf:"bytes,4,opt,name=KeyCodec,proto3" json:"KeyCodec,omitempty"`
+	ValCodec             string   `protobuf:"bytes,5,opt,name=ValCodec,proto3" json:"ValCodec,omitempty"`
+	Dict                 []byte   `protobuf:"bytes,6,opt,name=Dict,proto3" json:"Dict,omitempty"`
+	ValDict              []byte   `protobuf:"bytes,7,opt,name=ValDict,proto3" json:"ValDict,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MultiProof) Reset()         { *m = MultiProof{} }
+func (m *MultiProof) String() string { return proto.CompactTextString(m) }
+func (*MultiProof) ProtoMessage()    {}
+func (*MultiProof) Descriptor() ([]byte, []int) {
+	return fileDescriptor_patricia_efa377143fb21cae, []int{3}
+}
+func (m *MultiProof) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MultiProof.Unmarshal(m, b)
+}
+func (m *MultiProof) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MultiProof.Marshal(b, m, deterministic)
+}
+func (dst *MultiProof) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MultiProof.Merge(dst, src)
+}
+func (m *MultiProof) XXX_Size() int {
+	return xxx_messageInfo_MultiProof.Size(m)
+}
+func (m *MultiProof) XXX_DiscardUnknown() {
+	xxx_messageInfo_MultiProof.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MultiProof proto.InternalMessageInfo
+
+func (m *MultiProof) GetRoot() string {
+	if m != nil {
+		return m.Root
+	}
+	return ""
+}
+
+func (m *MultiProof) GetNodes() []*Node {
+	if m != nil {
+		return m.Nodes
+	}
+	return nil
+}
+
+func (m *MultiProof) GetZipped() bool {
+	if m != nil {
+		return m.Zipped
+	}
+	return false
+}
+
+func (m *MultiProof) GetKeyCodec() string {
+	if m != nil {
+		return m.KeyCodec
+	}
+	return ""
+}
+
+func (m *MultiProof) GetValCodec() string {
+	if m != nil {
+		return m.ValCodec
+	}
+	return ""
+}
+
+func (m *MultiProof) GetDict() []byte {
+	if m != nil {
+		return m.Dict
+	}
+	return nil
+}
+
+func (m *MultiProof) GetValDict() []byte {
+	if m != nil {
+		return m.ValDict
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Node)(nil), "pb.Node")
 	proto.RegisterMapType((map[string]string)(nil), "pb.Node.EncodedPathsEntry")
 	proto.RegisterType((*Tree)(nil), "pb.Tree")
 	proto.RegisterMapType((map[string]*Node)(nil), "pb.Tree.HtEntry")
+	proto.RegisterType((*TreeHeader)(nil), "pb.TreeHeader")
+	proto.RegisterType((*MultiProof)(nil), "pb.MultiProof")
 }
 
-func init() { proto.RegisterFile("patricia.proto", fileDescriptor_patricia_76c2e0332967e273) }
-
-var fileDescriptor_patricia_76c2e0332967e273 = []byte{
-	// 314 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x51, 0x41, 0x4b, 0x33, 0x31,
-	0x14, 0x24, 0xc9, 0x6e, 0xbf, 0xf6, 0xf5, 0x43, 0x6a, 0x28, 0x12, 0x4a, 0x91, 0xd0, 0xd3, 0x9e,
-	0xf6, 0xb0, 0x5e, 0xc4, 0x83, 0x05, 0x4b, 0x61, 0x0f, 0x52, 0x24, 0x8a, 0x07, 0x6f, 0x69, 0x37,
-	0xd0, 0x60, 0xdd, 0x84, 0xdd, 0x54, 0x5a, 0xff, 0xa8, 0xbf, 0xc3, 0x7f, 0x20, 0xc9, 0x56, 0xba,
-	0x62, 0x6f, 0xf3, 0xe6, 0x4d, 0x98, 0x99, 0x17, 0x38, 0xb3, 0xd2, 0x55, 0x7a, 0xa5, 0x65, 0x6a,
-	0x2b, 0xe3, 0x0c, 0xc5, 0x76, 0x39, 0xf9, 0x44, 0x10, 0x2d, 0x4c, 0xa1, 0x28, 0x85, 0x28, 0x97,
-	0xf5, 0x9a, 0x21, 0x8e, 0x92, 0x9e, 0x08, 0xd8, 0x73, 0x0b, 0xb5, 0x73, 0x0c, 0x73, 0xe2, 0x39,
-	0x8f, 0xe9, 0x00, 0xc8, 0xb3, 0xdc, 0x30, 0x12, 0x64, 0x1e, 0xd2, 0x21, 0xc4, 0x33, 0xb3, 0x2d,
-	0x1d, 0x8b, 0x38, 0x4a, 0x62, 0xd1, 0x0c, 0xf4, 0x16, 0xfe, 0xcf, 0xcb, 0x95, 0x29, 0x54, 0xf1,
-	0x20, 0xdd, 0xba, 0x66, 0x31, 0x27, 0x49, 0x3f, 0x1b, 0xa5, 0x76, 0x99, 0x7a, 0xbf, 0xb4, 0xbd,
-	0x9c, 0x97, 0xae, 0xda, 0x8b, 0x5f, 0xfa, 0xd1, 0x14, 0xce, 0xff, 0x48, 0xbc, 0xf9, 0xab, 0xda,
-	0x1f, 0x32, 0x7a, 0xe8, 0xcd, 0xdf, 0xe5, 0x66, 0xab, 0x18, 0x0e, 0x5c, 0x33, 0xdc, 0xe0, 0x6b,
-	0x34, 0xf9, 0x42, 0x10, 0x3d, 0x55, 0x4a, 0xd1, 0x31, 0x44, 0xc2, 0x18, 0x17, 0x5e, 0xf5, 0xb3,
-	0xee, 0x4f, 0x02, 0x11, 0x58, 0xca, 0x01, 0xe7, 0x4d, 0xc3, 0x7e, 0x36, 0xf0, 0x3b, 0xff, 0x26,
-	0xcd, 0x5d, 0x93, 0x09, 0xe7, 0x8e, 0x66, 0x30, 0xbc, 0x97, 0xb5, 0x13, 0xb2, 0xd0, 0xbb, 0x99,
-	0x79, 0xb3, 0x95, 0xaa, 0x6b, 0x6d, 0xca, 0x70, 0x02, 0x22, 0x4e, 0xee, 0xe8, 0x18, 0x7a, 0x77,
-	0xd2, 0xad, 0xd6, 0x8f, 0xfa, 0x43, 0x85, 0xbb, 0x10, 0x71, 0x24, 0xe8, 0x05, 0x74, 0x5e, 0xb4,
-	0xb5, 0xaa, 0x60, 0x31, 0x47, 0x49, 0x57, 0x1c, 0xa6, 0xd1, 0x14, 0xfe, 0x1d, 0x8c, 0x4f, 0x34,
-	0xbd, 0x6c, 0x37, 0x6d, 0xf7, 0x38, 0x76, 0x5e, 0x76, 0xc2, 0xc7, 0x5e, 0x7d, 0x07, 0x00, 0x00,
-	0xff, 0xff, 0xec, 0x9a, 0x0e, 0x2b, 0xea, 0x01, 0x00, 0x00,
+func init() { proto.RegisterFile("patricia.proto", fileDescriptor_patricia_efa377143fb21cae) }
+
+var fileDescriptor_patricia_efa377143fb21cae = []byte{
+	// 486 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x94, 0xcf, 0x8a, 0xdb, 0x30,
+	0x10, 0xc6, 0xf1, 0xbf, 0xd8, 0x99, 0x2c, 0x65, 0x2b, 0x96, 0x22, 0x42, 0x58, 0x4c, 0x4e, 0x3e,
+	0xf9, 0x90, 0x5e, 0x4a, 0x0f, 0x5d, 0x68, 0xba, 0x60, 0x48, 0xbb, 0x2c, 0x6a, 0xc9, 0xa1, 0x37,
+	0xc5, 0x56, 0x89, 0xa9, 0x6b, 0x19, 0x5b, 0x29, 0xeb, 0x3e, 0x48, 0x1f, 0xa7, 0xef, 0x53, 0xfa,
+	0x12, 0x65, 0x24, 0x27, 0x55, 0x68, 0xbd, 0xf4, 0x36, 0xf3, 0xcd, 0xc8, 0x9a, 0xef, 0xa7, 0xc1,
+	0xf0, 0xa4, 0xe1, 0xaa, 0x2d, 0xf3, 0x92, 0xa7, 0x4d, 0x2b, 0x95, 0x24, 0x6e, 0xb3, 0x5b, 0xfe,
+	0x74, 0xc0, 0xbf, 0x93, 0x85, 0x20, 0x04, 0xfc, 0x8c, 0x77, 0x7b, 0xea, 0xc4, 0x4e, 0x32, 0x65,
+	0x3a, 0x46, 0xed, 0x4e, 0x3c, 0x28, 0xea, 0xc6, 0x1e, 0x6a, 0x18, 0x93, 0x4b, 0xf0, 0xb6, 0xbc,
+	0xa2, 0x9e, 0x6e, 0xc3, 0x90, 0x5c, 0x41, 0xb0, 0x96, 0x87, 0x5a, 0x51, 0x3f, 0x76, 0x92, 0x80,
+	0x99, 0x84, 0xbc, 0x82, 0x8b, 0xdb, 0x3a, 0x97, 0x85, 0x28, 0xee, 0xb9, 0xda, 0x77, 0x34, 0x88,
+	0xbd, 0x64, 0xb6, 0x9a, 0xa7, 0xcd, 0x2e, 0xc5, 0xfb, 0x52, 0xbb, 0x78, 0x5b, 0xab, 0xb6, 0x67,
+	0x67, 0xfd, 0x78, 0xcf, 0x46, 0xf4, 0x74, 0x62, 0xee, 0xd9, 0x88, 0x7e, 0x7e, 0x03, 0x4f, 0xff,
+	0x3a, 0x84, 0x6d, 0x9f, 0x45, 0x3f, 0x4c, 0x8d, 0x21, 0x8e, 0xf3, 0x95, 0x57, 0x07, 0x41, 0x5d,
+	0xad, 0x99, 0xe4, 0xa5, 0xfb, 0xc2, 0x59, 0xfe, 0x72, 0xc1, 0xff, 0xd0, 0x0a, 0x41, 0x16, 0xe0,
+	0x33, 0x29, 0x95, 0x3e, 0x35, 0x5b, 0x45, 0xc7, 0x99, 0x98, 0x56, 0x49, 0x0c, 0x6e, 0x66, 0x3c,
+	0xcf, 0x56, 0x97, 0x58, 0xc3, 0x33, 0x69, 0xa6, 0xcc, 0x94, 0x6e, 0xa6, 0xc8, 0x0a, 0xae, 0xde,
+	0xf2, 0x4e, 0x31, 0x5e, 0x94, 0x0f, 0x6b, 0xf9, 0xa5, 0x69, 0x45, 0xd7, 0x95, 0xb2, 0xd6, 0x50,
+	0x3c, 0xf6, 0xcf, 0x1a, 0x59, 0xc0, 0xf4, 0x35, 0x57, 0xf9, 0xfe, 0x7d, 0xf9, 0x4d, 0x68, 0x52,
+	0x1e, 0xfb, 0x23, 0x90, 0x67, 0x30, 0xf9, 0x58, 0x36, 0x8d, 0x28, 0x68, 0x10, 0x3b, 0x49, 0xc4,
+	0x86, 0x8c, 0xcc, 0x21, 0xda, 0x88, 0x7e, 0x2d, 0x0b, 0x91, 0x0f, 0x28, 0x4e, 0x39, 0xd6, 0xb6,
+	0xbc, 0x32, 0xb5, 0xd0, 0xd4, 0x8e, 0x39, 0xbe, 0xdc, 0x9b, 0x32, 0x57, 0x34, 0x8a, 0x9d, 0xe4,
+	0x82, 0xe9, 0x18, 0xb5, 0x4d, 0x59, 0x17, 0x14, 0xcc, 0x0b, 0x63, 0x4c, 0x28, 0x84, 0x5b, 0x5e,
+	0xe9, 0xd6, 0x99, 0x6e, 0x3d, 0xa6, 0xf3, 0x1b, 0x08, 0x33, 0x35, 0xc6, 0xf8, 0xda, 0x66, 0x6c,
+	0x13, 0xb4, 0x68, 0x7f, 0x77, 0x01, 0x90, 0x5c, 0x26, 0x78, 0x21, 0x5a, 0x9c, 0x16, 0xe9, 0x5a,
+	0x3b, 0x76, 0xca, 0x47, 0x79, 0xba, 0xff, 0xcb, 0xd3, 0x1b, 0xe7, 0xe9, 0x8f, 0xf2, 0x0c, 0x1e,
+	0xe1, 0x39, 0x19, 0xe1, 0x19, 0x5a, 0x3c, 0x17, 0x30, 0x45, 0xcf, 0x66, 0xf7, 0x23, 0x33, 0xc1,
+	0x49, 0xb0, 0xc9, 0xc2, 0x19, 0xd9, 0xe5, 0x0f, 0x07, 0xe0, 0xdd, 0xa1, 0x52, 0xe5, 0x7d, 0x2b,
+	0xe5, 0x27, 0xfc, 0xf4, 0x69, 0x19, 0xa7, 0xc3, 0x0a, 0x5e, 0x43, 0x80, 0x5f, 0xea, 0x86, 0x2d,
+	0xb4, 0xf8, 0x6a, 0xd9, 0xb2, 0xe7, 0x8d, 0xda, 0xf3, 0x1f, 0xb1, 0x17, 0x8c, 0xd8, 0x9b, 0x58,
+	0xf6, 0x2c, 0x03, 0xe1, 0x99, 0x81, 0xdd, 0x44, 0xff, 0x3e, 0x9e, 0xff, 0x1e, 0x00, 0x5d, 0x7a,
+	0x48, 0xbf, 0x50, 0x04, 0x00, 0x00,
 }
@@ -10,15 +10,23 @@ import (
 	"github.com/gogo/protobuf/proto"
 )
 
+// GetHash returns the hash of the content of the node, i.e. without its own Hash field,
+// so that any node can be verified on its own
 func GetHash(n *pb.Node) (string, error) {
 	if n.Count == 0 && len(n.Val) == 0 {
 		return "", nil
 	}
-	return Hash(n)
+	return Hash(&pb.Node{
+		Next:         n.Next,
+		Val:          n.Val,
+		Count:        n.Count,
+		EncodedPaths: n.EncodedPaths,
+	})
 }
 
+// Hash returns the hash of the deterministic encoding of the message, where map entries are sorted by key
 func Hash(msg proto.Message) (string, error) {
-	data, err := proto.Marshal(msg)
+	data, err := Encode(msg)
 	if err != nil {
 		return "", err
 	}
@@ -26,9 +34,48 @@ func Hash(msg proto.Message) (string, error) {
 	return HashBytes(data), nil
 }
 
+// Encode returns the deterministic encoding of the message, e.g. to sign it
+func Encode(msg proto.Message) ([]byte, error) {
+	buf := proto.NewBuffer(nil)
+	buf.SetDeterministic(true)
+	if err := buf.Marshal(msg); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// HashBlock returns the ID of the block, the hash of its header, which links it to its parent
 func HashBlock(block *pb.Block) string {
-	raw := block.Txs.Root.Hash + block.Balances.Root.Hash
-	return HashBytes([]byte(raw))
+	return HashHeader(BlockHeader(block))
+}
+
+// HashHeader returns the hash of the canonical encoding of the header: the deterministic encoding, with the fields
+// in order and the empty ones left out, so every node gets the same bytes for the same header
+func HashHeader(header *pb.BlockHeader) string {
+	hash, _ := Hash(header)
+	return hash
+}
+
+// BlockHeader returns the compact header of the block, with the root hashes of its tries and the hash of its votes
+// and evidence
+func BlockHeader(block *pb.Block) *pb.BlockHeader {
+	header := &pb.BlockHeader{
+		Index:      block.Index,
+		PrevHash:   block.PrevHash,
+		TxRoot:     block.GetTxs().GetRoot().GetHash(),
+		StateRoot:  block.GetBalances().GetRoot().GetHash(),
+		Timestamp:  block.Timestamp,
+		Difficulty: block.Difficulty,
+		Nonce:      block.Proof,
+		ChainId:    block.ChainId,
+		Signer:     block.Signer,
+		Coinbase:   block.Coinbase,
+	}
+	if len(block.Votes) > 0 || len(block.Evidence) > 0 {
+		header.ExtraHash, _ = Hash(&pb.Block{Votes: block.Votes, Evidence: block.Evidence})
+	}
+	return header
 }
 
 func HashBytes(data []byte) string {
@@ -48,6 +95,15 @@ func ToNibbles(s string) []byte {
 	return rst
 }
 
+// FromNibbles packs pairs of nibbles back into bytes. It is the reverse of ToNibbles.
+func FromNibbles(nibbles []byte) []byte {
+	rst := make([]byte, len(nibbles)/2)
+	for i := range rst {
+		rst[i] = nibbles[2*i]<<4 | nibbles[2*i+1]
+	}
+	return rst
+}
+
 func ToInts(bs []byte) []int {
 	var rst []int
 	for _, b := range bs {